@@ -15,6 +15,8 @@ import (
 	"github.com/Candoo/thornton-pickard-api/internal/database"
 	"github.com/Candoo/thornton-pickard-api/internal/handlers"
 	"github.com/Candoo/thornton-pickard-api/internal/middleware"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
 )
 
 // Define a default service name as fallback
@@ -61,6 +63,9 @@ func main() {
 		}
 	}
 
+	// Permanently delete records left in the trash past the retention period
+	services.StartTrashPurger(db, services.TrashRetention())
+
 	// Set Gin mode
 	if os.Getenv("ENV") == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -89,7 +94,15 @@ func main() {
 
 	// Initialize handlers
 	cameraHandler := handlers.NewCameraHandler(db)
-	userHandler := handlers.NewUserHandler(db)
+	mailer := services.NewMailer()
+	userHandler := handlers.NewUserHandler(db, mailer)
+
+	// Write routes need a signed-in user, with a verified email address if
+	// REQUIRE_VERIFIED_EMAIL=true
+	writeAccess := []gin.HandlerFunc{middleware.AuthRequired(db)}
+	if services.RequireVerifiedEmail() {
+		writeAccess = append(writeAccess, middleware.VerifiedEmailRequired())
+	}
 
 	// API v1 routes
 	v1 := r.Group("/api/v1")
@@ -99,9 +112,19 @@ func main() {
 		// Public auth routes
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/register", handlers.Register(db))
+			auth.POST("/register", handlers.Register(db, mailer))
 			auth.POST("/login", handlers.Login(db))
-			auth.GET("/profile", middleware.AuthRequired(), handlers.GetProfile(db))
+			auth.POST("/refresh", handlers.Refresh(db))
+			auth.GET("/profile", middleware.AuthRequired(db), handlers.GetProfile(db))
+			auth.PATCH("/profile", middleware.AuthRequired(db), handlers.UpdateProfile(db, mailer))
+			auth.DELETE("/profile", middleware.AuthRequired(db), handlers.DeleteProfile(db))
+			auth.POST("/change-password", middleware.AuthRequired(db), handlers.ChangePassword(db, mailer))
+			auth.POST("/logout", middleware.AuthRequired(db), handlers.Logout(db))
+			auth.POST("/logout-all", middleware.AuthRequired(db), handlers.LogoutAll(db))
+			auth.POST("/forgot-password", handlers.ForgotPassword(db, mailer))
+			auth.POST("/reset-password", handlers.ResetPassword(db))
+			auth.POST("/verify-email", handlers.VerifyEmail(db))
+			auth.POST("/verify-email/resend", middleware.AuthRequired(db), handlers.ResendVerification(db, mailer))
 		}
 
 		// Public camera routes (read-only)
@@ -109,23 +132,53 @@ func main() {
 		{
 			cameras.GET("", cameraHandler.GetCameras)
 			cameras.GET("/:id", cameraHandler.GetCamera)
+			cameras.GET("/:id/ephemera", handlers.GetCameraEphemera(db))
+			cameras.GET("/:id/revisions", handlers.GetRevisions(db, models.RevisionCamera))
+			cameras.GET("/:id/revisions/:rev", handlers.GetRevision(db, models.RevisionCamera))
+			cameras.GET("/:id/valuations", handlers.GetValuations(db))
+			cameras.GET("/:id/sales", handlers.GetSales(db))
+			cameras.GET("/:id/price-history", handlers.GetPriceHistory(db))
+			cameras.GET("/:id/examples", middleware.AuthOptional(db), handlers.GetExamples(db))
+			cameras.GET("/:id/examples/:example", middleware.AuthOptional(db), handlers.GetExample(db))
 		}
 
-		// Protected camera routes (require auth)
+		v1.GET("/rarities", handlers.GetRarities)
+
+		// Protected camera routes (require auth and a permission)
 		camerasProtected := v1.Group("/cameras")
-		camerasProtected.Use(middleware.AuthRequired())
+		camerasProtected.Use(writeAccess...)
 
 		{
-			camerasProtected.POST("", cameraHandler.CreateCamera)
-			camerasProtected.PUT("/:id", cameraHandler.UpdateCamera)
-			camerasProtected.DELETE("/:id", middleware.AdminRequired(), cameraHandler.DeleteCamera)
+			camerasProtected.POST("", middleware.RequirePermission(models.PermCamerasWrite), cameraHandler.CreateCamera)
+			camerasProtected.PUT("/:id", middleware.RequirePermission(models.PermCamerasWrite), cameraHandler.UpdateCamera)
+			camerasProtected.PATCH("/:id", middleware.RequirePermission(models.PermCamerasWrite), cameraHandler.PatchCamera)
+			camerasProtected.DELETE("/:id", middleware.RequirePermission(models.PermCamerasDelete), cameraHandler.DeleteCamera)
+			camerasProtected.POST("/:id/revisions/:rev/restore", middleware.RequirePermission(models.PermRevisionsRestore), cameraHandler.RestoreCameraRevision)
+			camerasProtected.POST("/:id/valuations", middleware.RequirePermission(models.PermValuationsWrite), handlers.CreateValuation(db))
+			camerasProtected.PUT("/:id/valuations/:valuation", middleware.RequirePermission(models.PermValuationsWrite), handlers.UpdateValuation(db))
+			camerasProtected.DELETE("/:id/valuations/:valuation", middleware.RequirePermission(models.PermValuationsDelete), handlers.DeleteValuation(db))
+			camerasProtected.POST("/:id/sales", middleware.RequirePermission(models.PermSalesWrite), handlers.CreateSale(db))
+			camerasProtected.PUT("/:id/sales/:sale", middleware.RequirePermission(models.PermSalesWrite), handlers.UpdateSale(db))
+			camerasProtected.DELETE("/:id/sales/:sale", middleware.RequirePermission(models.PermSalesDelete), handlers.DeleteSale(db))
+			camerasProtected.POST("/:id/examples", middleware.RequirePermission(models.PermExamplesWrite), handlers.CreateExample(db))
+			camerasProtected.PUT("/:id/examples/:example", handlers.UpdateExample(db))
+			camerasProtected.DELETE("/:id/examples/:example", handlers.DeleteExample(db))
 		}
 
-		// User Routes (Protected: Requires Auth/Admin)
+		// User Routes (Protected: Requires Auth and users:manage)
 		users := v1.Group("/users")
-		users.Use(middleware.AuthRequired()) // Protect the whole group
+		users.Use(middleware.AuthRequired(db)) // Protect the whole group
+		users.Use(middleware.RequirePermission(models.PermUsersManage))
 		{
-			users.GET("", middleware.AdminRequired(), userHandler.GetUsers) 
+			users.GET("", userHandler.GetUsers)
+			users.GET("/:id", userHandler.GetUser)
+			users.GET("/:id/audit", userHandler.GetUserAudit)
+			users.PUT("/:id/role", middleware.RequirePermission(models.PermRolesAssign), userHandler.UpdateUserRole)
+			users.POST("/:id/suspend", userHandler.SuspendUser)
+			users.POST("/:id/unsuspend", userHandler.UnsuspendUser)
+			users.POST("/:id/reset-password", userHandler.ForcePasswordReset)
+			users.DELETE("/:id", userHandler.DeleteUser)
+			users.POST("/:id/restore", userHandler.RestoreUser)
 		}
 
 		// Ephemera routes
@@ -133,23 +186,81 @@ func main() {
 		{
 			ephemera.GET("", handlers.GetEphemera(db))
 			ephemera.GET("/:id", handlers.GetEphemeraItem(db))
+			ephemera.GET("/:id/revisions", handlers.GetRevisions(db, models.RevisionEphemera))
+			ephemera.GET("/:id/revisions/:rev", handlers.GetRevision(db, models.RevisionEphemera))
 		}
 
 		ephemeraProtected := v1.Group("/ephemera")
-		ephemeraProtected.Use(middleware.AuthRequired())
+		ephemeraProtected.Use(writeAccess...)
 		{
-			ephemeraProtected.POST("", handlers.CreateEphemeraItem(db))
+			ephemeraProtected.POST("", middleware.RequirePermission(models.PermEphemeraWrite), handlers.CreateEphemeraItem(db))
+			ephemeraProtected.PUT("/:id", middleware.RequirePermission(models.PermEphemeraWrite), handlers.UpdateEphemeraItem(db))
+			ephemeraProtected.PATCH("/:id", middleware.RequirePermission(models.PermEphemeraWrite), handlers.PatchEphemeraItem(db))
+			ephemeraProtected.DELETE("/:id", middleware.RequirePermission(models.PermEphemeraDelete), handlers.DeleteEphemeraItem(db))
 		}
 
 		// Manufacturer routes
 		manufacturers := v1.Group("/manufacturers")
 		{
 			manufacturers.GET("", handlers.GetManufacturers(db))
+			manufacturers.GET("/:id", handlers.GetManufacturer(db))
+			manufacturers.GET("/:id/cameras", cameraHandler.GetManufacturerCameras)
+			manufacturers.GET("/:id/revisions", handlers.GetRevisions(db, models.RevisionManufacturer))
+			manufacturers.GET("/:id/revisions/:rev", handlers.GetRevision(db, models.RevisionManufacturer))
+		}
+
+		// Protected manufacturer routes
+		manufacturersProtected := v1.Group("/manufacturers")
+		manufacturersProtected.Use(writeAccess...)
+		{
+			manufacturersProtected.POST("", middleware.RequirePermission(models.PermManufacturersWrite), handlers.CreateManufacturer(db))
+			manufacturersProtected.PUT("/:id", middleware.RequirePermission(models.PermManufacturersWrite), handlers.UpdateManufacturer(db))
+			manufacturersProtected.PATCH("/:id", middleware.RequirePermission(models.PermManufacturersWrite), handlers.PatchManufacturer(db))
+			manufacturersProtected.DELETE("/:id", middleware.RequirePermission(models.PermManufacturersDelete), handlers.DeleteManufacturer(db))
+		}
+
+		// Serial number dating routes
+		serials := v1.Group("/serials")
+		{
+			serials.GET("", handlers.GetSerialRanges(db))
+			serials.GET("/lookup", handlers.LookupSerial(db))
+			serials.GET("/:id", handlers.GetSerialRange(db))
+		}
+
+		serialsProtected := v1.Group("/serials")
+		serialsProtected.Use(writeAccess...)
+		{
+			serialsProtected.POST("", middleware.RequirePermission(models.PermSerialsWrite), handlers.CreateSerialRange(db))
+			serialsProtected.PUT("/:id", middleware.RequirePermission(models.PermSerialsWrite), handlers.UpdateSerialRange(db))
+			serialsProtected.DELETE("/:id", middleware.RequirePermission(models.PermSerialsDelete), handlers.DeleteSerialRange(db))
+		}
+
+		// Export routes (public)
+		export := v1.Group("/export")
+		{
+			export.GET("/cameras", cameraHandler.ExportCameras)
+			export.GET("/ephemera", handlers.ExportEphemera(db))
+			export.GET("/manufacturers", handlers.ExportManufacturers(db))
+		}
+
+		// Admin routes: trash, bulk import and roles
+		admin := v1.Group("/admin")
+		admin.Use(writeAccess...)
+		{
+			admin.GET("/trash/:type", middleware.RequirePermission(models.PermTrashManage), handlers.GetTrash(db))
+			admin.POST("/trash/:type/:id/restore", middleware.RequirePermission(models.PermTrashManage), handlers.RestoreTrashItem(db))
+			admin.DELETE("/trash/:type/:id", middleware.RequirePermission(models.PermTrashManage), handlers.PurgeTrashItem(db))
+			admin.POST("/import/cameras", middleware.RequirePermission(models.PermImportRun), handlers.ImportCameras(db))
+			admin.POST("/import/sales", middleware.RequirePermission(models.PermImportRun), handlers.ImportSales(db))
+			admin.GET("/roles", middleware.RequirePermission(models.PermRolesManage), handlers.GetRoles(db))
+			admin.PUT("/roles/:role", middleware.RequirePermission(models.PermRolesManage), handlers.UpdateRolePermissions(db))
+			admin.GET("/permissions", middleware.RequirePermission(models.PermRolesManage), handlers.GetPermissions())
 		}
 
 		// Upload routes (require auth)
 		upload := v1.Group("/upload")
-		upload.Use(middleware.AuthRequired())
+		upload.Use(writeAccess...)
+		upload.Use(middleware.RequirePermission(models.PermUploadsWrite))
 		{
 			upload.POST("", handlers.UploadImage())
 			upload.POST("/multiple", handlers.UploadMultipleImages())
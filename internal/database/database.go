@@ -8,7 +8,6 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
-	"github.com/Candoo/thornton-pickard-api/internal/models"
 )
 
 func Initialize() (*gorm.DB, error) {
@@ -29,13 +28,8 @@ func Initialize() (*gorm.DB, error) {
 		return nil, err
 	}
 
-	// Auto-migrate models (added User model)
-	if err := db.AutoMigrate(
-		&models.Camera{},
-		&models.Ephemera{},
-		&models.Manufacturer{},
-		&models.User{}, // NEW
-	); err != nil {
+	// Auto-migrate models and convert legacy data
+	if err := Migrate(db); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,467 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+// Migrate brings the schema up to date and converts any legacy data in place.
+// It is safe to run on every start-up.
+func Migrate(db *gorm.DB) error {
+	// Accounts from before email verification are trusted as they are
+	verifyExistingUsers := db.Migrator().HasTable(&models.User{}) && !hasColumn(db, &models.User{}, "email_verified_at")
+	seedRolePermissions := !db.Migrator().HasTable(&models.RolePermission{})
+
+	if err := db.AutoMigrate(
+		&models.Camera{},
+		&models.PlateSize{},
+		&models.Feature{},
+		&models.CameraImage{},
+		&models.Ephemera{},
+		&models.EphemeraCamera{},
+		&models.Manufacturer{},
+		&models.User{},
+		&models.Revision{},
+		&models.Valuation{},
+		&models.SaleRecord{},
+		&models.SerialRange{},
+		&models.Example{},
+		&models.ExampleImage{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.UserToken{},
+		&models.AuditLog{},
+		&models.RolePermission{},
+	); err != nil {
+		return err
+	}
+
+	if verifyExistingUsers {
+		if err := db.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL").Error; err != nil {
+			return err
+		}
+		log.Println("✓ Marked existing users' email addresses verified")
+	}
+
+	if seedRolePermissions {
+		// Plain users from before roles existed become contributors
+		if err := db.Exec("UPDATE users SET role = ? WHERE role = ?", models.RoleContributor, "user").Error; err != nil {
+			return err
+		}
+
+		if err := services.SeedRolePermissions(db); err != nil {
+			return err
+		}
+		log.Println("✓ Seeded default role permissions")
+	}
+
+	if err := migrateCameraLists(db); err != nil {
+		return err
+	}
+
+	if err := migrateManufacturerNames(db); err != nil {
+		return err
+	}
+
+	if err := migrateCameraManufacturers(db); err != nil {
+		return err
+	}
+
+	if err := migrateEphemeraCameras(db); err != nil {
+		return err
+	}
+
+	if err := migrateCameraRarity(db); err != nil {
+		return err
+	}
+
+	if err := migrateCameraValuations(db); err != nil {
+		return err
+	}
+
+	// Must run last: table rebuilds above drop the search triggers
+	if err := migrateCameraSearch(db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// legacyCameraListColumns are the JSON-encoded string columns that Camera used
+// before plate sizes, features and images became tables.
+var legacyCameraListColumns = []string{"plate_sizes", "features", "image_urls"}
+
+// migrateCameraLists moves the legacy JSON string columns on cameras into the
+// plate_sizes, features and camera_images tables, then drops the columns.
+func migrateCameraLists(db *gorm.DB) error {
+	var columns []string
+	for _, column := range legacyCameraListColumns {
+		if hasColumn(db, &models.Camera{}, column) {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	log.Printf("Migrating legacy camera columns %v to relational tables", columns)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var rows []map[string]interface{}
+		if err := tx.Table("cameras").Select(append([]string{"id"}, columns...)).Find(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			camera := models.Camera{ID: toUint(row["id"])}
+
+			plateSizes := parseLegacyList(camera.ID, "plate_sizes", row["plate_sizes"])
+			features := parseLegacyList(camera.ID, "features", row["features"])
+			imageURLs := parseLegacyList(camera.ID, "image_urls", row["image_urls"])
+
+			images := (&models.CameraRequest{ImageURLs: imageURLs}).ImageList()
+			if err := services.SetCameraRelations(tx, &camera, plateSizes, features, images); err != nil {
+				return err
+			}
+		}
+
+		for _, column := range columns {
+			if err := tx.Migrator().DropColumn(&models.Camera{}, column); err != nil {
+				return err
+			}
+		}
+
+		log.Printf("✓ Migrated list columns for %d cameras", len(rows))
+		return nil
+	})
+}
+
+// migrateManufacturerNames fills in the normalized name of manufacturers
+// from before it was stored. A manufacturer whose name is equivalent to an
+// earlier one's is reported and given a placeholder that no name lookup
+// matches, to be merged by hand.
+func migrateManufacturerNames(db *gorm.DB) error {
+	var manufacturers []models.Manufacturer
+	if err := db.Where("normalized_name IS NULL").Order("id").Find(&manufacturers).Error; err != nil {
+		return err
+	}
+	if len(manufacturers) == 0 {
+		return nil
+	}
+
+	log.Printf("Normalizing the names of %d manufacturers", len(manufacturers))
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, manufacturer := range manufacturers {
+			normalized := models.NormalizeManufacturerName(manufacturer.Name)
+
+			var count int64
+			if err := tx.Model(&models.Manufacturer{}).Where("normalized_name = ?", normalized).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				log.Printf("Warning: manufacturer %q (id %d) duplicates another manufacturer's name; merge them by hand", manufacturer.Name, manufacturer.ID)
+				normalized = fmt.Sprintf("%s#%d", normalized, manufacturer.ID)
+			}
+
+			if err := tx.Model(&manufacturer).UpdateColumn("normalized_name", normalized).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateCameraManufacturers links cameras to manufacturer rows using the
+// legacy free-text manufacturer column, then drops that column. Names that
+// match no manufacturer are reported and preserved in legacy_manufacturer so
+// they can be fixed by hand.
+func migrateCameraManufacturers(db *gorm.DB) error {
+	if !hasColumn(db, &models.Camera{}, "manufacturer") {
+		return nil
+	}
+
+	log.Println("Migrating legacy camera manufacturer names to manufacturer_id")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var names []string
+		if err := tx.Table("cameras").
+			Where("manufacturer_id IS NULL").
+			Distinct("manufacturer").
+			Pluck("manufacturer", &names).Error; err != nil {
+			return err
+		}
+
+		unmatched := 0
+		for _, name := range names {
+			manufacturer, err := services.FindManufacturerByName(tx, name)
+			if err == gorm.ErrRecordNotFound {
+				var count int64
+				tx.Table("cameras").Where("manufacturer = ? AND manufacturer_id IS NULL", name).Count(&count)
+				log.Printf("Warning: no manufacturer matches %q (%d cameras); kept in cameras.legacy_manufacturer", name, count)
+				unmatched++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Table("cameras").
+				Where("manufacturer = ? AND manufacturer_id IS NULL", name).
+				Update("manufacturer_id", manufacturer.ID).Error; err != nil {
+				return err
+			}
+			log.Printf("✓ Linked cameras with manufacturer %q to %q (id %d)", name, manufacturer.Name, manufacturer.ID)
+		}
+
+		if unmatched > 0 {
+			if !hasColumn(tx, &models.Camera{}, "legacy_manufacturer") {
+				if err := tx.Exec("ALTER TABLE cameras ADD COLUMN legacy_manufacturer varchar(255)").Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec("UPDATE cameras SET legacy_manufacturer = manufacturer WHERE manufacturer_id IS NULL").Error; err != nil {
+				return err
+			}
+			log.Printf("Warning: %d manufacturer names could not be matched", unmatched)
+		}
+
+		return tx.Migrator().DropColumn(&models.Camera{}, "manufacturer")
+	})
+}
+
+// migrateEphemeraCameras moves the legacy related_cameras JSON column on
+// ephemera into the ephemera_cameras join table, then drops the column. IDs
+// of cameras that no longer exist are reported and skipped.
+func migrateEphemeraCameras(db *gorm.DB) error {
+	if !hasColumn(db, &models.Ephemera{}, "related_cameras") {
+		return nil
+	}
+
+	log.Println("Migrating legacy ephemera related_cameras to ephemera_cameras")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var rows []map[string]interface{}
+		if err := tx.Model(&models.Ephemera{}).Select("id", "related_cameras").Find(&rows).Error; err != nil {
+			return err
+		}
+
+		linked := 0
+		for _, row := range rows {
+			ephemeraID := toUint(row["id"])
+
+			for _, value := range parseLegacyList(ephemeraID, "related_cameras", row["related_cameras"]) {
+				cameraID, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+				if err != nil {
+					log.Printf("Warning: ephemera %d has invalid camera ID %q in related_cameras", ephemeraID, value)
+					continue
+				}
+
+				var count int64
+				if err := tx.Model(&models.Camera{}).Where("id = ?", cameraID).Count(&count).Error; err != nil {
+					return err
+				}
+				if count == 0 {
+					log.Printf("Warning: ephemera %d links to missing camera %d; link dropped", ephemeraID, cameraID)
+					continue
+				}
+
+				link := models.EphemeraCamera{EphemeraID: ephemeraID, CameraID: uint(cameraID)}
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&link).Error; err != nil {
+					return err
+				}
+				linked++
+			}
+		}
+
+		if err := tx.Migrator().DropColumn(&models.Ephemera{}, "related_cameras"); err != nil {
+			return err
+		}
+
+		log.Printf("✓ Migrated %d camera links for %d ephemera", linked, len(rows))
+		return nil
+	})
+}
+
+// migrateCameraRarity ranks cameras whose free-text rarity has not been
+// placed on the rarity scale yet, rewriting it in the scale's spelling.
+// Values that match no rarity are reported, moved to legacy_rarity so they
+// can be fixed by hand, and cleared so they are not migrated again.
+func migrateCameraRarity(db *gorm.DB) error {
+	var values []string
+	if err := db.Table("cameras").
+		Where("rarity <> '' AND rarity_rank = 0").
+		Distinct("rarity").
+		Pluck("rarity", &values).Error; err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	log.Println("Migrating free-text camera rarity to the rarity scale")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, value := range values {
+			rarity, ok := models.ParseRarity(value)
+			if !ok {
+				if !hasColumn(tx, &models.Camera{}, "legacy_rarity") {
+					if err := tx.Exec("ALTER TABLE cameras ADD COLUMN legacy_rarity varchar(255)").Error; err != nil {
+						return err
+					}
+				}
+				result := tx.Exec("UPDATE cameras SET legacy_rarity = rarity, rarity = '' WHERE rarity = ? AND rarity_rank = 0", value)
+				if result.Error != nil {
+					return result.Error
+				}
+				log.Printf("Warning: rarity %q (%d cameras) is not on the scale (%s); kept in cameras.legacy_rarity", value, result.RowsAffected, models.RarityNames())
+				continue
+			}
+
+			if err := tx.Table("cameras").
+				Where("rarity = ? AND rarity_rank = 0", value).
+				Updates(map[string]interface{}{"rarity": rarity.Name, "rarity_rank": rarity.Rank}).Error; err != nil {
+				return err
+			}
+			log.Printf("✓ Ranked cameras with rarity %q as %q (%d)", value, rarity.Name, rarity.Rank)
+		}
+		return nil
+	})
+}
+
+// migrateCameraValuations turns the legacy estimated_value_min/max columns
+// on cameras into valuations, then drops the columns. The old estimates were
+// shown in dollars, so they become USD valuations dated when the camera was
+// last updated.
+func migrateCameraValuations(db *gorm.DB) error {
+	if !hasColumn(db, &models.Camera{}, "estimated_value_min") {
+		return nil
+	}
+
+	log.Println("Migrating legacy camera value estimates to valuations")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			ID                uint
+			EstimatedValueMin *float64
+			EstimatedValueMax *float64
+			UpdatedAt         time.Time
+		}
+		if err := tx.Table("cameras").
+			Select("id", "estimated_value_min", "estimated_value_max", "updated_at").
+			Where("estimated_value_min IS NOT NULL OR estimated_value_max IS NOT NULL").
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			min, max := row.EstimatedValueMin, row.EstimatedValueMax
+			if min == nil {
+				min = max
+			}
+			if max == nil {
+				max = min
+			}
+
+			valuation := models.Valuation{
+				CameraID:  row.ID,
+				AmountMin: *min,
+				AmountMax: *max,
+				Currency:  "USD",
+				ValuedOn:  row.UpdatedAt,
+				Source:    "Legacy catalogue estimate",
+			}
+			if err := tx.Create(&valuation).Error; err != nil {
+				return err
+			}
+		}
+
+		// Dropped in place: the SQLite migrator's DropColumn rebuilds the
+		// table, which the search triggers on manufacturers refuse once they
+		// exist
+		for _, column := range []string{"estimated_value_min", "estimated_value_max"} {
+			if err := tx.Exec("ALTER TABLE cameras DROP COLUMN " + column).Error; err != nil {
+				return err
+			}
+		}
+
+		log.Printf("✓ Migrated value estimates of %d cameras to valuations", len(rows))
+		return nil
+	})
+}
+
+// parseLegacyList decodes a legacy list column of row id. Values were meant
+// to be JSON arrays but comma-separated strings also occur; anything else is
+// logged and skipped rather than silently dropped.
+func parseLegacyList(id uint, column string, value interface{}) []string {
+	var raw string
+	switch v := value.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	}
+
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(raw, "[") {
+		var values []interface{}
+		decoder := json.NewDecoder(strings.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			log.Printf("Warning: row %d has malformed %s %q: %v", id, column, raw, err)
+			return nil
+		}
+		list := make([]string, len(values))
+		for i, value := range values {
+			list[i] = fmt.Sprint(value)
+		}
+		return list
+	}
+
+	return strings.Split(raw, ",")
+}
+
+// hasColumn reports whether the table for model has the exact column. The
+// SQLite migrator's HasColumn matches on the table DDL with LIKE, so a column
+// such as legacy_manufacturer would also count as "manufacturer".
+func hasColumn(db *gorm.DB, model interface{}, column string) bool {
+	columnTypes, err := db.Migrator().ColumnTypes(model)
+	if err != nil {
+		return false
+	}
+	for _, columnType := range columnTypes {
+		if columnType.Name() == column {
+			return true
+		}
+	}
+	return false
+}
+
+func toUint(value interface{}) uint {
+	switch v := value.(type) {
+	case int64:
+		return uint(v)
+	case int32:
+		return uint(v)
+	case int:
+		return uint(v)
+	case uint:
+		return v
+	case uint64:
+		return uint(v)
+	}
+	return 0
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// migrateCameraSearch creates the full-text index used by camera search:
+// a weighted tsvector column with a GIN index on Postgres, and an FTS5
+// virtual table on SQLite. Both are kept current by triggers, which are
+// recreated on every start-up. Cameras missing from the index, such as those
+// from before it existed, are added; the rest are left alone so start-up does
+// not rewrite the whole table. A changed document definition therefore only
+// applies to cameras as they are next written.
+func migrateCameraSearch(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return migratePostgresCameraSearch(db)
+	case "sqlite":
+		return migrateSQLiteCameraSearch(db)
+	default:
+		log.Printf("Warning: full-text search is not supported on %s", db.Dialector.Name())
+		return nil
+	}
+}
+
+// postgresCameraSearchVector builds the weighted document for a camera row;
+// the argument is the row reference ("NEW." inside the trigger, "cameras."
+// for backfilling).
+func postgresCameraSearchVector(row string) string {
+	return fmt.Sprintf(`setweight(to_tsvector('english', coalesce(%[1]sname, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce((SELECT manufacturers.name FROM manufacturers WHERE manufacturers.id = %[1]smanufacturer_id), '')), 'B') ||
+		setweight(to_tsvector('english', coalesce(%[1]sdescription, '')), 'C')`, row)
+}
+
+func migratePostgresCameraSearch(db *gorm.DB) error {
+	statements := []string{
+		`ALTER TABLE cameras ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+		`CREATE INDEX IF NOT EXISTS idx_cameras_search_vector ON cameras USING GIN (search_vector)`,
+		`CREATE OR REPLACE FUNCTION cameras_search_vector_refresh() RETURNS trigger AS $$
+		BEGIN
+			NEW.search_vector := ` + postgresCameraSearchVector("NEW.") + `;
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS cameras_search_vector_trigger ON cameras`,
+		`CREATE TRIGGER cameras_search_vector_trigger BEFORE INSERT OR UPDATE ON cameras
+			FOR EACH ROW EXECUTE FUNCTION cameras_search_vector_refresh()`,
+		// Renaming a manufacturer re-indexes its cameras via the trigger above
+		`CREATE OR REPLACE FUNCTION manufacturers_search_vector_refresh() RETURNS trigger AS $$
+		BEGIN
+			UPDATE cameras SET search_vector = NULL WHERE manufacturer_id = NEW.id;
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS manufacturers_search_vector_trigger ON manufacturers`,
+		`CREATE TRIGGER manufacturers_search_vector_trigger AFTER UPDATE OF name ON manufacturers
+			FOR EACH ROW EXECUTE FUNCTION manufacturers_search_vector_refresh()`,
+		`UPDATE cameras SET search_vector = ` + postgresCameraSearchVector("cameras.") + ` WHERE search_vector IS NULL`,
+	}
+
+	return execAll(db, statements)
+}
+
+func migrateSQLiteCameraSearch(db *gorm.DB) error {
+	const columns = "name, manufacturer, description"
+	const values = "new.name, (SELECT name FROM manufacturers WHERE id = new.manufacturer_id), new.description"
+	const rows = `SELECT cameras.id, cameras.name, manufacturers.name, cameras.description
+		FROM cameras LEFT JOIN manufacturers ON manufacturers.id = cameras.manufacturer_id`
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS camera_search USING fts5(` + columns + `, tokenize = 'porter unicode61')`,
+		`DROP TRIGGER IF EXISTS cameras_search_insert`,
+		`DROP TRIGGER IF EXISTS cameras_search_update`,
+		`DROP TRIGGER IF EXISTS cameras_search_delete`,
+		`CREATE TRIGGER cameras_search_insert AFTER INSERT ON cameras BEGIN
+			INSERT INTO camera_search (rowid, ` + columns + `) VALUES (new.id, ` + values + `);
+		END`,
+		`CREATE TRIGGER cameras_search_update AFTER UPDATE ON cameras BEGIN
+			DELETE FROM camera_search WHERE rowid = old.id;
+			INSERT INTO camera_search (rowid, ` + columns + `) VALUES (new.id, ` + values + `);
+		END`,
+		`CREATE TRIGGER cameras_search_delete AFTER DELETE ON cameras BEGIN
+			DELETE FROM camera_search WHERE rowid = old.id;
+		END`,
+		`DROP TRIGGER IF EXISTS manufacturers_search_update`,
+		`CREATE TRIGGER manufacturers_search_update AFTER UPDATE OF name ON manufacturers BEGIN
+			DELETE FROM camera_search WHERE rowid IN (SELECT id FROM cameras WHERE manufacturer_id = new.id);
+			INSERT INTO camera_search (rowid, ` + columns + `) ` + rows + ` WHERE cameras.manufacturer_id = new.id;
+		END`,
+		`DELETE FROM camera_search WHERE rowid NOT IN (SELECT id FROM cameras)`,
+		`INSERT INTO camera_search (rowid, ` + columns + `) ` + rows + ` WHERE cameras.id NOT IN (SELECT rowid FROM camera_search)`,
+	}
+
+	return execAll(db, statements)
+}
+
+func execAll(db *gorm.DB, statements []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
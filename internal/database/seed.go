@@ -1,12 +1,15 @@
 package database
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"gorm.io/gorm"
 	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
 )
 
 func SeedDatabase(db *gorm.DB) error {
@@ -27,6 +30,11 @@ func SeedDatabase(db *gorm.DB) error {
 		return err
 	}
 
+	// Seed valuations
+	if err := seedValuations(db); err != nil {
+		return err
+	}
+
 	log.Println("Database seeding completed successfully")
 	return nil
 }
@@ -39,11 +47,13 @@ func seedAdminUser(db *gorm.DB) error {
 		return nil
 	}
 
+	verifiedAt := time.Now()
 	admin := models.User{
 		Email: "admin@thorntonpickard.com",
-		Role:  "admin",
+		Role:  models.RoleAdmin,
 		FirstName: "System", 
         LastName:  "Admin",
+		EmailVerifiedAt: &verifiedAt,
 	}
 
 	if err := admin.HashPassword("admin123"); err != nil {
@@ -96,68 +106,67 @@ func seedCameras(db *gorm.DB) error {
 	if err != nil {
 		log.Printf("Warning: Failed to read cameras.json, falling back to default seed data: %v", err)
 	} else {
-		var cameras []models.Camera
-		if err := json.Unmarshal(data, &cameras); err != nil {
-			log.Fatalf("FATAL JSON UNMARSHAL ERROR: Failed to parse cameras.json: %v", err)
-			return err 
+		rows, err := services.ParseCameraImport(data, services.ImportJSON)
+		if err != nil {
+			return fmt.Errorf("failed to parse seeds/cameras.json: %w", err)
 		}
 
-		if err := db.Create(&cameras).Error; err != nil {
-			log.Fatalf("FATAL GORM SEED ERROR: Failed to create cameras from JSON: %v", err) 
-			return err
+		result, err := services.ImportCameras(db, rows, services.ImportOptions{Atomic: true})
+		if err != nil {
+			return fmt.Errorf("failed to seed cameras from seeds/cameras.json: %w", err)
+		}
+		if result.Failed > 0 {
+			for _, rowErr := range result.Errors {
+				log.Printf("Warning: seeds/cameras.json line %d (%s): %s", rowErr.Line, rowErr.Name, rowErr.Error)
+			}
+			return fmt.Errorf("%d of %d cameras in seeds/cameras.json are invalid; none were seeded", result.Failed, result.Rows)
 		}
 
-		log.Printf("✓ Seeded %d cameras from JSON file", len(cameras))
+		log.Printf("✓ Seeded %d cameras from JSON file", result.Created)
 		return nil
 	}
 
 	// Default seed data
-	cameras := []models.Camera{
+	cameras := []models.CameraRequest{
 		{
 			Name:              "Ruby Reflex",
 			Manufacturer:      "Thornton-Pickard",
 			YearIntroduced:    1909,
 			YearDiscontinued:  intPtr(1926),
 			Format:            "Plate",
-			PlateSizes:        `["4x5", "5x7"]`,
+			PlateSizes:        []string{"4x5", "5x7"},
 			Lens:              "Various",
 			Shutter:           "Focal Plane",
-			Features:          `["Reflex viewing", "Tilting back", "Rising front"]`,
+			Features:          []string{"Reflex viewing", "Tilting back", "Rising front"},
 			Description:       "Professional reflex camera popular with press photographers",
 			Rarity:            "Uncommon",
-			EstimatedValueMin: float64Ptr(500),
-			EstimatedValueMax: float64Ptr(800),
 		},
 		{
 			Name:              "Imperial Triple Extension",
 			Manufacturer:      "Thornton-Pickard",
 			YearIntroduced:    1895,
 			Format:            "Plate",
-			PlateSizes:        `["Half-plate", "Whole-plate"]`,
+			PlateSizes:        []string{"Half-plate", "Whole-plate"},
 			Shutter:           "Time Shutter",
-			Features:          `["Triple extension bellows", "Mahogany construction"]`,
+			Features:          []string{"Triple extension bellows", "Mahogany construction"},
 			Description:       "High-quality field camera with extensive movements",
 			Rarity:            "Rare",
-			EstimatedValueMin: float64Ptr(300),
-			EstimatedValueMax: float64Ptr(600),
 		},
 		{
 			Name:              "Time & Instantaneous",
 			Manufacturer:      "Thornton-Pickard",
 			YearIntroduced:    1892,
 			Format:            "Plate",
-			PlateSizes:        `["Quarter-plate", "Half-plate"]`,
+			PlateSizes:        []string{"Quarter-plate", "Half-plate"},
 			Lens:              "",
 			Shutter:           "T&I Shutter",
-			Features:          `["Mahogany body", "Brass fittings"]`,
+			Features:          []string{"Mahogany body", "Brass fittings"},
 			Description:       "Early hand camera with distinctive T&I shutter",
 			Rarity:            "Very Rare",
-			EstimatedValueMin: float64Ptr(200),
-			EstimatedValueMax: float64Ptr(400),
 		},
 	}
 
-	if err := db.Create(&cameras).Error; err != nil {
+	if err := createCameras(db, cameras); err != nil {
 		return err
 	}
 
@@ -165,10 +174,61 @@ func seedCameras(db *gorm.DB) error {
 	return nil
 }
 
+// seedValuations gives the seeded cameras their price guide estimates.
+func seedValuations(db *gorm.DB) error {
+	var count int64
+	db.Model(&models.Valuation{}).Count(&count)
+	if count > 0 {
+		log.Println("Valuations already exist, skipping seed")
+		return nil
+	}
+
+	estimates := []struct {
+		camera   string
+		min, max float64
+	}{
+		{"Ruby Reflex", 500, 800},
+		{"Imperial Triple Extension", 300, 600},
+		{"Time & Instantaneous", 200, 400},
+	}
+
+	seeded := 0
+	for _, estimate := range estimates {
+		var camera models.Camera
+		if err := db.Where("name = ?", estimate.camera).First(&camera).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+
+		valuation := models.Valuation{CameraID: camera.ID}
+		req := models.ValuationRequest{
+			AmountMin: estimate.min,
+			AmountMax: estimate.max,
+			Currency:  "USD",
+			ValuedOn:  models.NewDate(time.Now()),
+			Source:    "Collector price guide",
+		}
+		if err := services.SaveValuation(db, &valuation, &req); err != nil {
+			return err
+		}
+		seeded++
+	}
+
+	log.Printf("✓ Seeded %d valuations", seeded)
+	return nil
+}
+
+func createCameras(db *gorm.DB, cameras []models.CameraRequest) error {
+	for i := range cameras {
+		if _, err := services.CreateCamera(db, &cameras[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func intPtr(i int) *int {
 	return &i
 }
-
-func float64Ptr(f float64) *float64 {
-	return &f
-}
\ No newline at end of file
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -11,7 +13,7 @@ import (
 
 // Register godoc
 // @Summary Register a new user
-// @Description Register a new user and return a JWT token
+// @Description Register a new user, email them a link to verify their address, and return an access token and refresh token
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -21,7 +23,7 @@ import (
 // @Failure 409 {object} map[string]string "error: User already exists"
 // @Failure 500 {object} map[string]string "error: Could not create user"
 // @Router /auth/register [post]
-func Register(db *gorm.DB) gin.HandlerFunc {
+func Register(db *gorm.DB, mailer services.Mailer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req models.RegisterRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -32,17 +34,18 @@ func Register(db *gorm.DB) gin.HandlerFunc {
 		// Check if user already exists
 		var existingUser models.User
 		// NOTE: You are not checking for deleted users here, which is standard practice for uniqueness.
-		if err := db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+		email := models.NormalizeEmail(req.Email)
+		if err := db.Where("LOWER(email) = ?", email).First(&existingUser).Error; err == nil {
 			c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
 			return
 		}
 
 		// Create user
 		user := models.User{
-			Email: req.Email,
+			Email: email,
 			FirstName: req.FirstName, // ADDED: Required for passing tests/proper user creation
 			LastName:  req.LastName,  // ADDED: Required for passing tests/proper user creation
-			Role: "user",
+			Role: services.DefaultRole,
 		}
 
 		if err := user.HashPassword(req.Password); err != nil {
@@ -55,23 +58,25 @@ func Register(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Generate token
-		token, err := services.GenerateToken(&user)
+		// A mail failure should not lose the account; the user can ask again
+		if err := services.SendVerificationEmail(db, mailer, &user); err != nil {
+			log.Printf("Failed to send verification email to %s: %v", user.Email, err)
+		}
+
+		// Sign the new user in
+		response, err := services.StartSession(db, &user, c.Request.UserAgent())
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 			return
 		}
 
-		c.JSON(http.StatusCreated, models.AuthResponse{
-			Token: token,
-			User:  user,
-		})
+		c.JSON(http.StatusCreated, response)
 	}
 }
 
 // Login authenticates a user
 // @Summary Login
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and start a session: returns a short-lived access token and a refresh token
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -90,7 +95,7 @@ func Login(db *gorm.DB) gin.HandlerFunc {
 
 		// Find user
 		var user models.User
-		if err := db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		if err := db.Where("LOWER(email) = ?", models.NormalizeEmail(req.Email)).First(&user).Error; err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 			return
 		}
@@ -101,17 +106,24 @@ func Login(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Generate token
-		token, err := services.GenerateToken(&user)
+		// Accounts an admin has acted on stay signed out
+		if user.SuspendedAt != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account suspended"})
+			return
+		}
+		if user.ResetRequired {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Choose a new password with the reset link emailed to you"})
+			return
+		}
+
+		// Start a session
+		response, err := services.StartSession(db, &user, c.Request.UserAgent())
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 			return
 		}
 
-		c.JSON(http.StatusOK, models.AuthResponse{
-			Token: token,
-			User:  user,
-		})
+		c.JSON(http.StatusOK, response)
 	}
 }
 
@@ -137,4 +149,329 @@ func GetProfile(db *gorm.DB) gin.HandlerFunc {
 		// Security Improvement: Return only the safe UserResponse object
 		c.JSON(http.StatusOK, user.ToUserResponse()) 
 	}
-}
\ No newline at end of file
+}
+
+// Refresh renews an access token
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token works once; presenting a used one again revokes its session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.AuthResponse
+// @Failure 400 {object} map[string]string "error: Invalid request data"
+// @Failure 401 {object} map[string]string "error: Invalid or expired refresh token"
+// @Router /auth/refresh [post]
+func Refresh(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		response, err := services.RefreshSession(db, req.RefreshToken)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrRefreshTokenReused):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token was already used; the session has been revoked"})
+			case errors.Is(err, services.ErrInvalidRefreshToken):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// Logout ends the current session
+// @Summary Logout
+// @Description Revoke the session of the access token used, so neither it nor the session's refresh token works again
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Router /auth/logout [post]
+func Logout(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := services.RevokeSession(db, c.GetString("session_id"), services.RevokedLogout); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// LogoutAll ends every session of the current user
+// @Summary Logout everywhere
+// @Description Revoke every session of the authenticated user, including the current one
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Router /auth/logout-all [post]
+func LogoutAll(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := currentUser(c)
+		if err := services.RevokeUserSessions(db, userID, services.RevokedLogoutAll, ""); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// ForgotPassword emails a password reset link
+// @Summary Forgot password
+// @Description Email a single-use password reset link to the address, if it has an account. The answer is the same whether or not it does, and comes before the email is sent.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Account email"
+// @Success 202 {object} map[string]string "message: If the address has an account, a reset link has been sent"
+// @Failure 400 {object} map[string]string "error: Invalid request data"
+// @Router /auth/forgot-password [post]
+func ForgotPassword(db *gorm.DB, mailer services.Mailer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Look the address up and mail it after answering, so the response
+		// time does not reveal whether the address has an account
+		go func(email string) {
+			if err := services.RequestPasswordReset(db, mailer, email); err != nil {
+				log.Printf("Failed to send password reset to %s: %v", email, err)
+			}
+		}(req.Email)
+
+		c.JSON(http.StatusAccepted, gin.H{"message": "If the address has an account, a reset link has been sent"})
+	}
+}
+
+// ResetPassword sets a new password with an emailed token
+// @Summary Reset password
+// @Description Set a new password using the token from a password reset email. The token works once; every session of the account is logged out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 204
+// @Failure 400 {object} map[string]string "error: Invalid or expired token"
+// @Router /auth/reset-password [post]
+func ResetPassword(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := services.ResetPassword(db, req.Token, req.Password); err != nil {
+			if errors.Is(err, services.ErrInvalidUserToken) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// VerifyEmail confirms an email address with an emailed token
+// @Summary Verify email
+// @Description Mark the account's email address verified using the token from a verification email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]string "error: Invalid or expired token"
+// @Router /auth/verify-email [post]
+func VerifyEmail(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, err := services.VerifyEmail(db, req.Token)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidUserToken) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+			return
+		}
+
+		c.JSON(http.StatusOK, user.ToUserResponse())
+	}
+}
+
+// ResendVerification emails a new verification link
+// @Summary Resend verification email
+// @Description Email the authenticated user a new link to verify their address. Earlier links stop working.
+// @Tags auth
+// @Security BearerAuth
+// @Success 202
+// @Failure 409 {object} map[string]string "error: Email already verified"
+// @Router /auth/verify-email/resend [post]
+func ResendVerification(db *gorm.DB, mailer services.Mailer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := currentUser(c)
+
+		var user models.User
+		if err := db.First(&user, userID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if user.EmailVerified() {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already verified"})
+			return
+		}
+
+		if err := services.SendVerificationEmail(db, mailer, &user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+			return
+		}
+
+		c.Status(http.StatusAccepted)
+	}
+}
+
+// currentAccount loads the authenticated user, writing the error response
+// itself when it cannot.
+func currentAccount(db *gorm.DB, c *gin.Context) (models.User, bool) {
+	userID, _ := currentUser(c)
+
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	return user, true
+}
+
+// accountError answers an error from an account change.
+func accountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrIncorrectPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+	case errors.Is(err, services.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "Email address is already in use"})
+	case errors.Is(err, services.ErrInvalidProfile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// UpdateProfile changes the current user's name or email
+// @Summary Update profile
+// @Description Change the first name, last name or email address of the authenticated user; only the fields sent change. A new email address needs current_password, starts out unverified and is sent a verification link.
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.UpdateProfileRequest true "Fields to change"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]string "error: Invalid request data"
+// @Failure 403 {object} map[string]string "error: Current password is incorrect"
+// @Failure 409 {object} map[string]string "error: Email address is already in use"
+// @Router /auth/profile [patch]
+func UpdateProfile(db *gorm.DB, mailer services.Mailer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentAccount(db, c)
+		if !ok {
+			return
+		}
+
+		var req models.UpdateProfileRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := services.UpdateProfile(db, mailer, &user, &req); err != nil {
+			accountError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, user.ToUserResponse())
+	}
+}
+
+// ChangePassword changes the current user's password
+// @Summary Change password
+// @Description Change the authenticated user's password after checking the current one. Every other session of the account is logged out; this one stays signed in.
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Param request body models.ChangePasswordRequest true "Current and new password"
+// @Success 204
+// @Failure 400 {object} map[string]string "error: Invalid request data"
+// @Failure 403 {object} map[string]string "error: Current password is incorrect"
+// @Router /auth/change-password [post]
+func ChangePassword(db *gorm.DB, mailer services.Mailer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentAccount(db, c)
+		if !ok {
+			return
+		}
+
+		var req models.ChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := services.ChangePassword(db, mailer, &user, c.GetString("session_id"), req.CurrentPassword, req.NewPassword); err != nil {
+			accountError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// DeleteProfile deletes the current user's account
+// @Summary Delete account
+// @Description Permanently delete the authenticated user's account after checking their password. Revisions they authored stay without their name, and surviving examples they recorded stay without their private details.
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Param request body models.DeleteAccountRequest true "Password"
+// @Success 204
+// @Failure 400 {object} map[string]string "error: Invalid request data"
+// @Failure 403 {object} map[string]string "error: Current password is incorrect"
+// @Router /auth/profile [delete]
+func DeleteProfile(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentAccount(db, c)
+		if !ok {
+			return
+		}
+
+		var req models.DeleteAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := services.DeleteAccount(db, &user, req.Password); err != nil {
+			accountError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -1,12 +1,21 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"gorm.io/gorm"
 	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
 	"github.com/Candoo/thornton-pickard-api/internal/utils"
 )
 
@@ -20,53 +29,231 @@ func NewCameraHandler(db *gorm.DB) *CameraHandler {
 	return &CameraHandler{DB: db}
 }
 
-func (h *CameraHandler) getCameraQuery(c *gin.Context) *gorm.DB {
+// cameraSearch returns the full-text search requested by the "search" query
+// parameter, or nil when there is none.
+func (h *CameraHandler) cameraSearch(c *gin.Context) *services.CameraSearch {
+	return services.NewCameraSearch(h.DB, c.Query("search"))
+}
+
+// filterCameraQuery applies the search and filter query parameters shared by
+// the camera listing, its facets and the export. formatParam names the
+// camera format filter, which exports take as "camera_format" because
+// "format" picks the file format there.
+func (h *CameraHandler) filterCameraQuery(c *gin.Context, search *services.CameraSearch, formatParam string) *gorm.DB {
 	query := h.DB.Model(&models.Camera{})
 
-	// Search
-	if search := c.Query("search"); search != "" {
-		query = query.Where(
-			"name LIKE ? OR manufacturer LIKE ? OR description LIKE ?",
-			"%"+search+"%",
-			"%"+search+"%",
-			"%"+search+"%",
-		)
+	// Full-text search
+	if search != nil {
+		query = search.Filter(query)
 	}
 
-	// Filter by manufacturer
-	if manufacturer := c.Query("manufacturer"); manufacturer != "" {
-		query = query.Where("manufacturer = ?", manufacturer)
+	// Filter by manufacturer, by ID or by (loosely matched) name
+	if manufacturerID := c.Query("manufacturer_id"); manufacturerID != "" {
+		query = query.Where("cameras.manufacturer_id = ?", manufacturerID)
+	}
+	if name := c.Query("manufacturer"); name != "" {
+		manufacturer, err := services.FindManufacturerByName(h.DB, name)
+		if err != nil {
+			// Unknown manufacturer: match nothing rather than everything
+			query = query.Where("1 = 0")
+		} else {
+			query = query.Where("cameras.manufacturer_id = ?", manufacturer.ID)
+		}
 	}
 
 	// Filter by year range
 	if yearFrom := c.Query("year_from"); yearFrom != "" {
-		query = query.Where("year_introduced >= ?", yearFrom)
+		query = query.Where("cameras.year_introduced >= ?", yearFrom)
 	}
 	if yearTo := c.Query("year_to"); yearTo != "" {
-		query = query.Where("year_introduced <= ?", yearTo)
+		query = query.Where("cameras.year_introduced <= ?", yearTo)
 	}
 
 	// Filter by format
-	if format := c.Query("format"); format != "" {
-		query = query.Where("format = ?", format)
+	if format := c.Query(formatParam); format != "" {
+		query = query.Where("cameras.format = ?", format)
+	}
+
+	// Filter by rarity range, given as names or ranks on the rarity scale
+	for param, operator := range map[string]string{"rarity_min": ">=", "rarity_max": "<="} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		if rank, ok := rarityRank(value); ok {
+			query = query.Where("cameras.rarity_rank "+operator+" ? AND cameras.rarity_rank > 0", rank)
+		} else {
+			// Unknown rarity: match nothing rather than everything
+			query = query.Where("1 = 0")
+		}
+	}
+
+	// Filter by plate size and feature; repeated parameters must all match
+	for _, plateSize := range c.QueryArray("plate_size") {
+		query = query.Where("cameras.id IN (?)", h.DB.
+			Table("camera_plate_sizes").
+			Select("camera_plate_sizes.camera_id").
+			Joins("JOIN plate_sizes ON plate_sizes.id = camera_plate_sizes.plate_size_id").
+			Where("LOWER(plate_sizes.name) = LOWER(?)", plateSize))
+	}
+	for _, feature := range c.QueryArray("feature") {
+		query = query.Where("cameras.id IN (?)", h.DB.
+			Table("camera_features").
+			Select("camera_features.camera_id").
+			Joins("JOIN features ON features.id = camera_features.feature_id").
+			Where("LOWER(features.name) = LOWER(?)", feature))
 	}
 
-	// Sorting
+	return query
+}
+
+// rarityRank reads a rarity query parameter, either a rarity name or its
+// rank.
+func rarityRank(value string) (int, bool) {
+	if rank, err := strconv.Atoi(value); err == nil {
+		return rank, rank >= 1 && rank <= len(models.RarityScale)
+	}
+	rarity, ok := models.ParseRarity(value)
+	return rarity.Rank, ok && rarity.Rank > 0
+}
+
+// cameraSortColumns maps each sort field to the column it orders by. Rarity
+// sorts by its rank on the scale rather than alphabetically.
+var cameraSortColumns = map[string]string{
+	"name":            "cameras.name",
+	"year_introduced": "cameras.year_introduced",
+	"rarity":          "cameras.rarity_rank",
+}
+
+// cameraSort returns the validated sort field and order of the request.
+// Relevance is only accepted alongside a search.
+func cameraSort(c *gin.Context, search *services.CameraSearch) (string, string) {
 	sortField := c.DefaultQuery("sort", "name")
 	sortOrder := c.DefaultQuery("order", "asc")
 	if sortOrder != "asc" && sortOrder != "desc" {
 		sortOrder = "asc"
 	}
-	validSortFields := map[string]bool{"name": true, "year_introduced": true, "rarity": true}
-	if !validSortFields[sortField] {
+
+	// Relevance only makes sense for a search and is always best-first
+	if sortField == "relevance" && search != nil {
+		return sortField, "desc"
+	}
+
+	if _, ok := cameraSortColumns[sortField]; !ok {
 		sortField = "name" // Default if invalid field is provided
 	}
 
-	query = query.Order(sortField + " " + sortOrder)
+	return sortField, sortOrder
+}
+
+func (h *CameraHandler) getCameraQuery(c *gin.Context) *gorm.DB {
+	search := h.cameraSearch(c)
+	query := h.filterCameraQuery(c, search, "format")
+
+	sortField, sortOrder := cameraSort(c, search)
+	if sortField == "relevance" {
+		return search.OrderByRelevance(query)
+	}
+
+	query = query.Order(cameraSortColumns[sortField] + " " + sortOrder)
 	
 	return query
 }
 
+// cameraKeyset is the cursor pagination order for the requested sort, with
+// the ID breaking ties.
+func cameraKeyset(sortField, sortOrder string) utils.Keyset {
+	desc := sortOrder == "desc"
+	return utils.Keyset{
+		{Column: cameraSortColumns[sortField], Desc: desc},
+		{Column: "cameras.id", Desc: desc},
+	}
+}
+
+// cameraFacets maps each facet name to the expression it groups by and any
+// joins it needs.
+var cameraFacets = map[string]struct {
+	expr  string
+	joins []string
+}{
+	"manufacturer": {
+		expr:  "manufacturers.name",
+		joins: []string{"JOIN manufacturers ON manufacturers.id = cameras.manufacturer_id"},
+	},
+	"format":       {expr: "cameras.format"},
+	"rarity":       {expr: "cameras.rarity"},
+	"decade": {
+		// Undated cameras (year 0) are excluded by the NULLIF
+		expr: "CAST(NULLIF(cameras.year_introduced, 0) / 10 * 10 AS TEXT)",
+	},
+	"plate_size": {
+		expr: "plate_sizes.name",
+		joins: []string{
+			"JOIN camera_plate_sizes ON camera_plate_sizes.camera_id = cameras.id",
+			"JOIN plate_sizes ON plate_sizes.id = camera_plate_sizes.plate_size_id",
+		},
+	},
+	"feature": {
+		expr: "features.name",
+		joins: []string{
+			"JOIN camera_features ON camera_features.camera_id = cameras.id",
+			"JOIN features ON features.id = camera_features.feature_id",
+		},
+	},
+}
+
+// parseCameraFacets validates the comma-separated "facets" query parameter.
+func parseCameraFacets(c *gin.Context) ([]string, error) {
+	param := c.Query("facets")
+	if param == "" {
+		return nil, nil
+	}
+
+	names := strings.Split(param, ",")
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if _, ok := cameraFacets[names[i]]; !ok {
+			return nil, fmt.Errorf("unknown facet %q", names[i])
+		}
+	}
+	return names, nil
+}
+
+// getCameraFacets counts cameras per value of each named facet, honouring
+// the active search and filters and the given scopes.
+func (h *CameraHandler) getCameraFacets(c *gin.Context, names []string, scopes ...func(*gorm.DB) *gorm.DB) (map[string][]utils.FacetValue, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	search := h.cameraSearch(c)
+	facets := make(map[string][]utils.FacetValue, len(names))
+
+	for _, name := range names {
+		facet := cameraFacets[name]
+
+		query := h.filterCameraQuery(c, search, "format").Scopes(scopes...)
+		for _, join := range facet.joins {
+			query = query.Joins(join)
+		}
+
+		values := []utils.FacetValue{}
+		err := query.
+			Select(facet.expr + " AS value, COUNT(DISTINCT cameras.id) AS count").
+			Where(facet.expr + " IS NOT NULL AND " + facet.expr + " <> ''").
+			Group(facet.expr).
+			Order("count DESC, value").
+			Scan(&values).Error
+		if err != nil {
+			return nil, err
+		}
+
+		facets[name] = values
+	}
+
+	return facets, nil
+}
+
 // GetCameras retrieves all cameras with pagination
 // @Summary List all cameras
 // @Description Get a paginated list of all cameras with filtering, sorting, and search
@@ -74,36 +261,133 @@ func (h *CameraHandler) getCameraQuery(c *gin.Context) *gorm.DB {
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
-// @Param search query string false "Search query (name, manufacturer, or description)"
-// @Param manufacturer query string false "Filter by manufacturer"
+// @Param cursor query string false "Page by cursor instead of page number: empty for the first page, then the previous page's next_cursor"
+// @Param search query string false "Full-text search over name, manufacturer and description"
+// @Param manufacturer query string false "Filter by manufacturer name (case, spacing and punctuation are ignored)"
+// @Param manufacturer_id query int false "Filter by manufacturer ID"
 // @Param year_from query int false "Filter by year from"
 // @Param year_to query int false "Filter by year to"
+// @Param rarity_min query string false "Filter to cameras at least this rare (rarity name or rank)"
+// @Param rarity_max query string false "Filter to cameras at most this rare (rarity name or rank)"
 // @Param format query string false "Filter by format"
-// @Param sort query string false "Sort by field (name, year_introduced, rarity)" default(name)
+// @Param plate_size query []string false "Filter by plate size (repeatable, all must match)" collectionFormat(multi)
+// @Param feature query []string false "Filter by feature (repeatable, all must match)" collectionFormat(multi)
+// @Param sort query string false "Sort by field (name, year_introduced, rarity by rank, relevance)" default(name)
 // @Param order query string false "Sort order (asc, desc)" default(asc)
+// @Param facets query string false "Comma-separated facets to count (manufacturer, format, rarity, decade, plate_size, feature)"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,name"
+// @Param include query string false "Comma-separated related resources to embed (manufacturer, ephemera, images)"
 // @Success 200 {object} utils.Pagination
+// @Failure 400 {object} map[string]string "error: Unknown facet"
 // @Router /cameras [get]
 func (h *CameraHandler) GetCameras(c *gin.Context) {
+	h.listCameras(c)
+}
+
+// GetManufacturerCameras lists the cameras made by one manufacturer
+// @Summary List a manufacturer's cameras
+// @Description Get a paginated list of cameras by manufacturer, accepting the same filters as /cameras
+// @Tags manufacturers
+// @Produce json
+// @Param id path int true "Manufacturer ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param cursor query string false "Page by cursor instead of page number: empty for the first page, then the previous page's next_cursor"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,name"
+// @Param include query string false "Comma-separated related resources to embed (manufacturer, ephemera, images)"
+// @Success 200 {object} utils.Pagination
+// @Failure 404 {object} map[string]string "error: Manufacturer not found"
+// @Router /manufacturers/{id}/cameras [get]
+func (h *CameraHandler) GetManufacturerCameras(c *gin.Context) {
+	var manufacturer models.Manufacturer
+	if err := h.DB.First(&manufacturer, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Manufacturer not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return
+	}
+
+	h.listCameras(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("cameras.manufacturer_id = ?", manufacturer.ID)
+	})
+}
+
+// listCameras writes one page of the cameras matching the request's filters
+// and the given scopes.
+func (h *CameraHandler) listCameras(c *gin.Context, scopes ...func(*gorm.DB) *gorm.DB) {
 	var cameras []models.Camera
 	var total int64
 
-	query := h.getCameraQuery(c)
+	search := h.cameraSearch(c)
+	query := h.getCameraQuery(c).Scopes(scopes...)
+
+	facetNames, err := parseCameraFacets(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fieldset, err := utils.ParseFieldset(c, models.CameraResponse{}, cameraRelations...)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sortField, sortOrder := cameraSort(c, search)
+	cursor, err := utils.NewCursorPage(c, cameraKeyset(sortField, sortOrder))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if cursor != nil && sortField == "relevance" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cursor pagination is not available when sorting by relevance"})
+		return
+	}
+
+	// Count total before pagination; cursor pages are never counted
+	if cursor == nil {
+		query.Count(&total)
+	}
 
-	// Count total before pagination
-	query.Count(&total)
+	// Include relevance score and highlights when searching
+	if search != nil {
+		query = search.Select(query)
+	}
 
 	// Apply pagination and retrieve data
-	if err := query.Scopes(utils.Paginate(c)).Find(&cameras).Error; err != nil {
+	var nextCursor string
+	query = query.Scopes(services.PreloadCameraRelations)
+	if cursor != nil {
+		nextCursor, err = cursor.Find(query, &cameras)
+	} else {
+		err = query.Scopes(utils.Paginate(c)).Find(&cameras).Error
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cameras"})
 		return
 	}
 
-	cameraResponses := make([]models.CameraResponse, len(cameras))
-	for i, camera := range cameras {
-		cameraResponses[i] = camera.ToCameraResponse()
+	facets, err := h.getCameraFacets(c, facetNames, scopes...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute facets"})
+		return
 	}
 
-	response := utils.CreatePaginationResponse(c, cameraResponses, total)
+	cameraResponses, err := h.shapeCameras(fieldset, cameras)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cameras"})
+		return
+	}
+
+	var response utils.Pagination
+	if cursor != nil {
+		response = utils.CreateCursorResponse(c, cameraResponses, nextCursor)
+	} else {
+		response = utils.CreatePaginationResponse(c, cameraResponses, total)
+	}
+	response.Facets = facets
 	c.JSON(http.StatusOK, response)
 }
 
@@ -113,13 +397,23 @@ func (h *CameraHandler) GetCameras(c *gin.Context) {
 // @Tags cameras
 // @Produce json
 // @Param id path int true "Camera ID"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,name"
+// @Param include query string false "Comma-separated related resources to embed (manufacturer, ephemera, images)"
+// @Param If-None-Match header string false "ETag from an earlier read"
 // @Success 200 {object} models.CameraResponse
+// @Success 304 "Not modified"
 // @Router /cameras/{id} [get]
 func (h *CameraHandler) GetCamera(c *gin.Context) {
 	id := c.Param("id")
-	var camera models.Camera
 
-	if err := h.DB.First(&camera, id).Error; err != nil {
+	fieldset, err := utils.ParseFieldset(c, models.CameraResponse{}, cameraRelations...)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	camera, err := services.FindCamera(h.DB, id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Camera not found"})
 			return
@@ -128,8 +422,64 @@ func (h *CameraHandler) GetCamera(c *gin.Context) {
 		return
 	}
 
-	cameraResponse := camera.ToCameraResponse()
-	c.JSON(http.StatusOK, cameraResponse)
+	if notModified(c, fieldsetETag(camera.Version, fieldset)) {
+		return
+	}
+
+	cameraResponses, err := h.shapeCameras(fieldset, []models.Camera{camera})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cameraResponses[0])
+}
+
+// cameraRelations are the related resources a camera can embed with the
+// "include" query parameter.
+var cameraRelations = []string{"manufacturer", "ephemera", "images"}
+
+// shapeCameras converts cameras to responses cut down to the requested
+// fieldset, loading any relations it embeds: the full manufacturer, linked
+// ephemera, and images with their captions.
+func (h *CameraHandler) shapeCameras(fieldset *utils.Fieldset, cameras []models.Camera) ([]interface{}, error) {
+	var ephemera map[uint][]models.RelatedEphemera
+	if fieldset.Includes("ephemera") {
+		ids := make([]uint, len(cameras))
+		for i := range cameras {
+			ids[i] = cameras[i].ID
+		}
+
+		var err error
+		if ephemera, err = services.FindRelatedEphemera(h.DB, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	responses := make([]interface{}, len(cameras))
+	for i := range cameras {
+		camera := &cameras[i]
+
+		related := ephemera[camera.ID]
+		if related == nil {
+			related = []models.RelatedEphemera{}
+		}
+		images := camera.Images
+		if images == nil {
+			images = []models.CameraImage{}
+		}
+
+		response, err := fieldset.Shape(camera.ToCameraResponse(), map[string]interface{}{
+			"manufacturer": camera.Manufacturer,
+			"ephemera":     related,
+			"images":       images,
+		})
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+
+	return responses, nil
 }
 
 // CreateCamera creates a new camera
@@ -138,22 +488,29 @@ func (h *CameraHandler) GetCamera(c *gin.Context) {
 // @Tags cameras
 // @Accept json
 // @Produce json
-// @Param camera body models.Camera true "Camera object"
+// @Param camera body models.CameraRequest true "Camera object"
 // @Success 201 {object} models.CameraResponse
+// @Failure 400 {object} map[string]string "error: Invalid input or unknown manufacturer"
 // @Router /cameras [post]
 func (h *CameraHandler) CreateCamera(c *gin.Context) {
-	var camera models.Camera
+	var req models.CameraRequest
 
-	if err := c.ShouldBindJSON(&camera); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
 		return
 	}
 
-	if err := h.DB.Create(&camera).Error; err != nil {
+	camera, err := services.CreateCamera(authoredDB(h.DB, c), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCamera) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create camera: " + err.Error()})
 		return
 	}
 
+	c.Header("ETag", etag(camera.Version))
 	cameraResponse := camera.ToCameraResponse()
 	c.JSON(http.StatusCreated, cameraResponse)
 }
@@ -165,8 +522,12 @@ func (h *CameraHandler) CreateCamera(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Camera ID"
-// @Param camera body models.Camera true "Camera object"
+// @Param camera body models.CameraRequest true "Camera object"
+// @Param If-Match header string true "ETag from the last read, or * for any version"
 // @Success 200 {object} models.CameraResponse
+// @Failure 400 {object} map[string]string "error: Invalid input or unknown manufacturer"
+// @Failure 412 {object} map[string]string "error: The record has changed since you read it"
+// @Failure 428 {object} map[string]string "error: If-Match header required"
 // @Router /cameras/{id} [put]
 func (h *CameraHandler) UpdateCamera(c *gin.Context) {
 	id := c.Param("id")
@@ -181,16 +542,265 @@ func (h *CameraHandler) UpdateCamera(c *gin.Context) {
 		return
 	}
 
-	if err := c.ShouldBindJSON(&camera); err != nil {
+	if !checkIfMatch(c, etag(camera.Version)) {
+		return
+	}
+
+	var req models.CameraRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
 		return
 	}
 
-	if err := h.DB.Save(&camera).Error; err != nil {
+	if err := services.UpdateCamera(authoredDB(h.DB, c), &camera, &req); err != nil {
+		updateCameraError(c, err)
+		return
+	}
+
+	c.Header("ETag", etag(camera.Version))
+	cameraResponse := camera.ToCameraResponse()
+	c.JSON(http.StatusOK, cameraResponse)
+}
+
+// updateCameraError writes the response for a failed camera update.
+func updateCameraError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCamera):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVersionConflict):
+		preconditionFailed(c)
+	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update camera: " + err.Error()})
+	}
+}
+
+// PatchCamera partially updates a camera
+// @Summary Patch a camera
+// @Description Apply an RFC 7396 JSON merge patch to a camera's response document, so a client can patch what it read. List fields are JSON arrays and replace the whole list, null clears a field, and absent fields are unchanged. Read-only fields (id, rarity_rank, current_estimate, timestamps...) may only be sent with their current values. Set manufacturer.id, or manufacturer.name or a plain manufacturer name, to switch the manufacturer.
+// @Tags cameras
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Camera ID"
+// @Param patch body models.CameraResponse true "Fields to change"
+// @Param If-Match header string true "ETag from the last read, or * for any version"
+// @Success 200 {object} models.CameraResponse
+// @Failure 400 {object} map[string]string "error: Invalid patch"
+// @Failure 404 {object} map[string]string "error: Camera not found"
+// @Failure 415 {object} map[string]string "error: Unsupported content type"
+// @Failure 412 {object} map[string]string "error: The record has changed since you read it"
+// @Failure 428 {object} map[string]string "error: If-Match header required"
+// @Router /cameras/{id} [patch]
+func (h *CameraHandler) PatchCamera(c *gin.Context) {
+	id := c.Param("id")
+
+	camera, err := services.FindCamera(h.DB, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Camera not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
 		return
 	}
 
+	if !checkIfMatch(c, etag(camera.Version)) {
+		return
+	}
+
+	if contentType := c.ContentType(); contentType != utils.MergePatchContentType && contentType != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported content type: use " + utils.MergePatchContentType})
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patch: " + err.Error()})
+		return
+	}
+
+	req, err := applyCameraPatch(&camera, patch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patch: " + err.Error()})
+		return
+	}
+
+	if err := services.UpdateCamera(authoredDB(h.DB, c), &camera, &req); err != nil {
+		updateCameraError(c, err)
+		return
+	}
+
+	c.Header("ETag", etag(camera.Version))
+	cameraResponse := camera.ToCameraResponse()
+	c.JSON(http.StatusOK, cameraResponse)
+}
+
+// cameraReadOnlyFields are the fields of CameraResponse a patch cannot
+// change. They may be sent back with their current values, so that a client
+// can patch the document it read.
+var cameraReadOnlyFields = []string{
+	"id", "rarity_rank", "estimated_value_range", "current_estimate",
+	"created_at", "updated_at", "score", "highlights",
+}
+
+// applyCameraPatch merges patch into the camera's response document and maps
+// the result back to a CameraRequest. Changing a read-only field or sending
+// an unknown one is rejected. An "images" list as embedded by include=images
+// replaces image_urls. The manufacturer is switched by setting
+// manufacturer.id, or manufacturer.name (or manufacturer as a plain name) to
+// match one by name.
+func applyCameraPatch(camera *models.Camera, patch []byte) (models.CameraRequest, error) {
+	document, err := json.Marshal(camera.ToCameraResponse())
+	if err != nil {
+		return models.CameraRequest{}, err
+	}
+
+	merged, err := utils.MergePatch(document, patch)
+	if err != nil {
+		return models.CameraRequest{}, err
+	}
+
+	var before, after map[string]interface{}
+	if err := json.Unmarshal(document, &before); err != nil {
+		return models.CameraRequest{}, err
+	}
+	if err := json.Unmarshal(merged, &after); err != nil {
+		return models.CameraRequest{}, err
+	}
+
+	for _, name := range cameraReadOnlyFields {
+		if !reflect.DeepEqual(before[name], after[name]) {
+			return models.CameraRequest{}, fmt.Errorf("%s is read-only", name)
+		}
+		delete(after, name)
+	}
+
+	manufacturer, ok := after["manufacturer"]
+	if !ok {
+		return models.CameraRequest{}, errors.New("manufacturer is required")
+	}
+	delete(after, "manufacturer")
+
+	// Only the fields of the response shape are accepted, not the request's
+	if _, ok := after["manufacturer_id"]; ok {
+		return models.CameraRequest{}, errors.New(`unknown field "manufacturer_id": set manufacturer.id instead`)
+	}
+
+	// Images may be sent as include=images returns them, to set captions;
+	// their order gives their position
+	if images, ok := after["images"].([]interface{}); ok {
+		for i, image := range images {
+			if fields, ok := image.(map[string]interface{}); ok {
+				images[i] = map[string]interface{}{"url": fields["url"], "caption": fields["caption"]}
+			}
+		}
+	}
+
+	fields, err := json.Marshal(after)
+	if err != nil {
+		return models.CameraRequest{}, err
+	}
+
+	var req models.CameraRequest
+	decoder := json.NewDecoder(bytes.NewReader(fields))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return req, err
+	}
+
+	if err := patchCameraManufacturer(&req, camera, before["manufacturer"], manufacturer); err != nil {
+		return req, err
+	}
+
+	return req, binding.Validator.ValidateStruct(&req)
+}
+
+// patchCameraManufacturer sets the manufacturer of req from the patched
+// manufacturer of camera's response document, given the one it had before.
+func patchCameraManufacturer(req *models.CameraRequest, camera *models.Camera, before, after interface{}) error {
+	if name, ok := after.(string); ok {
+		req.Manufacturer = name
+		return nil
+	}
+
+	patched, ok := after.(map[string]interface{})
+	if !ok {
+		return errors.New("manufacturer must be an object or a name")
+	}
+	current, _ := before.(map[string]interface{})
+
+	for name := range patched {
+		if name != "id" && name != "name" && name != "country" {
+			return fmt.Errorf("unknown field \"manufacturer.%s\"", name)
+		}
+	}
+
+	if !reflect.DeepEqual(patched["id"], current["id"]) {
+		id, ok := patched["id"].(float64)
+		if !ok || id < 1 || id != math.Trunc(id) {
+			return errors.New("manufacturer.id must be a manufacturer ID")
+		}
+		manufacturerID := uint(id)
+		req.ManufacturerID = &manufacturerID
+		return nil
+	}
+
+	if !reflect.DeepEqual(patched["country"], current["country"]) {
+		return errors.New("manufacturer.country is read-only: change it on the manufacturer")
+	}
+
+	if !reflect.DeepEqual(patched["name"], current["name"]) {
+		name, ok := patched["name"].(string)
+		if !ok {
+			return errors.New("manufacturer.name must be a string")
+		}
+		req.Manufacturer = name
+		return nil
+	}
+
+	req.ManufacturerID = camera.ManufacturerID
+	return nil
+}
+
+// RestoreCameraRevision rolls a camera back to an earlier revision
+// @Summary Restore a camera revision
+// @Description Put a camera back to the state recorded in a revision; the restore is recorded as a new revision (admin only)
+// @Tags cameras
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Camera ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} models.CameraResponse
+// @Failure 400 {object} map[string]string "error: Revision cannot be restored"
+// @Failure 404 {object} map[string]string "error: Camera or revision not found"
+// @Router /cameras/{id}/revisions/{rev}/restore [post]
+func (h *CameraHandler) RestoreCameraRevision(c *gin.Context) {
+	var camera models.Camera
+	if err := h.DB.First(&camera, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Camera not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return
+	}
+
+	revision, ok := findRevision(h.DB, c, models.RevisionCamera)
+	if !ok {
+		return
+	}
+
+	if err := services.RestoreCameraRevision(authoredDB(h.DB, c), &camera, &revision); err != nil {
+		if errors.Is(err, services.ErrInvalidCamera) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Revision cannot be restored: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore camera: " + err.Error()})
+		return
+	}
+
+	c.Header("ETag", etag(camera.Version))
 	cameraResponse := camera.ToCameraResponse()
 	c.JSON(http.StatusOK, cameraResponse)
 }
@@ -200,13 +810,28 @@ func (h *CameraHandler) UpdateCamera(c *gin.Context) {
 // @Description Delete a camera by ID
 // @Tags cameras
 // @Param id path int true "Camera ID"
+// @Param If-Match header string true "ETag from the last read, or * for any version"
 // @Success 204
+// @Failure 412 {object} map[string]string "error: The record has changed since you read it"
+// @Failure 428 {object} map[string]string "error: If-Match header required"
 // @Router /cameras/{id} [delete]
 func (h *CameraHandler) DeleteCamera(c *gin.Context) {
-	id := c.Param("id")
-	
-	// Perform soft delete using GORM
-	if err := h.DB.Delete(&models.Camera{}, id).Error; err != nil {
+	var camera models.Camera
+	if err := h.DB.First(&camera, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Camera not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return
+	}
+
+	if !checkIfMatch(c, etag(camera.Version)) {
+		return
+	}
+
+	// Soft delete the camera, hiding its ephemera links until it is restored
+	if err := services.DeleteCamera(authoredDB(h.DB, c), camera.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete camera: " + err.Error()})
 		return
 	}
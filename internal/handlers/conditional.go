@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
+)
+
+// etag returns the strong entity tag of a record version.
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// fieldsetETag returns the strong entity tag of a read of a record version
+// cut down to fieldset. Each shape of the record is a different
+// representation, so only the full one shares the tag that If-Match checks.
+func fieldsetETag(version uint, fieldset *utils.Fieldset) string {
+	if fieldset.IsDefault() {
+		return etag(version)
+	}
+	// Commas separate tags in If-None-Match, so they cannot appear in one
+	shape := strings.ReplaceAll(fieldset.String(), ",", "+")
+	return `"` + strconv.FormatUint(uint64(version), 10) + ";" + shape + `"`
+}
+
+// matchesETag reports whether an If-Match or If-None-Match header lists tag.
+// Weak tags only match when weak is set, as If-Match compares strongly.
+func matchesETag(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the ETag header of a read and answers 304 when the
+// client's If-None-Match already has that version, reporting whether it did.
+func notModified(c *gin.Context, tag string) bool {
+	c.Header("ETag", tag)
+
+	if header := c.GetHeader("If-None-Match"); header != "" && matchesETag(header, tag, true) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// checkIfMatch guards a write with optimistic concurrency: the request must
+// send If-Match with the ETag it last read ("*" accepts any version). It
+// answers 428 or 412 itself and reports whether the write may go ahead.
+func checkIfMatch(c *gin.Context, tag string) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header required: send the ETag from your last read"})
+		return false
+	}
+	if !matchesETag(header, tag, false) {
+		preconditionFailed(c)
+		return false
+	}
+	return true
+}
+
+// preconditionFailed answers a write whose If-Match no longer matches.
+func preconditionFailed(c *gin.Context) {
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "The record has changed since you read it: fetch it again and retry"})
+}
@@ -1,71 +1,428 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"gorm.io/gorm"
 	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
 )
 
+// getEphemeraQuery applies the ephemera filter query parameters and the
+// listing order.
+func getEphemeraQuery(db *gorm.DB, c *gin.Context) *gorm.DB {
+	return filterEphemeraQuery(db, c).Order("year, title")
+}
+
+// ephemeraKeyset is the cursor pagination order, matching the listing order.
+var ephemeraKeyset = utils.Keyset{
+	{Column: "ephemeras.year"},
+	{Column: "ephemeras.title"},
+	{Column: "ephemeras.id"},
+}
+
+// filterEphemeraQuery applies the ephemera filter query parameters shared by
+// the listing and the export.
+func filterEphemeraQuery(db *gorm.DB, c *gin.Context) *gorm.DB {
+	query := db.Model(&models.Ephemera{})
+
+	// Filter by type (case-insensitive)
+	if itemType := c.Query("type"); itemType != "" {
+		query = query.Where("LOWER(type) = LOWER(?)", strings.TrimSpace(itemType))
+	}
+
+	// Filter by year range
+	if yearFrom := c.Query("year_from"); yearFrom != "" {
+		query = query.Where("year >= ?", yearFrom)
+	}
+	if yearTo := c.Query("year_to"); yearTo != "" {
+		query = query.Where("year <= ?", yearTo)
+	}
+
+	// Search in title and description
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where("(LOWER(title) LIKE ? OR LOWER(description) LIKE ?)", pattern, pattern)
+	}
+
+	return query
+}
+
+// findEphemeraItem loads the item named by the "id" path parameter, writing
+// the error response itself when it cannot.
+func findEphemeraItem(db *gorm.DB, c *gin.Context) (models.Ephemera, bool) {
+	item, err := services.FindEphemera(db, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			return item, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return item, false
+	}
+	return item, true
+}
+
+// GetEphemera lists ephemera with pagination
+// @Summary List ephemera
+// @Description Get a paginated list of catalogues, manuals, advertisements and other ephemera
+// @Tags ephemera
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param cursor query string false "Page by cursor instead of page number: empty for the first page, then the previous page's next_cursor"
+// @Param type query string false "Filter by type, e.g. catalog (case-insensitive)"
+// @Param year_from query int false "Filter by year from"
+// @Param year_to query int false "Filter by year to"
+// @Param search query string false "Search in title and description"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,name"
+// @Param include query string false "Comma-separated related resources to embed (cameras)"
+// @Success 200 {object} utils.Pagination
+// @Router /ephemera [get]
 func GetEphemera(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ephemera []models.Ephemera
-		
-		if err := db.Find(&ephemera).Error; err != nil {
+		listEphemera(db, c)
+	}
+}
+
+// GetCameraEphemera lists the ephemera that mention one camera
+// @Summary List a camera's ephemera
+// @Description Get a paginated list of the catalogues, manuals and other ephemera linked to a camera, accepting the same filters as /ephemera
+// @Tags cameras
+// @Produce json
+// @Param id path int true "Camera ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param cursor query string false "Page by cursor instead of page number: empty for the first page, then the previous page's next_cursor"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,name"
+// @Param include query string false "Comma-separated related resources to embed (cameras)"
+// @Success 200 {object} utils.Pagination
+// @Failure 404 {object} map[string]string "error: Camera not found"
+// @Router /cameras/{id}/ephemera [get]
+func GetCameraEphemera(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var camera models.Camera
+		if err := db.First(&camera, c.Param("id")).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Camera not found"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, ephemera)
+		listEphemera(db, c, func(query *gorm.DB) *gorm.DB {
+			return query.Where("id IN (?)", db.
+				Model(&models.EphemeraCamera{}).
+				Select("ephemera_id").
+				Where("camera_id = ?", camera.ID))
+		})
+	}
+}
+
+// listEphemera writes one page of the ephemera matching the request's
+// filters and the given scopes.
+func listEphemera(db *gorm.DB, c *gin.Context, scopes ...func(*gorm.DB) *gorm.DB) {
+	var ephemera []models.Ephemera
+	var total int64
+
+	fieldset, err := utils.ParseFieldset(c, models.EphemeraResponse{}, ephemeraRelations...)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cursor, err := utils.NewCursorPage(c, ephemeraKeyset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := getEphemeraQuery(db, c).Scopes(scopes...)
+
+	var nextCursor string
+	if cursor != nil {
+		nextCursor, err = cursor.Find(query.Scopes(services.PreloadEphemeraRelations), &ephemera)
+	} else {
+		query.Count(&total)
+		err = query.Scopes(utils.Paginate(c), services.PreloadEphemeraRelations).Find(&ephemera).Error
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	responses, err := shapeEphemera(db, fieldset, ephemera)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if cursor != nil {
+		c.JSON(http.StatusOK, utils.CreateCursorResponse(c, responses, nextCursor))
+		return
+	}
+	c.JSON(http.StatusOK, utils.CreatePaginationResponse(c, responses, total))
 }
 
+// GetEphemeraItem retrieves a single ephemera item by ID
+// @Summary Get an ephemera item
+// @Description Get an ephemera item by ID
+// @Tags ephemera
+// @Produce json
+// @Param id path int true "Ephemera ID"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,name"
+// @Param include query string false "Comma-separated related resources to embed (cameras)"
+// @Param If-None-Match header string false "ETag from an earlier read"
+// @Success 200 {object} models.EphemeraResponse
+// @Failure 404 {object} map[string]string "error: Item not found"
+// @Success 304 "Not modified"
+// @Router /ephemera/{id} [get]
 func GetEphemeraItem(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id := c.Param("id")
-		var item models.Ephemera
+		fieldset, err := utils.ParseFieldset(c, models.EphemeraResponse{}, ephemeraRelations...)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		if err := db.First(&item, id).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
-				return
-			}
+		item, ok := findEphemeraItem(db, c)
+		if !ok {
+			return
+		}
+
+		if notModified(c, fieldsetETag(item.Version, fieldset)) {
+			return
+		}
+
+		responses, err := shapeEphemera(db, fieldset, []models.Ephemera{item})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		c.JSON(http.StatusOK, responses[0])
+	}
+}
+
+// ephemeraRelations are the related resources an ephemera item can embed
+// with the "include" query parameter.
+var ephemeraRelations = []string{"cameras"}
+
+// shapeEphemera converts items to responses cut down to the requested
+// fieldset, loading the full records of linked cameras when they are
+// embedded.
+func shapeEphemera(db *gorm.DB, fieldset *utils.Fieldset, items []models.Ephemera) ([]interface{}, error) {
+	var cameras map[uint]models.Camera
+	if fieldset.Includes("cameras") {
+		var ids []uint
+		for _, item := range items {
+			for _, link := range item.CameraLinks {
+				ids = append(ids, link.CameraID)
+			}
+		}
 
-		c.JSON(http.StatusOK, item)
+		var err error
+		if cameras, err = services.FindCameras(db, ids); err != nil {
+			return nil, err
+		}
 	}
+
+	responses := make([]interface{}, len(items))
+	for i := range items {
+		item := &items[i]
+
+		related := []models.CameraResponse{}
+		for _, link := range item.CameraLinks {
+			if camera, ok := cameras[link.CameraID]; ok {
+				related = append(related, camera.ToCameraResponse())
+			}
+		}
+
+		response, err := fieldset.Shape(item.ToEphemeraResponse(), map[string]interface{}{
+			"cameras": related,
+		})
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+
+	return responses, nil
 }
 
+// CreateEphemeraItem creates a new ephemera item
+// @Summary Create an ephemera item
+// @Description Create a new ephemera item
+// @Tags ephemera
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param item body models.EphemeraRequest true "Ephemera object"
+// @Success 201 {object} models.EphemeraResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Router /ephemera [post]
 func CreateEphemeraItem(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var req models.EphemeraRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		var item models.Ephemera
+		saveEphemeraItem(db, c, &item, &req, http.StatusCreated)
+	}
+}
 
-		if err := c.ShouldBindJSON(&item); err != nil {
+// UpdateEphemeraItem replaces an ephemera item
+// @Summary Update an ephemera item
+// @Description Replace an ephemera item by ID; omitted fields are cleared
+// @Tags ephemera
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Ephemera ID"
+// @Param item body models.EphemeraRequest true "Ephemera object"
+// @Param If-Match header string true "ETag from the last read, or * for any version"
+// @Success 200 {object} models.EphemeraResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 404 {object} map[string]string "error: Item not found"
+// @Failure 412 {object} map[string]string "error: The record has changed since you read it"
+// @Failure 428 {object} map[string]string "error: If-Match header required"
+// @Router /ephemera/{id} [put]
+func UpdateEphemeraItem(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findEphemeraItem(db, c)
+		if !ok {
+			return
+		}
+
+		if !checkIfMatch(c, etag(item.Version)) {
+			return
+		}
+
+		var req models.EphemeraRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		if err := db.Create(&item).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		saveEphemeraItem(db, c, &item, &req, http.StatusOK)
+	}
+}
+
+// PatchEphemeraItem partially updates an ephemera item
+// @Summary Patch an ephemera item
+// @Description Update only the fields present in the body
+// @Tags ephemera
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Ephemera ID"
+// @Param item body models.EphemeraRequest true "Fields to change"
+// @Param If-Match header string true "ETag from the last read, or * for any version"
+// @Success 200 {object} models.EphemeraResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 404 {object} map[string]string "error: Item not found"
+// @Failure 412 {object} map[string]string "error: The record has changed since you read it"
+// @Failure 428 {object} map[string]string "error: If-Match header required"
+// @Router /ephemera/{id} [patch]
+func PatchEphemeraItem(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		item, ok := findEphemeraItem(db, c)
+		if !ok {
+			return
+		}
+
+		if !checkIfMatch(c, etag(item.Version)) {
 			return
 		}
 
-		c.JSON(http.StatusCreated, item)
+		body, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Binding onto the current values leaves absent fields unchanged.
+		// Links sent in the body replace the current ones, which must not be
+		// decoded into or their page and note would carry over.
+		req := item.ToRequest()
+		if _, ok := fields["related_cameras"]; ok {
+			req.RelatedCameras = nil
+		}
+		if err := binding.JSON.BindBody(body, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		saveEphemeraItem(db, c, &item, &req, http.StatusOK)
+	}
+}
+
+// saveEphemeraItem stores req, answering with the saved item or the matching
+// error status.
+func saveEphemeraItem(db *gorm.DB, c *gin.Context, item *models.Ephemera, req *models.EphemeraRequest, status int) {
+	if err := services.SaveEphemera(authoredDB(db, c), item, req); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidEphemera):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrVersionConflict):
+			preconditionFailed(c)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
 	}
+
+	c.Header("ETag", etag(item.Version))
+	c.JSON(status, item.ToEphemeraResponse())
 }
 
-func GetManufacturers(db *gorm.DB) gin.HandlerFunc {
+// DeleteEphemeraItem deletes an ephemera item
+// @Summary Delete an ephemera item
+// @Description Move an ephemera item to the trash; its camera links come back if it is restored (admin only)
+// @Tags ephemera
+// @Security BearerAuth
+// @Param id path int true "Ephemera ID"
+// @Param If-Match header string true "ETag from the last read, or * for any version"
+// @Success 204
+// @Failure 404 {object} map[string]string "error: Item not found"
+// @Failure 412 {object} map[string]string "error: The record has changed since you read it"
+// @Failure 428 {object} map[string]string "error: If-Match header required"
+// @Router /ephemera/{id} [delete]
+func DeleteEphemeraItem(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var manufacturers []models.Manufacturer
-		
-		if err := db.Find(&manufacturers).Error; err != nil {
+		item, ok := findEphemeraItem(db, c)
+		if !ok {
+			return
+		}
+
+		if !checkIfMatch(c, etag(item.Version)) {
+			return
+		}
+
+		if err := services.DeleteEphemera(authoredDB(db, c), &item); err != nil {
+			if errors.Is(err, services.ErrVersionConflict) {
+				preconditionFailed(c)
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, manufacturers)
+		c.Status(http.StatusNoContent)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
+)
+
+// currentUser returns the ID and permissions of the authenticated user, or
+// zero values for anonymous requests.
+func currentUser(c *gin.Context) (uint, models.PermissionSet) {
+	userID, _ := c.Get("user_id")
+	granted, _ := c.Get("user_permissions")
+	id, _ := userID.(uint)
+	permissions, _ := granted.(models.PermissionSet)
+	return id, permissions
+}
+
+// findExample loads the example named by the "id" and "example" path
+// parameters, writing the error response itself when it cannot. The camera
+// is loaded first so that examples of trashed cameras are not found.
+func findExample(db *gorm.DB, c *gin.Context) (models.Example, bool) {
+	camera, ok := findCamera(db, c)
+	if !ok {
+		return models.Example{}, false
+	}
+
+	example, err := services.FindExample(db, camera.ID, c.Param("example"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Example not found"})
+			return example, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return example, false
+	}
+	return example, true
+}
+
+// findManagedExample loads an example like findExample and checks the
+// authenticated user may change it.
+func findManagedExample(db *gorm.DB, c *gin.Context) (models.Example, bool) {
+	example, ok := findExample(db, c)
+	if !ok {
+		return example, false
+	}
+
+	if !example.ManagedBy(currentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the user who recorded this example or a user with examples:manage can change it"})
+		return example, false
+	}
+	return example, true
+}
+
+// GetExamples lists the known surviving examples of a camera
+// @Summary List a camera's surviving examples
+// @Description Get a paginated list of the known surviving examples of a camera. Owners are shown when public; the private fields appear only for examples the authenticated user recorded, or for users with examples:manage.
+// @Tags examples
+// @Produce json
+// @Param id path int true "Camera ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} utils.Pagination
+// @Failure 404 {object} map[string]string "error: Camera not found"
+// @Router /cameras/{id}/examples [get]
+func GetExamples(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		camera, ok := findCamera(db, c)
+		if !ok {
+			return
+		}
+
+		var examples []models.Example
+		var total int64
+
+		query := services.ListExamples(db, camera.ID)
+		query.Count(&total)
+		if err := query.Scopes(utils.Paginate(c)).Find(&examples).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve examples"})
+			return
+		}
+
+		userID, permissions := currentUser(c)
+		responses := make([]models.ExampleResponse, len(examples))
+		for i, example := range examples {
+			responses[i] = example.ToExampleResponse(example.ManagedBy(userID, permissions))
+		}
+
+		c.JSON(http.StatusOK, utils.CreatePaginationResponse(c, responses, total))
+	}
+}
+
+// GetExample gets one surviving example of a camera
+// @Summary Get a surviving example
+// @Description Get one surviving example of a camera, with its private fields if the authenticated user recorded it or has examples:manage
+// @Tags examples
+// @Produce json
+// @Param id path int true "Camera ID"
+// @Param example path int true "Example ID"
+// @Success 200 {object} models.ExampleResponse
+// @Failure 404 {object} map[string]string "error: Example not found"
+// @Router /cameras/{id}/examples/{example} [get]
+func GetExample(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		example, ok := findExample(db, c)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, example.ToExampleResponse(example.ManagedBy(currentUser(c))))
+	}
+}
+
+// CreateExample records a surviving example of a camera
+// @Summary Add a surviving example
+// @Description Record a specific surviving camera: serial number, condition grade, location, owner, provenance, private notes and photos. owner is public only when owner_public is set; private_notes is never public.
+// @Tags examples
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Camera ID"
+// @Param example body models.ExampleRequest true "Example"
+// @Success 201 {object} models.ExampleResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 404 {object} map[string]string "error: Camera not found"
+// @Router /cameras/{id}/examples [post]
+func CreateExample(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		camera, ok := findCamera(db, c)
+		if !ok {
+			return
+		}
+
+		var req models.ExampleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		userID, _ := currentUser(c)
+		example := models.Example{CameraID: camera.ID, AddedByID: userID}
+		saveExample(db, c, &example, &req, http.StatusCreated)
+	}
+}
+
+// UpdateExample replaces a surviving example
+// @Summary Update a surviving example
+// @Description Replace a surviving example. Only the user who recorded it or a user with examples:manage may.
+// @Tags examples
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Camera ID"
+// @Param example path int true "Example ID"
+// @Param body body models.ExampleRequest true "Example"
+// @Success 200 {object} models.ExampleResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 403 {object} map[string]string "error: Not your example"
+// @Failure 404 {object} map[string]string "error: Example not found"
+// @Router /cameras/{id}/examples/{example} [put]
+func UpdateExample(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		example, ok := findManagedExample(db, c)
+		if !ok {
+			return
+		}
+
+		var req models.ExampleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		saveExample(db, c, &example, &req, http.StatusOK)
+	}
+}
+
+// saveExample validates and stores req, answering with the saved example,
+// private fields included, or the matching error status.
+func saveExample(db *gorm.DB, c *gin.Context, example *models.Example, req *models.ExampleRequest, status int) {
+	if err := services.SaveExample(db, example, req); err != nil {
+		if errors.Is(err, services.ErrInvalidExample) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(status, example.ToExampleResponse(true))
+}
+
+// DeleteExample removes a surviving example
+// @Summary Delete a surviving example
+// @Description Delete a surviving example and its photos. Only the user who recorded it or a user with examples:manage may.
+// @Tags examples
+// @Security BearerAuth
+// @Param id path int true "Camera ID"
+// @Param example path int true "Example ID"
+// @Success 204
+// @Failure 403 {object} map[string]string "error: Not your example"
+// @Failure 404 {object} map[string]string "error: Example not found"
+// @Router /cameras/{id}/examples/{example} [delete]
+func DeleteExample(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		example, ok := findManagedExample(db, c)
+		if !ok {
+			return
+		}
+
+		if err := services.DeleteExample(db, &example); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+// streamExport answers with an attachment named after the resource and the
+// date, written by export as records are loaded. Errors before the first
+// byte are answered as usual; later ones can only cut the download short.
+func streamExport(c *gin.Context, name string, columns []string, export func(w *services.ExportWriter) error) {
+	format := c.DefaultQuery("format", services.ExportJSON)
+	writer, err := services.NewExportWriter(c.Writer, format, columns)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	contentType, _ := services.ExportContentType(format)
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("2006-01-02"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	if err := export(writer); err != nil {
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "")
+			c.Header("Content-Disposition", "")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export " + name})
+			return
+		}
+		log.Printf("Warning: %s export failed after %d records: %v", name, writer.Rows(), err)
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Printf("Warning: %s export failed after %d records: %v", name, writer.Rows(), err)
+	}
+}
+
+// ExportCameras streams every camera matching the filters
+// @Summary Export cameras
+// @Description Download every camera matching the /cameras filters as CSV, NDJSON or a JSON array, in ID order. The camera format filter is camera_format here, as format picks the file format. The CSV columns can be imported again.
+// @Tags export
+// @Produce json
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "File format (csv, ndjson, json)" default(json)
+// @Param search query string false "Full-text search over name, manufacturer and description"
+// @Param manufacturer query string false "Filter by manufacturer name (case, spacing and punctuation are ignored)"
+// @Param manufacturer_id query int false "Filter by manufacturer ID"
+// @Param year_from query int false "Filter by year from"
+// @Param year_to query int false "Filter by year to"
+// @Param rarity_min query string false "Filter to cameras at least this rare (rarity name or rank)"
+// @Param rarity_max query string false "Filter to cameras at most this rare (rarity name or rank)"
+// @Param camera_format query string false "Filter by camera format"
+// @Param plate_size query []string false "Filter by plate size (repeatable, all must match)" collectionFormat(multi)
+// @Param feature query []string false "Filter by feature (repeatable, all must match)" collectionFormat(multi)
+// @Success 200 {array} models.CameraResponse
+// @Failure 400 {object} map[string]string "error: Unsupported format"
+// @Router /export/cameras [get]
+func (h *CameraHandler) ExportCameras(c *gin.Context) {
+	query := h.filterCameraQuery(c, h.cameraSearch(c), "camera_format")
+	streamExport(c, "cameras", services.CameraExportColumns, func(w *services.ExportWriter) error {
+		return services.ExportCameras(query, w)
+	})
+}
+
+// ExportEphemera streams every ephemera item matching the filters
+// @Summary Export ephemera
+// @Description Download every ephemera item matching the /ephemera filters as CSV, NDJSON or a JSON array, in ID order
+// @Tags export
+// @Produce json
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "File format (csv, ndjson, json)" default(json)
+// @Param type query string false "Filter by type, e.g. catalog (case-insensitive)"
+// @Param year_from query int false "Filter by year from"
+// @Param year_to query int false "Filter by year to"
+// @Param search query string false "Search in title and description"
+// @Success 200 {array} models.EphemeraResponse
+// @Failure 400 {object} map[string]string "error: Unsupported format"
+// @Router /export/ephemera [get]
+func ExportEphemera(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := filterEphemeraQuery(db, c)
+		streamExport(c, "ephemera", services.EphemeraExportColumns, func(w *services.ExportWriter) error {
+			return services.ExportEphemera(query, w)
+		})
+	}
+}
+
+// ExportManufacturers streams every manufacturer matching the filters
+// @Summary Export manufacturers
+// @Description Download every manufacturer matching the /manufacturers filters as CSV, NDJSON or a JSON array, in ID order
+// @Tags export
+// @Produce json
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "File format (csv, ndjson, json)" default(json)
+// @Param country query string false "Filter by country (case-insensitive)"
+// @Param active_from query int false "Only manufacturers still trading in or after this year"
+// @Param active_to query int false "Only manufacturers founded in or before this year"
+// @Success 200 {array} models.Manufacturer
+// @Failure 400 {object} map[string]string "error: Unsupported format"
+// @Router /export/manufacturers [get]
+func ExportManufacturers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := filterManufacturerQuery(db, c)
+		streamExport(c, "manufacturers", services.ManufacturerExportColumns, func(w *services.ExportWriter) error {
+			return services.ExportManufacturers(query, w)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+// maxImportSize limits the size of an import file.
+const maxImportSize = 10 << 20
+
+// importFormats maps content types and file extensions to import formats.
+var importFormats = map[string]string{
+	"text/csv":             services.ImportCSV,
+	"application/json":     services.ImportJSON,
+	"application/x-ndjson": services.ImportNDJSON,
+	"application/ndjson":   services.ImportNDJSON,
+	".csv":                 services.ImportCSV,
+	".json":                services.ImportJSON,
+	".ndjson":              services.ImportNDJSON,
+	".jsonl":               services.ImportNDJSON,
+}
+
+// readImport returns the uploaded file, from a multipart "file" field or the
+// raw request body, and its format. An explicit "format" query parameter
+// wins over the file extension or content type.
+func readImport(c *gin.Context) ([]byte, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	var data []byte
+	var format string
+
+	contentType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if contentType == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", errors.New("no file provided")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+
+		if data, err = io.ReadAll(file); err != nil {
+			return nil, "", err
+		}
+		format = importFormats[strings.ToLower(filepath.Ext(header.Filename))]
+	} else {
+		var err error
+		if data, err = io.ReadAll(c.Request.Body); err != nil {
+			return nil, "", err
+		}
+		format = importFormats[contentType]
+	}
+
+	if param := c.Query("format"); param != "" {
+		format = param
+	}
+	if format == "" {
+		return nil, "", errors.New("unknown import format: set format to csv, json or ndjson")
+	}
+
+	return data, format, nil
+}
+
+// ImportCameras creates or updates cameras in bulk
+// @Summary Import cameras
+// @Description Create or update cameras from a CSV, JSON or NDJSON file, sent as the request body or a multipart "file" field. CSV needs a header row of CameraRequest field names, with lists separated by ";". Rows matching an existing camera by manufacturer, name and year introduced update it. Every rejected row is reported with its line number (admin only).
+// @Tags admin
+// @Accept text/csv
+// @Accept json
+// @Accept application/x-ndjson
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param format query string false "File format (csv, json, ndjson); defaults to the content type or file extension"
+// @Param dry_run query bool false "Validate every row without saving anything"
+// @Param atomic query bool false "Save nothing unless every row is valid"
+// @Success 200 {object} services.ImportResult
+// @Failure 400 {object} map[string]string "error: Unreadable import file"
+// @Failure 422 {object} services.ImportResult "Atomic import with rejected rows; nothing was saved"
+// @Router /admin/import/cameras [post]
+func ImportCameras(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+		atomic, _ := strconv.ParseBool(c.Query("atomic"))
+
+		data, format, err := readImport(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rows, err := services.ParseCameraImport(data, format)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := services.ImportCameras(authoredDB(db, c), rows, services.ImportOptions{DryRun: dryRun, Atomic: atomic})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Import failed: " + err.Error()})
+			return
+		}
+
+		status := http.StatusOK
+		if atomic && result.Failed > 0 {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, result)
+	}
+}
+
+// ImportSales records auction results in bulk
+// @Summary Import sales
+// @Description Record camera sales from a CSV file, sent as the request body or a multipart "file" field. The header row names the columns: camera_id, or camera with manufacturer where the name is shared, plus sold_on (YYYY-MM-DD), venue, price, currency, condition, lot_url and notes. A row matching a recorded sale of the camera by date, venue and price updates it. Every rejected row is reported with its line number (admin only).
+// @Tags admin
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param dry_run query bool false "Validate every row without saving anything"
+// @Param atomic query bool false "Save nothing unless every row is valid"
+// @Success 200 {object} services.ImportResult
+// @Failure 400 {object} map[string]string "error: Unreadable import file"
+// @Failure 422 {object} services.ImportResult "Atomic import with rejected rows; nothing was saved"
+// @Router /admin/import/sales [post]
+func ImportSales(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+		atomic, _ := strconv.ParseBool(c.Query("atomic"))
+
+		data, format, err := readImport(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rows, err := services.ParseSaleImport(data, format)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := services.ImportSales(db, rows, services.ImportOptions{DryRun: dryRun, Atomic: atomic})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Import failed: " + err.Error()})
+			return
+		}
+
+		status := http.StatusOK
+		if atomic && result.Failed > 0 {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, result)
+	}
+}
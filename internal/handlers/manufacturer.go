@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
+)
+
+// getManufacturerQuery applies the manufacturer filter query parameters and the
+// listing order.
+func getManufacturerQuery(db *gorm.DB, c *gin.Context) *gorm.DB {
+	return filterManufacturerQuery(db, c).Order("name")
+}
+
+// manufacturerKeyset is the cursor pagination order, matching the listing
+// order.
+var manufacturerKeyset = utils.Keyset{
+	{Column: "manufacturers.name"},
+	{Column: "manufacturers.id"},
+}
+
+// filterManufacturerQuery applies the manufacturer filter query parameters shared by
+// the listing and the export.
+func filterManufacturerQuery(db *gorm.DB, c *gin.Context) *gorm.DB {
+	query := db.Model(&models.Manufacturer{})
+
+	// Filter by country (case-insensitive)
+	if country := c.Query("country"); country != "" {
+		query = query.Where("LOWER(country) = LOWER(?)", strings.TrimSpace(country))
+	}
+
+	// Filter by years active: manufacturers trading at any point in the range
+	if activeFrom := c.Query("active_from"); activeFrom != "" {
+		query = query.Where("(defunct IS NULL OR defunct >= ?)", activeFrom)
+	}
+	if activeTo := c.Query("active_to"); activeTo != "" {
+		query = query.Where("founded <= ?", activeTo)
+	}
+
+	return query
+}
+
+// findManufacturer loads the manufacturer named by the "id" path parameter,
+// writing the error response itself when it cannot.
+func findManufacturer(db *gorm.DB, c *gin.Context) (models.Manufacturer, bool) {
+	var manufacturer models.Manufacturer
+	if err := db.First(&manufacturer, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Manufacturer not found"})
+			return manufacturer, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return manufacturer, false
+	}
+	return manufacturer, true
+}
+
+// saveManufacturer validates and stores req, answering with the saved
+// manufacturer or the matching error status.
+func saveManufacturer(db *gorm.DB, c *gin.Context, manufacturer *models.Manufacturer, req *models.ManufacturerRequest, status int) {
+	if err := services.SaveManufacturer(authoredDB(db, c), manufacturer, req); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidManufacturer):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrDuplicateManufacturer):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save manufacturer: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(status, manufacturer)
+}
+
+// GetManufacturers lists manufacturers with pagination
+// @Summary List manufacturers
+// @Description Get a paginated list of manufacturers ordered by name
+// @Tags manufacturers
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param cursor query string false "Page by cursor instead of page number: empty for the first page, then the previous page's next_cursor"
+// @Param country query string false "Filter by country (case-insensitive)"
+// @Param active_from query int false "Only manufacturers still trading in or after this year"
+// @Param active_to query int false "Only manufacturers founded in or before this year"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,name"
+// @Param include query string false "Comma-separated related resources to embed (cameras)"
+// @Success 200 {object} utils.Pagination
+// @Router /manufacturers [get]
+func GetManufacturers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var manufacturers []models.Manufacturer
+		var total int64
+
+		fieldset, err := utils.ParseFieldset(c, models.Manufacturer{}, manufacturerRelations...)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		cursor, err := utils.NewCursorPage(c, manufacturerKeyset)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		query := getManufacturerQuery(db, c)
+
+		var nextCursor string
+		if cursor != nil {
+			nextCursor, err = cursor.Find(query, &manufacturers)
+		} else {
+			query.Count(&total)
+			err = query.Scopes(utils.Paginate(c)).Find(&manufacturers).Error
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve manufacturers"})
+			return
+		}
+
+		responses, err := shapeManufacturers(db, fieldset, manufacturers)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve manufacturers"})
+			return
+		}
+
+		if cursor != nil {
+			c.JSON(http.StatusOK, utils.CreateCursorResponse(c, responses, nextCursor))
+			return
+		}
+		c.JSON(http.StatusOK, utils.CreatePaginationResponse(c, responses, total))
+	}
+}
+
+// GetManufacturer retrieves a single manufacturer by ID
+// @Summary Get a manufacturer
+// @Description Get a manufacturer by ID
+// @Tags manufacturers
+// @Produce json
+// @Param id path int true "Manufacturer ID"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,name"
+// @Param include query string false "Comma-separated related resources to embed (cameras)"
+// @Success 200 {object} models.Manufacturer
+// @Failure 404 {object} map[string]string "error: Manufacturer not found"
+// @Router /manufacturers/{id} [get]
+func GetManufacturer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fieldset, err := utils.ParseFieldset(c, models.Manufacturer{}, manufacturerRelations...)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		manufacturer, ok := findManufacturer(db, c)
+		if !ok {
+			return
+		}
+
+		responses, err := shapeManufacturers(db, fieldset, []models.Manufacturer{manufacturer})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, responses[0])
+	}
+}
+
+// manufacturerRelations are the related resources a manufacturer can embed
+// with the "include" query parameter.
+var manufacturerRelations = []string{"cameras"}
+
+// shapeManufacturers cuts manufacturers down to the requested fieldset,
+// loading their cameras when they are embedded.
+func shapeManufacturers(db *gorm.DB, fieldset *utils.Fieldset, manufacturers []models.Manufacturer) ([]interface{}, error) {
+	var cameras map[uint][]models.CameraSummary
+	if fieldset.Includes("cameras") {
+		ids := make([]uint, len(manufacturers))
+		for i := range manufacturers {
+			ids[i] = manufacturers[i].ID
+		}
+
+		var err error
+		if cameras, err = services.FindManufacturerCameras(db, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	responses := make([]interface{}, len(manufacturers))
+	for i := range manufacturers {
+		manufacturer := &manufacturers[i]
+
+		related := cameras[manufacturer.ID]
+		if related == nil {
+			related = []models.CameraSummary{}
+		}
+
+		response, err := fieldset.Shape(manufacturer, map[string]interface{}{
+			"cameras": related,
+		})
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+
+	return responses, nil
+}
+
+// CreateManufacturer creates a new manufacturer
+// @Summary Create a manufacturer
+// @Description Create a new manufacturer (admin only)
+// @Tags manufacturers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param manufacturer body models.ManufacturerRequest true "Manufacturer object"
+// @Success 201 {object} models.Manufacturer
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 409 {object} map[string]string "error: Manufacturer already exists"
+// @Router /manufacturers [post]
+func CreateManufacturer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ManufacturerRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		var manufacturer models.Manufacturer
+		saveManufacturer(db, c, &manufacturer, &req, http.StatusCreated)
+	}
+}
+
+// UpdateManufacturer replaces a manufacturer
+// @Summary Update a manufacturer
+// @Description Replace a manufacturer by ID; omitted fields are cleared (admin only)
+// @Tags manufacturers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Manufacturer ID"
+// @Param manufacturer body models.ManufacturerRequest true "Manufacturer object"
+// @Success 200 {object} models.Manufacturer
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 404 {object} map[string]string "error: Manufacturer not found"
+// @Failure 409 {object} map[string]string "error: Manufacturer already exists"
+// @Router /manufacturers/{id} [put]
+func UpdateManufacturer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		manufacturer, ok := findManufacturer(db, c)
+		if !ok {
+			return
+		}
+
+		var req models.ManufacturerRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		saveManufacturer(db, c, &manufacturer, &req, http.StatusOK)
+	}
+}
+
+// PatchManufacturer partially updates a manufacturer
+// @Summary Patch a manufacturer
+// @Description Update only the fields present in the body (admin only)
+// @Tags manufacturers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Manufacturer ID"
+// @Param manufacturer body models.ManufacturerRequest true "Fields to change"
+// @Success 200 {object} models.Manufacturer
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 404 {object} map[string]string "error: Manufacturer not found"
+// @Failure 409 {object} map[string]string "error: Manufacturer already exists"
+// @Router /manufacturers/{id} [patch]
+func PatchManufacturer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		manufacturer, ok := findManufacturer(db, c)
+		if !ok {
+			return
+		}
+
+		// Binding onto the current values leaves absent fields unchanged
+		req := manufacturer.ToRequest()
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		saveManufacturer(db, c, &manufacturer, &req, http.StatusOK)
+	}
+}
+
+// DeleteManufacturer deletes a manufacturer
+// @Summary Delete a manufacturer
+// @Description Delete a manufacturer by ID; refused while any camera, including deleted ones, references it (admin only)
+// @Tags manufacturers
+// @Security BearerAuth
+// @Param id path int true "Manufacturer ID"
+// @Success 204
+// @Failure 404 {object} map[string]string "error: Manufacturer not found"
+// @Failure 409 {object} map[string]string "error: Manufacturer is still referenced by cameras"
+// @Router /manufacturers/{id} [delete]
+func DeleteManufacturer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		manufacturer, ok := findManufacturer(db, c)
+		if !ok {
+			return
+		}
+
+		if err := services.DeleteManufacturer(authoredDB(db, c), &manufacturer); err != nil {
+			if errors.Is(err, services.ErrManufacturerInUse) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete manufacturer: " + err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
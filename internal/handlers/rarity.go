@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+)
+
+// GetRarities lists the rarity scale
+// @Summary List rarities
+// @Description Get the rarity scale cameras are rated on, most common first. The rank is what sort=rarity and the rarity_min/rarity_max filters use.
+// @Tags cameras
+// @Produce json
+// @Success 200 {array} models.Rarity
+// @Router /rarities [get]
+func GetRarities(c *gin.Context) {
+	c.JSON(http.StatusOK, models.RarityScale)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
+)
+
+// authoredDB returns db tagged with the authenticated user, who is recorded
+// as the author of any revisions written through it.
+func authoredDB(db *gorm.DB, c *gin.Context) *gorm.DB {
+	ctx := c.Request.Context()
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uint); ok {
+			ctx = services.WithAuthor(ctx, id)
+		}
+	}
+	return db.WithContext(ctx)
+}
+
+// revisionKeyset is the cursor pagination order, newest first.
+var revisionKeyset = utils.Keyset{
+	{Column: "revisions.number", Desc: true},
+	{Column: "revisions.id", Desc: true},
+}
+
+// GetRevisions lists the revision history of one record, newest first
+// @Summary List revisions
+// @Description Get a paginated revision history (author, time and field-level changes) of a camera, ephemera item or manufacturer
+// @Tags revisions
+// @Produce json
+// @Param id path int true "Record ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param cursor query string false "Page by cursor instead of page number: empty for the first page, then the previous page's next_cursor"
+// @Success 200 {object} utils.Pagination
+// @Router /cameras/{id}/revisions [get]
+// @Router /ephemera/{id}/revisions [get]
+// @Router /manufacturers/{id}/revisions [get]
+func GetRevisions(db *gorm.DB, entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var revisions []models.Revision
+		var total int64
+
+		cursor, err := utils.NewCursorPage(c, revisionKeyset)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		query := services.ListRevisions(db, entityType, c.Param("id"))
+
+		var nextCursor string
+		if cursor != nil {
+			nextCursor, err = cursor.Find(query, &revisions)
+		} else {
+			query.Count(&total)
+			err = query.Scopes(utils.Paginate(c)).Find(&revisions).Error
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revisions"})
+			return
+		}
+
+		responses := make([]models.RevisionResponse, len(revisions))
+		for i, revision := range revisions {
+			responses[i] = revision.ToRevisionResponse(false)
+		}
+
+		if cursor != nil {
+			c.JSON(http.StatusOK, utils.CreateCursorResponse(c, responses, nextCursor))
+			return
+		}
+		c.JSON(http.StatusOK, utils.CreatePaginationResponse(c, responses, total))
+	}
+}
+
+// GetRevision retrieves one revision of a record, including its snapshot
+// @Summary Get a revision
+// @Description Get one revision of a camera, ephemera item or manufacturer with the full snapshot of the record at that point
+// @Tags revisions
+// @Produce json
+// @Param id path int true "Record ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} models.RevisionResponse
+// @Failure 404 {object} map[string]string "error: Revision not found"
+// @Router /cameras/{id}/revisions/{rev} [get]
+// @Router /ephemera/{id}/revisions/{rev} [get]
+// @Router /manufacturers/{id}/revisions/{rev} [get]
+func GetRevision(db *gorm.DB, entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		revision, ok := findRevision(db, c, entityType)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, revision.ToRevisionResponse(true))
+	}
+}
+
+// findRevision loads the revision named by the "id" and "rev" path
+// parameters, writing the error response itself when it cannot.
+func findRevision(db *gorm.DB, c *gin.Context, entityType string) (models.Revision, bool) {
+	revision, err := services.FindRevision(db, entityType, c.Param("id"), c.Param("rev"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return revision, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return revision, false
+	}
+	return revision, true
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+// GetRoles lists the roles and their permissions
+// @Summary List roles
+// @Description Get every role with the permissions it grants. The admin role always has every permission. (requires roles:manage)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.RoleResponse
+// @Router /admin/roles [get]
+func GetRoles(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles, err := services.ListRoles(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve roles"})
+			return
+		}
+
+		c.JSON(http.StatusOK, roles)
+	}
+}
+
+// GetPermissions lists the permissions
+// @Summary List permissions
+// @Description Get the names of every permission a role can grant (requires roles:manage)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} string
+// @Router /admin/permissions [get]
+func GetPermissions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, models.Permissions)
+	}
+}
+
+// UpdateRolePermissions replaces the permissions of a role
+// @Summary Set a role's permissions
+// @Description Replace the permissions a role grants. Changes apply to signed-in users at once. The admin role cannot be changed. (requires roles:manage)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param role path string true "Role (viewer, contributor, editor, moderator)"
+// @Param request body models.RolePermissionsRequest true "Permissions"
+// @Success 200 {object} models.RoleResponse
+// @Failure 400 {object} map[string]string "error: Unknown permission"
+// @Router /admin/roles/{role} [put]
+func UpdateRolePermissions(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.RolePermissionsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		role := c.Param("role")
+		if err := services.SetRolePermissions(db, role, req.Permissions); err != nil {
+			if errors.Is(err, services.ErrInvalidRolePermissions) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role: " + err.Error()})
+			return
+		}
+
+		permissions, err := services.RolePermissions(db, role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.RoleResponse{Role: role, Permissions: permissions.List(), Editable: true})
+	}
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
+)
+
+// findSale loads the sale record named by the "id" and "sale" path
+// parameters, writing the error response itself when it cannot.
+func findSale(db *gorm.DB, c *gin.Context) (models.SaleRecord, bool) {
+	sale, err := services.FindSale(db, c.Param("id"), c.Param("sale"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sale record not found"})
+			return sale, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return sale, false
+	}
+	return sale, true
+}
+
+// GetSales lists the recorded sales of a camera, most recent first
+// @Summary List a camera's sales
+// @Description Get a paginated list of the auction results and other recorded sales of a camera, most recent first
+// @Tags sales
+// @Produce json
+// @Param id path int true "Camera ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} utils.Pagination
+// @Failure 404 {object} map[string]string "error: Camera not found"
+// @Router /cameras/{id}/sales [get]
+func GetSales(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		camera, ok := findCamera(db, c)
+		if !ok {
+			return
+		}
+
+		var sales []models.SaleRecord
+		var total int64
+
+		query := services.ListSales(db, camera.ID)
+		query.Count(&total)
+		if err := query.Scopes(utils.Paginate(c)).Find(&sales).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sales"})
+			return
+		}
+
+		responses := make([]models.SaleRecordResponse, len(sales))
+		for i, sale := range sales {
+			responses[i] = sale.ToSaleRecordResponse()
+		}
+
+		c.JSON(http.StatusOK, utils.CreatePaginationResponse(c, responses, total))
+	}
+}
+
+// CreateSale records a sale of a camera
+// @Summary Record a sale
+// @Description Record what a camera sold for: date, venue, price, ISO 4217 currency, condition grade and lot URL or notes
+// @Tags sales
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Camera ID"
+// @Param sale body models.SaleRecordRequest true "Sale record"
+// @Success 201 {object} models.SaleRecordResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 404 {object} map[string]string "error: Camera not found"
+// @Router /cameras/{id}/sales [post]
+func CreateSale(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		camera, ok := findCamera(db, c)
+		if !ok {
+			return
+		}
+
+		var req models.SaleRecordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		sale := models.SaleRecord{CameraID: camera.ID}
+		saveSale(db, c, &sale, &req, http.StatusCreated)
+	}
+}
+
+// UpdateSale replaces a sale record
+// @Summary Update a sale
+// @Description Replace one of a camera's sale records
+// @Tags sales
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Camera ID"
+// @Param sale path int true "Sale record ID"
+// @Param body body models.SaleRecordRequest true "Sale record"
+// @Success 200 {object} models.SaleRecordResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 404 {object} map[string]string "error: Sale record not found"
+// @Router /cameras/{id}/sales/{sale} [put]
+func UpdateSale(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sale, ok := findSale(db, c)
+		if !ok {
+			return
+		}
+
+		var req models.SaleRecordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		saveSale(db, c, &sale, &req, http.StatusOK)
+	}
+}
+
+// saveSale validates and stores req, answering with the saved sale record
+// or the matching error status.
+func saveSale(db *gorm.DB, c *gin.Context, sale *models.SaleRecord, req *models.SaleRecordRequest, status int) {
+	if err := services.SaveSale(db, sale, req); err != nil {
+		if errors.Is(err, services.ErrInvalidSale) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(status, sale.ToSaleRecordResponse())
+}
+
+// DeleteSale removes a sale record
+// @Summary Delete a sale
+// @Description Delete one of a camera's sale records (admin only)
+// @Tags sales
+// @Security BearerAuth
+// @Param id path int true "Camera ID"
+// @Param sale path int true "Sale record ID"
+// @Success 204
+// @Failure 404 {object} map[string]string "error: Sale record not found"
+// @Router /cameras/{id}/sales/{sale} [delete]
+func DeleteSale(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sale, ok := findSale(db, c)
+		if !ok {
+			return
+		}
+
+		if err := services.DeleteSale(db, &sale); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// GetPriceHistory summarises a camera's sale prices per year
+// @Summary Get a camera's price history
+// @Description Get the number of sales and the minimum, median and maximum price of a camera for each year it sold, in one currency. Sales in other currencies are left out, not converted; currencies lists what is available.
+// @Tags sales
+// @Produce json
+// @Param id path int true "Camera ID"
+// @Param currency query string false "ISO 4217 currency; defaults to the one the camera most often sold in"
+// @Success 200 {object} models.PriceHistory
+// @Failure 400 {object} map[string]string "error: Invalid currency"
+// @Failure 404 {object} map[string]string "error: Camera not found"
+// @Router /cameras/{id}/price-history [get]
+func GetPriceHistory(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		camera, ok := findCamera(db, c)
+		if !ok {
+			return
+		}
+
+		history, err := services.GetPriceHistory(db, camera.ID, c.Query("currency"))
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidSale) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve price history"})
+			return
+		}
+
+		c.JSON(http.StatusOK, history)
+	}
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
+)
+
+// findSerialRange loads the serial range named by the "id" path parameter,
+// writing the error response itself when it cannot.
+func findSerialRange(db *gorm.DB, c *gin.Context) (models.SerialRange, bool) {
+	serialRange, err := services.FindSerialRange(db, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Serial range not found"})
+			return serialRange, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return serialRange, false
+	}
+	return serialRange, true
+}
+
+// LookupSerial dates a serial number
+// @Summary Look up a serial number
+// @Description Find the serial ranges a serial number falls in and estimate when it was made, assuming serials were issued at a steady rate through each range. Narrowest ranges come first.
+// @Tags serials
+// @Produce json
+// @Param serial query string true "Serial number, e.g. 12345 or No. 12,345"
+// @Param model query string false "Model name, or part of it, to restrict the lookup to"
+// @Success 200 {object} models.SerialLookup
+// @Failure 400 {object} map[string]string "error: Invalid serial"
+// @Failure 404 {object} map[string]string "error: No serial range covers the serial"
+// @Router /serials/lookup [get]
+func LookupSerial(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serial, err := services.ParseSerial(c.Query("serial"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		lookup, err := services.LookupSerial(db, c.Query("model"), serial)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up serial"})
+			return
+		}
+		if len(lookup.Matches) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No serial range covers the serial"})
+			return
+		}
+
+		c.JSON(http.StatusOK, lookup)
+	}
+}
+
+// GetSerialRanges lists the serial ranges
+// @Summary List serial ranges
+// @Description Get a paginated list of the serial number ranges used for dating, ordered by model and first serial
+// @Tags serials
+// @Produce json
+// @Param model query string false "Model name, or part of it"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} utils.Pagination
+// @Router /serials [get]
+func GetSerialRanges(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ranges []models.SerialRange
+		var total int64
+
+		query := services.ListSerialRanges(db, c.Query("model"))
+		query.Count(&total)
+		if err := query.Scopes(utils.Paginate(c)).Find(&ranges).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve serial ranges"})
+			return
+		}
+
+		responses := make([]models.SerialRangeResponse, len(ranges))
+		for i, serialRange := range ranges {
+			responses[i] = serialRange.ToSerialRangeResponse()
+		}
+
+		c.JSON(http.StatusOK, utils.CreatePaginationResponse(c, responses, total))
+	}
+}
+
+// GetSerialRange gets a serial range by ID
+// @Summary Get a serial range
+// @Description Get one serial number range by ID
+// @Tags serials
+// @Produce json
+// @Param id path int true "Serial range ID"
+// @Success 200 {object} models.SerialRangeResponse
+// @Failure 404 {object} map[string]string "error: Serial range not found"
+// @Router /serials/{id} [get]
+func GetSerialRange(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serialRange, ok := findSerialRange(db, c)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, serialRange.ToSerialRangeResponse())
+	}
+}
+
+// CreateSerialRange adds a serial range
+// @Summary Add a serial range
+// @Description Record that serials start_serial to end_serial of a model were made between date_from and date_to. Ranges of the same model may not overlap.
+// @Tags serials
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param range body models.SerialRangeRequest true "Serial range"
+// @Success 201 {object} models.SerialRangeResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Router /serials [post]
+func CreateSerialRange(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.SerialRangeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		serialRange := models.SerialRange{}
+		saveSerialRange(db, c, &serialRange, &req, http.StatusCreated)
+	}
+}
+
+// UpdateSerialRange replaces a serial range
+// @Summary Update a serial range
+// @Description Replace a serial number range
+// @Tags serials
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Serial range ID"
+// @Param range body models.SerialRangeRequest true "Serial range"
+// @Success 200 {object} models.SerialRangeResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 404 {object} map[string]string "error: Serial range not found"
+// @Router /serials/{id} [put]
+func UpdateSerialRange(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serialRange, ok := findSerialRange(db, c)
+		if !ok {
+			return
+		}
+
+		var req models.SerialRangeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		saveSerialRange(db, c, &serialRange, &req, http.StatusOK)
+	}
+}
+
+// saveSerialRange validates and stores req, answering with the saved serial
+// range or the matching error status.
+func saveSerialRange(db *gorm.DB, c *gin.Context, serialRange *models.SerialRange, req *models.SerialRangeRequest, status int) {
+	if err := services.SaveSerialRange(db, serialRange, req); err != nil {
+		if errors.Is(err, services.ErrInvalidSerialRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(status, serialRange.ToSerialRangeResponse())
+}
+
+// DeleteSerialRange removes a serial range
+// @Summary Delete a serial range
+// @Description Delete a serial number range (admin only)
+// @Tags serials
+// @Security BearerAuth
+// @Param id path int true "Serial range ID"
+// @Success 204
+// @Failure 404 {object} map[string]string "error: Serial range not found"
+// @Router /serials/{id} [delete]
+func DeleteSerialRange(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serialRange, ok := findSerialRange(db, c)
+		if !ok {
+			return
+		}
+
+		if err := services.DeleteSerialRange(db, &serialRange); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
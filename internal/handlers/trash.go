@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
+)
+
+// trashError writes the response for an error from the trash service.
+func trashError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownTrash):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown trash type: use cameras, ephemera or users"})
+	case errors.Is(err, services.ErrNotInTrash):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in trash"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// trashItemID parses the "id" path parameter.
+func trashItemID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetTrash lists soft-deleted records
+// @Summary List the trash
+// @Description Get a paginated list of soft-deleted cameras, ephemera or users, most recently deleted first (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param type path string true "Record type (cameras, ephemera, users)"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} utils.Pagination
+// @Failure 404 {object} map[string]string "error: Unknown trash type"
+// @Router /admin/trash/{type} [get]
+func GetTrash(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, err := services.ListTrash(db, c.Param("type"))
+		if err != nil {
+			trashError(c, err)
+			return
+		}
+
+		var total int64
+		query.Count(&total)
+
+		items := []services.TrashItem{}
+		if err := query.Scopes(utils.Paginate(c)).Scan(&items).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trash"})
+			return
+		}
+
+		c.JSON(http.StatusOK, utils.CreatePaginationResponse(c, items, total))
+	}
+}
+
+// RestoreTrashItem undeletes a soft-deleted record
+// @Summary Restore from the trash
+// @Description Undelete a soft-deleted camera, ephemera item or user (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Param type path string true "Record type (cameras, ephemera, users)"
+// @Param id path int true "Record ID"
+// @Success 204
+// @Failure 404 {object} map[string]string "error: Item not found in trash"
+// @Router /admin/trash/{type}/{id}/restore [post]
+func RestoreTrashItem(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := trashItemID(c)
+		if !ok {
+			return
+		}
+
+		if err := services.RestoreTrash(authoredDB(db, c), c.Param("type"), id); err != nil {
+			trashError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// PurgeTrashItem permanently deletes a soft-deleted record
+// @Summary Purge from the trash
+// @Description Permanently delete a soft-deleted camera, ephemera item or user; this cannot be undone (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Param type path string true "Record type (cameras, ephemera, users)"
+// @Param id path int true "Record ID"
+// @Success 204
+// @Failure 404 {object} map[string]string "error: Item not found in trash"
+// @Router /admin/trash/{type}/{id} [delete]
+func PurgeTrashItem(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := trashItemID(c)
+		if !ok {
+			return
+		}
+
+		if err := services.PurgeTrash(authoredDB(db, c), c.Param("type"), id); err != nil {
+			trashError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
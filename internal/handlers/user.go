@@ -1,45 +1,340 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
 )
 
 // Define a struct to hold the database dependency
 type UserHandler struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Mailer services.Mailer
 }
 
 // NewUserHandler creates a new handler instance
-func NewUserHandler(db *gorm.DB) *UserHandler {
-	return &UserHandler{DB: db}
+func NewUserHandler(db *gorm.DB, mailer services.Mailer) *UserHandler {
+	return &UserHandler{DB: db, Mailer: mailer}
 }
 
-// GetUsers retrieves all users
-// NOTE: This endpoint should typically be protected and requires administrator privileges.
-// @Summary List all users
-// @Description Get a list of all users
+// findUser loads the user named by the "id" path parameter, deleted or not,
+// writing the error response itself when it cannot.
+func (h *UserHandler) findUser(c *gin.Context) (models.User, bool) {
+	user, err := services.FindUser(h.DB, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return user, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return user, false
+	}
+	return user, true
+}
+
+// findManagedUser loads a user like findUser and checks the authenticated
+// user may act on them: only those who can assign roles may act on admins,
+// so that a moderator cannot lock them out.
+func (h *UserHandler) findManagedUser(c *gin.Context) (models.User, bool) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return user, false
+	}
+
+	if _, permissions := currentUser(c); user.Role == models.RoleAdmin && !permissions.Has(models.PermRolesAssign) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only users who can assign roles can manage admin accounts"})
+		return user, false
+	}
+	return user, true
+}
+
+// respondUser answers with the current state of a user after an action.
+func (h *UserHandler) respondUser(c *gin.Context, id uint) {
+	user, err := services.FindUser(h.DB, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, user.ToUserResponse())
+}
+
+// userError writes the response for an error from a user admin action.
+func userError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidUserAction) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// GetUsers retrieves users
+// @Summary List users
+// @Description Get a paginated list of users, oldest first, optionally searched by email or name and filtered by role or status (requires users:manage)
 // @Tags users
 // @Produce json
-// @Success 200 {array} models.UserResponse
+// @Security BearerAuth
+// @Param q query string false "Text to find in email or name"
+// @Param role query string false "Role"
+// @Param status query string false "active, suspended or deleted; all but deleted users by default"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} utils.Pagination
+// @Failure 400 {object} map[string]string "error: Invalid status"
 // @Router /users [get]
 func (h *UserHandler) GetUsers(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && !slices.Contains(services.UserStatuses, status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status: use " + strings.Join(services.UserStatuses, ", ")})
+		return
+	}
+
 	var users []models.User
+	var total int64
 
-	// 1. Fetch all users from the database. GORM automatically excludes soft-deleted records.
-	if err := h.DB.Find(&users).Error; err != nil {
+	query := services.ListUsers(h.DB, c.Query("q"), c.Query("role"), status)
+	query.Count(&total)
+	if err := query.Scopes(utils.Paginate(c)).Find(&users).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
 		return
 	}
 
-	// 2. Convert to safe response struct (excluding the password field)
+	// Convert to safe response struct (excluding the password field)
 	userResponses := make([]models.UserResponse, len(users))
 	for i, user := range users {
 		userResponses[i] = user.ToUserResponse()
 	}
 
-	c.JSON(http.StatusOK, userResponses)
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, utils.CreatePaginationResponse(c, userResponses, total))
+}
+
+// GetUser retrieves one user
+// @Summary Get a user
+// @Description Get a user by ID, including a deleted one (requires users:manage)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.UserResponse
+// @Failure 404 {object} map[string]string "error: User not found"
+// @Router /users/{id} [get]
+func (h *UserHandler) GetUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, user.ToUserResponse())
+}
+
+// GetUserAudit lists the audit trail of a user
+// @Summary List a user's audit trail
+// @Description Get a paginated list of the admin actions taken on a user, newest first (requires users:manage)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} utils.Pagination
+// @Failure 404 {object} map[string]string "error: User not found"
+// @Router /users/{id}/audit [get]
+func (h *UserHandler) GetUserAudit(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	var entries []models.AuditLog
+	var total int64
+
+	query := services.ListAudit(h.DB, user.ID)
+	query.Count(&total)
+	if err := query.Scopes(utils.Paginate(c)).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve audit trail"})
+		return
+	}
+
+	responses := make([]models.AuditLogResponse, len(entries))
+	for i, entry := range entries {
+		responses[i] = entry.ToAuditLogResponse()
+	}
+
+	c.JSON(http.StatusOK, utils.CreatePaginationResponse(c, responses, total))
+}
+
+// UpdateUserRole changes a user's role
+// @Summary Change a user's role
+// @Description Give a user another role, taking effect on their current sessions at once. Users cannot change their own role. (requires roles:assign)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body models.UpdateRoleRequest true "New role"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]string "error: Invalid role"
+// @Failure 404 {object} map[string]string "error: User not found"
+// @Router /users/{id}/role [put]
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	user, ok := h.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ChangeRole(authoredDB(h.DB, c), &user, req.Role); err != nil {
+		userError(c, err)
+		return
+	}
+
+	h.respondUser(c, user.ID)
+}
+
+// SuspendUser suspends a user
+// @Summary Suspend a user
+// @Description Stop a user from signing in and log out all their sessions. Users cannot suspend themselves. (requires users:manage)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body models.SuspendUserRequest false "Reason"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]string "error: The account is already suspended"
+// @Failure 404 {object} map[string]string "error: User not found"
+// @Router /users/{id}/suspend [post]
+func (h *UserHandler) SuspendUser(c *gin.Context) {
+	user, ok := h.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.SuspendUserRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := services.SuspendUser(authoredDB(h.DB, c), &user, req.Reason); err != nil {
+		userError(c, err)
+		return
+	}
+
+	h.respondUser(c, user.ID)
+}
+
+// UnsuspendUser lifts a user's suspension
+// @Summary Unsuspend a user
+// @Description Let a suspended user sign in again (requires users:manage)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]string "error: The account is not suspended"
+// @Failure 404 {object} map[string]string "error: User not found"
+// @Router /users/{id}/unsuspend [post]
+func (h *UserHandler) UnsuspendUser(c *gin.Context) {
+	user, ok := h.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := services.UnsuspendUser(authoredDB(h.DB, c), &user); err != nil {
+		userError(c, err)
+		return
+	}
+
+	h.respondUser(c, user.ID)
+}
+
+// ForcePasswordReset makes a user choose a new password
+// @Summary Force a password reset
+// @Description Log a user out everywhere and keep them from signing in until they choose a new password through the reset link emailed to them (requires users:manage)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]string "error: The account is deleted"
+// @Failure 404 {object} map[string]string "error: User not found"
+// @Router /users/{id}/reset-password [post]
+func (h *UserHandler) ForcePasswordReset(c *gin.Context) {
+	user, ok := h.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := services.ForcePasswordReset(authoredDB(h.DB, c), h.Mailer, &user); err != nil {
+		userError(c, err)
+		return
+	}
+
+	h.respondUser(c, user.ID)
+}
+
+// DeleteUser soft-deletes a user
+// @Summary Delete a user
+// @Description Move a user to the trash and log out all their sessions. They can be restored until the trash is purged. Users cannot delete themselves. (requires users:manage)
+// @Tags users
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} map[string]string "error: The account is deleted"
+// @Failure 404 {object} map[string]string "error: User not found"
+// @Router /users/{id} [delete]
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	user, ok := h.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteUser(authoredDB(h.DB, c), &user); err != nil {
+		userError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreUser undeletes a user
+// @Summary Restore a user
+// @Description Undelete a soft-deleted user (requires users:manage)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]string "error: The account is not deleted"
+// @Failure 404 {object} map[string]string "error: User not found"
+// @Router /users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	user, ok := h.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := services.RestoreUser(authoredDB(h.DB, c), user.ID); err != nil {
+		if errors.Is(err, services.ErrNotInTrash) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The account is not deleted"})
+			return
+		}
+		userError(c, err)
+		return
+	}
+
+	h.respondUser(c, user.ID)
+}
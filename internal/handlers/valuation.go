@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
+)
+
+// findCamera loads the camera named by the "id" path parameter, writing the
+// error response itself when it cannot.
+func findCamera(db *gorm.DB, c *gin.Context) (models.Camera, bool) {
+	var camera models.Camera
+	if err := db.First(&camera, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Camera not found"})
+			return camera, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return camera, false
+	}
+	return camera, true
+}
+
+// findValuation loads the valuation named by the "id" and "valuation" path
+// parameters, writing the error response itself when it cannot.
+func findValuation(db *gorm.DB, c *gin.Context) (models.Valuation, bool) {
+	valuation, err := services.FindValuation(db, c.Param("id"), c.Param("valuation"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Valuation not found"})
+			return valuation, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return valuation, false
+	}
+	return valuation, true
+}
+
+// GetValuations lists the valuations of a camera, newest first
+// @Summary List a camera's valuations
+// @Description Get a paginated history of a camera's valuations, newest first. The camera's current_estimate averages the latest of these.
+// @Tags valuations
+// @Produce json
+// @Param id path int true "Camera ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} utils.Pagination
+// @Failure 404 {object} map[string]string "error: Camera not found"
+// @Router /cameras/{id}/valuations [get]
+func GetValuations(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		camera, ok := findCamera(db, c)
+		if !ok {
+			return
+		}
+
+		var valuations []models.Valuation
+		var total int64
+
+		query := services.ListValuations(db, camera.ID)
+		query.Count(&total)
+		if err := query.Scopes(utils.Paginate(c)).Find(&valuations).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve valuations"})
+			return
+		}
+
+		responses := make([]models.ValuationResponse, len(valuations))
+		for i, valuation := range valuations {
+			responses[i] = valuation.ToValuationResponse()
+		}
+
+		c.JSON(http.StatusOK, utils.CreatePaginationResponse(c, responses, total))
+	}
+}
+
+// CreateValuation adds a valuation to a camera
+// @Summary Add a valuation
+// @Description Record a dated valuation of a camera: amount range, ISO 4217 currency, condition grade and source
+// @Tags valuations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Camera ID"
+// @Param valuation body models.ValuationRequest true "Valuation object"
+// @Success 201 {object} models.ValuationResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 404 {object} map[string]string "error: Camera not found"
+// @Router /cameras/{id}/valuations [post]
+func CreateValuation(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		camera, ok := findCamera(db, c)
+		if !ok {
+			return
+		}
+
+		var req models.ValuationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		valuation := models.Valuation{CameraID: camera.ID}
+		saveValuation(db, c, &valuation, &req, http.StatusCreated)
+	}
+}
+
+// UpdateValuation replaces a valuation
+// @Summary Update a valuation
+// @Description Replace one of a camera's valuations
+// @Tags valuations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Camera ID"
+// @Param valuation path int true "Valuation ID"
+// @Param body body models.ValuationRequest true "Valuation object"
+// @Success 200 {object} models.ValuationResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 404 {object} map[string]string "error: Valuation not found"
+// @Router /cameras/{id}/valuations/{valuation} [put]
+func UpdateValuation(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		valuation, ok := findValuation(db, c)
+		if !ok {
+			return
+		}
+
+		var req models.ValuationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+
+		saveValuation(db, c, &valuation, &req, http.StatusOK)
+	}
+}
+
+// saveValuation validates and stores req, answering with the saved
+// valuation or the matching error status.
+func saveValuation(db *gorm.DB, c *gin.Context, valuation *models.Valuation, req *models.ValuationRequest, status int) {
+	if err := services.SaveValuation(db, valuation, req); err != nil {
+		if errors.Is(err, services.ErrInvalidValuation) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(status, valuation.ToValuationResponse())
+}
+
+// DeleteValuation removes a valuation
+// @Summary Delete a valuation
+// @Description Delete one of a camera's valuations (admin only)
+// @Tags valuations
+// @Security BearerAuth
+// @Param id path int true "Camera ID"
+// @Param valuation path int true "Valuation ID"
+// @Success 204
+// @Failure 404 {object} map[string]string "error: Valuation not found"
+// @Router /cameras/{id}/valuations/{valuation} [delete]
+func DeleteValuation(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		valuation, ok := findValuation(db, c)
+		if !ok {
+			return
+		}
+
+		if err := services.DeleteValuation(db, &valuation); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
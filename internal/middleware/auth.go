@@ -1,14 +1,17 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
 	"github.com/Candoo/thornton-pickard-api/internal/services"
 )
 
-func AuthRequired() gin.HandlerFunc {
+func AuthRequired(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -33,22 +36,89 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
-		// Store user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_role", claims.Role)
+		// The token is only good while its session is
+		user, err := services.CheckSession(db, claims)
+		if err != nil {
+			if errors.Is(err, services.ErrSessionRevoked) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			} else if errors.Is(err, services.ErrAccountSuspended) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Account suspended"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+			}
+			c.Abort()
+			return
+		}
+
+		permissions, err := services.RolePermissions(db, user.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+			c.Abort()
+			return
+		}
+
+		setUser(c, &user, claims, permissions)
 		c.Next()
 	}
 }
 
-func AdminRequired() gin.HandlerFunc {
+// AuthOptional identifies the user like AuthRequired when a valid bearer
+// token is sent, but lets anonymous requests through. It is for public
+// routes that show signed-in users more.
+func AuthOptional(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("user_role")
-		if !exists || role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if claims, err := services.ValidateToken(parts[1]); err == nil {
+				if user, err := services.CheckSession(db, claims); err == nil {
+					if permissions, err := services.RolePermissions(db, user.Role); err == nil {
+						setUser(c, &user, claims, permissions)
+					}
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// setUser stores the authenticated user in the context. The role and its
+// permissions come from the database rather than the token, so changes to
+// either apply at once.
+func setUser(c *gin.Context, user *models.User, claims *services.Claims, permissions models.PermissionSet) {
+	c.Set("user_id", user.ID)
+	c.Set("user_email", user.Email)
+	c.Set("user_role", user.Role)
+	c.Set("user_permissions", permissions)
+	c.Set("session_id", claims.SessionID)
+	c.Set("email_verified", user.EmailVerified())
+}
+
+// VerifiedEmailRequired refuses users who have not verified their email
+// address. It must run after AuthRequired.
+func VerifiedEmailRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("email_verified") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Verify your email address first"})
 			c.Abort()
 			return
 		}
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// RequirePermission refuses users whose role lacks any of permissions. It
+// must run after AuthRequired.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, _ := c.Get("user_permissions")
+		set, _ := granted.(models.PermissionSet)
+		for _, permission := range permissions {
+			if !set.Has(permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Permission required: " + permission})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audited actions taken by admins on user accounts.
+const (
+	AuditRoleChange    = "role_change"
+	AuditSuspend       = "suspend"
+	AuditUnsuspend     = "unsuspend"
+	AuditPasswordReset = "password_reset"
+	AuditDelete        = "delete"
+	AuditRestore       = "restore"
+	AuditPurge         = "purge"
+)
+
+// AuditLog is an immutable record of an action taken on a user account.
+// ActorID is the admin who took it, or nil when it was automatic or the
+// admin's account has since been deleted. Details is JSON.
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	ActorID   *uint     `gorm:"index" json:"actor_id"`
+	Action    string    `gorm:"not null" json:"action"`
+	Details   string    `gorm:"type:text" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuditLogResponse struct {
+	ID        uint                   `json:"id"`
+	UserID    uint                   `json:"user_id"`
+	ActorID   *uint                  `json:"actor_id"`
+	Action    string                 `json:"action"`
+	Details   map[string]interface{} `json:"details"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+func (a *AuditLog) ToAuditLogResponse() AuditLogResponse {
+	resp := AuditLogResponse{
+		ID:        a.ID,
+		UserID:    a.UserID,
+		ActorID:   a.ActorID,
+		Action:    a.Action,
+		Details:   map[string]interface{}{},
+		CreatedAt: a.CreatedAt,
+	}
+
+	if a.Details != "" {
+		json.Unmarshal([]byte(a.Details), &resp.Details)
+	}
+
+	return resp
+}
@@ -1,37 +1,164 @@
 package models
 
 import (
+    "html"
+    "strings"
     "time"
-    "fmt" 
-    "encoding/json"
     "gorm.io/gorm"
 )
 
+// Search queries wrap matched terms in these control characters, which
+// ToCameraResponse turns into <mark> tags once the text is HTML-escaped.
+const (
+	SearchMatchStart = "\x02"
+	SearchMatchEnd   = "\x03"
+)
+
+var highlightMarkup = strings.NewReplacer(SearchMatchStart, "<mark>", SearchMatchEnd, "</mark>")
+
+// highlightHTML escapes search result text and marks its matches.
+func highlightHTML(text string) string {
+	return highlightMarkup.Replace(html.EscapeString(text))
+}
+
 type Camera struct {
-	ID                  uint      `gorm:"primaryKey" json:"id"`
-	Name                string    `gorm:"not null" json:"name"`
-	Manufacturer        string    `gorm:"not null" json:"manufacturer"`
+	ID                  uint          `gorm:"primaryKey" json:"id"`
+	Name                string        `gorm:"not null" json:"name"`
+	ManufacturerID      *uint         `gorm:"index" json:"manufacturer_id"`
+	Manufacturer        *Manufacturer `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"manufacturer,omitempty"`
+	YearIntroduced      int           `json:"year_introduced"`
+	YearDiscontinued    *int          `json:"year_discontinued,omitempty"`
+	Format              string        `json:"format"`
+	PlateSizes          []PlateSize   `gorm:"many2many:camera_plate_sizes" json:"plate_sizes"`
+	Lens                string        `json:"lens"`
+	Shutter             string        `json:"shutter"`
+	Features            []Feature     `gorm:"many2many:camera_features" json:"features"`
+	Description         string        `gorm:"type:text" json:"description"`
+	Images              []CameraImage `gorm:"constraint:OnDelete:CASCADE" json:"images"`
+	Rarity              string        `json:"rarity"`
+	// RarityRank is the position of Rarity on RarityScale, 0 when unrated
+	RarityRank          int           `gorm:"not null;default:0;index" json:"rarity_rank"`
+	Valuations          []Valuation   `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	// Version increases with every update; it is the camera's ETag
+	Version             uint          `gorm:"not null;default:1" json:"-"`
+	CreatedAt           time.Time     `json:"created_at"`
+	UpdatedAt           time.Time     `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Read-only columns populated by full-text search queries
+	SearchScore         *float64      `gorm:"->;-:migration" json:"-"`
+	SearchNameHighlight *string       `gorm:"->;-:migration" json:"-"`
+	SearchSnippet       *string       `gorm:"->;-:migration" json:"-"`
+}
+
+// CameraRequest is the writable shape of a camera accepted by create and
+// update endpoints. List fields are plain names/URLs; the camera service
+// resolves them to PlateSize, Feature and CameraImage rows. Images, when
+// given, replaces ImageURLs and can set captions. The manufacturer is given
+// either by ID or by name, matched against existing manufacturers.
+type CameraRequest struct {
+	Name                string    `json:"name" binding:"required"`
+	ManufacturerID      *uint     `json:"manufacturer_id,omitempty"`
+	Manufacturer        string    `json:"manufacturer,omitempty"`
 	YearIntroduced      int       `json:"year_introduced"`
 	YearDiscontinued    *int      `json:"year_discontinued,omitempty"`
 	Format              string    `json:"format"`
-	PlateSizes          string    `json:"plate_sizes"` // Store as JSON string or comma-separated
+	PlateSizes          []string  `json:"plate_sizes"`
 	Lens                string    `json:"lens"`
 	Shutter             string    `json:"shutter"`
-	Features            string    `json:"features"` // Store as JSON string
-	Description         string    `gorm:"type:text" json:"description"`
-	ImageURLs           string    `json:"image_urls"` // Store as JSON string
+	Features            []string  `json:"features"`
+	Description         string    `json:"description"`
+	ImageURLs           []string  `json:"image_urls"`
+	Images              []CameraImageRequest `json:"images,omitempty" binding:"dive"`
 	Rarity              string    `json:"rarity"`
-	EstimatedValueMin   *float64  `json:"estimated_value_min,omitempty"`
-	EstimatedValueMax   *float64  `json:"estimated_value_max,omitempty"`
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
-	DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// ImageList returns the images of the request in order: Images if given,
+// otherwise ImageURLs with their stored captions kept.
+func (r *CameraRequest) ImageList() []CameraImageRequest {
+	if r.Images != nil {
+		return r.Images
+	}
+	images := make([]CameraImageRequest, len(r.ImageURLs))
+	for i, url := range r.ImageURLs {
+		images[i] = CameraImageRequest{URL: url}
+	}
+	return images
+}
+
+// ApplyTo copies the scalar fields of the request onto camera. Relations are
+// handled separately by the camera service. Rarity is stored in its
+// canonical spelling with its rank.
+func (r *CameraRequest) ApplyTo(c *Camera) {
+	c.Name = r.Name
+	c.YearIntroduced = r.YearIntroduced
+	c.YearDiscontinued = r.YearDiscontinued
+	c.Format = r.Format
+	c.Lens = r.Lens
+	c.Shutter = r.Shutter
+	c.Description = r.Description
+	c.Rarity = r.Rarity
+	c.RarityRank = 0
+	if rarity, ok := ParseRarity(r.Rarity); ok {
+		c.Rarity = rarity.Name
+		c.RarityRank = rarity.Rank
+	}
+}
+
+// ToRequest returns the writable fields of c, used as the document that
+// partial updates are applied to. PlateSizes, Features and Images must be
+// preloaded.
+func (c *Camera) ToRequest() CameraRequest {
+	req := CameraRequest{
+		Name:              c.Name,
+		ManufacturerID:    c.ManufacturerID,
+		YearIntroduced:    c.YearIntroduced,
+		YearDiscontinued:  c.YearDiscontinued,
+		Format:            c.Format,
+		PlateSizes:        make([]string, len(c.PlateSizes)),
+		Lens:              c.Lens,
+		Shutter:           c.Shutter,
+		Features:          make([]string, len(c.Features)),
+		Description:       c.Description,
+		ImageURLs:         make([]string, len(c.Images)),
+		Images:            make([]CameraImageRequest, len(c.Images)),
+		Rarity:            c.Rarity,
+	}
+
+	for i, plateSize := range c.PlateSizes {
+		req.PlateSizes[i] = plateSize.Name
+	}
+	for i, feature := range c.Features {
+		req.Features[i] = feature.Name
+	}
+	for i, image := range c.Images {
+		caption := image.Caption
+		req.ImageURLs[i] = image.URL
+		req.Images[i] = CameraImageRequest{URL: image.URL, Caption: &caption}
+	}
+
+	return req
+}
+
+// CameraSummary is the short form of a camera embedded in other resources.
+type CameraSummary struct {
+	ID             uint   `json:"id"`
+	Name           string `json:"name"`
+	YearIntroduced int    `json:"year_introduced"`
+}
+
+func (c *Camera) ToSummary() CameraSummary {
+	return CameraSummary{
+		ID:             c.ID,
+		Name:           c.Name,
+		YearIntroduced: c.YearIntroduced,
+	}
 }
 
 type CameraResponse struct {
 	ID                  uint      `json:"id"`
 	Name                string    `json:"name"`
-	Manufacturer        string    `json:"manufacturer"`
+	Manufacturer        *ManufacturerSummary `json:"manufacturer"`
 	YearIntroduced      int       `json:"year_introduced"`
 	YearDiscontinued    *int      `json:"year_discontinued,omitempty"`
 	Format              string    `json:"format"`
@@ -42,35 +169,68 @@ type CameraResponse struct {
 	Description         string    `json:"description"`
 	ImageURLs           []string  `json:"image_urls"`
 	Rarity              string    `json:"rarity"`
+	RarityRank          int       `json:"rarity_rank,omitempty"`
 	EstimatedValueRange string    `json:"estimated_value_range,omitempty"`
+	CurrentEstimate     *ValuationEstimate `json:"current_estimate,omitempty"`
 	CreatedAt           time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
+
+	// Only present in search results
+	Score               *float64          `json:"score,omitempty"`
+	Highlights          map[string]string `json:"highlights,omitempty"`
 }
 
 // ToCameraResponse converts a Camera model to the client-friendly CameraResponse model.
+// Manufacturer, PlateSizes, Features, Images and Valuations (newest first) must be preloaded to appear in the response.
 func (c *Camera) ToCameraResponse() CameraResponse {
     resp := CameraResponse{
         ID:             c.ID,
         Name:           c.Name,
-        Manufacturer:   c.Manufacturer,
         YearIntroduced: c.YearIntroduced,
         YearDiscontinued: c.YearDiscontinued,
         Format:         c.Format,
+        PlateSizes:     make([]string, len(c.PlateSizes)),
         Lens:           c.Lens,
         Shutter:        c.Shutter,
+        Features:       make([]string, len(c.Features)),
         Description:    c.Description,
+        ImageURLs:      make([]string, len(c.Images)),
         Rarity:         c.Rarity,
+        RarityRank:     c.RarityRank,
         CreatedAt:      c.CreatedAt,
         UpdatedAt:      c.UpdatedAt,
     }
 
-    json.Unmarshal([]byte(c.PlateSizes), &resp.PlateSizes)
-    json.Unmarshal([]byte(c.Features), &resp.Features)
-    json.Unmarshal([]byte(c.ImageURLs), &resp.ImageURLs)
+    if c.Manufacturer != nil {
+        summary := c.Manufacturer.ToSummary()
+        resp.Manufacturer = &summary
+    }
 
-    if c.EstimatedValueMin != nil && c.EstimatedValueMax != nil {
-        resp.EstimatedValueRange = fmt.Sprintf("$%.0f - $%.0f", *c.EstimatedValueMin, *c.EstimatedValueMax)
+    for i, plateSize := range c.PlateSizes {
+        resp.PlateSizes[i] = plateSize.Name
+    }
+    for i, feature := range c.Features {
+        resp.Features[i] = feature.Name
+    }
+    for i, image := range c.Images {
+        resp.ImageURLs[i] = image.URL
+    }
+
+    if c.SearchScore != nil {
+        resp.Score = c.SearchScore
+        resp.Highlights = map[string]string{}
+        if c.SearchNameHighlight != nil {
+            resp.Highlights["name"] = highlightHTML(*c.SearchNameHighlight)
+        }
+        if c.SearchSnippet != nil && *c.SearchSnippet != "" {
+            resp.Highlights["description"] = highlightHTML(*c.SearchSnippet)
+        }
+    }
+
+    if estimate := EstimateValue(c.Valuations); estimate != nil {
+        resp.CurrentEstimate = estimate
+        resp.EstimatedValueRange = estimate.Range
     }
     
     return resp
-}
\ No newline at end of file
+}
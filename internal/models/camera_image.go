@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// CameraImage is a photograph attached to a camera, ordered by Position.
+type CameraImage struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CameraID  uint      `gorm:"not null;index" json:"camera_id"`
+	URL       string    `gorm:"not null" json:"url"`
+	Caption   string    `json:"caption"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CameraImageRequest is the writable shape of a camera image, as sent in
+// CameraRequest.Images. A nil Caption keeps the caption already stored for
+// the URL.
+type CameraImageRequest struct {
+	URL     string  `json:"url" binding:"required"`
+	Caption *string `json:"caption,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"strings"
+)
+
+// ConditionGrades are the accepted condition grades of a camera example,
+// best first, as used by collectors and auction houses.
+var ConditionGrades = []string{"Mint", "Near Mint", "Excellent", "Very Good", "Good", "Fair", "Poor", "For Parts"}
+
+// ParseCondition returns the condition grade named by value, ignoring case,
+// spacing and hyphens. A blank value is the empty grade; ok is false if
+// value is not a grade.
+func ParseCondition(value string) (grade string, ok bool) {
+	key := normalizeGrade(value)
+	if key == "" {
+		return "", true
+	}
+	for _, grade := range ConditionGrades {
+		if normalizeGrade(grade) == key {
+			return grade, true
+		}
+	}
+	return "", false
+}
+
+func normalizeGrade(value string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	}), " ")
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateLayout is the format of calendar dates in requests and responses.
+const DateLayout = "2006-01-02"
+
+// Date is a calendar date, written as YYYY-MM-DD in JSON. The zero Date is
+// written as null.
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the calendar date of t.
+func NewDate(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.Format(DateLayout))
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value *string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("date must be a YYYY-MM-DD string")
+	}
+	if value == nil || *value == "" {
+		*d = Date{}
+		return nil
+	}
+
+	t, err := time.Parse(DateLayout, *value)
+	if err != nil {
+		return fmt.Errorf("%q is not a YYYY-MM-DD date", *value)
+	}
+	*d = Date{t}
+	return nil
+}
+
+func (d Date) String() string {
+	return d.Format(DateLayout)
+}
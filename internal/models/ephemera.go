@@ -15,8 +15,107 @@ type Ephemera struct {
 	Description    string    `gorm:"type:text" json:"description"`
 	ScanURL        string    `json:"scan_url"`
 	ThumbnailURL   string    `json:"thumbnail_url"`
-	RelatedCameras string    `json:"related_cameras"` // Store as JSON array of camera IDs
+	CameraLinks    []EphemeraCamera `json:"-"`
+	// Version increases with every update; it is the item's ETag
+	Version        uint      `gorm:"not null;default:1" json:"-"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
-}
\ No newline at end of file
+}
+
+// EphemeraRequest is the writable shape of an ephemera item. RelatedCameras
+// replaces all existing camera links.
+type EphemeraRequest struct {
+	Type           string               `json:"type" binding:"required"`
+	Title          string               `json:"title" binding:"required"`
+	Year           int                  `json:"year"`
+	Pages          *int                 `json:"pages"`
+	Description    string               `json:"description"`
+	ScanURL        string               `json:"scan_url"`
+	ThumbnailURL   string               `json:"thumbnail_url"`
+	RelatedCameras []EphemeraCameraLink `json:"related_cameras" binding:"dive"`
+}
+
+// ToRequest returns the writable fields of e, used as the base for partial
+// updates. CameraLinks must be preloaded.
+func (e *Ephemera) ToRequest() EphemeraRequest {
+	links := make([]EphemeraCameraLink, len(e.CameraLinks))
+	for i, link := range e.CameraLinks {
+		links[i] = EphemeraCameraLink{CameraID: link.CameraID, Page: link.Page, Note: link.Note}
+	}
+
+	return EphemeraRequest{
+		Type:           e.Type,
+		Title:          e.Title,
+		Year:           e.Year,
+		Pages:          e.Pages,
+		Description:    e.Description,
+		ScanURL:        e.ScanURL,
+		ThumbnailURL:   e.ThumbnailURL,
+		RelatedCameras: links,
+	}
+}
+
+// ApplyTo copies the scalar fields of the request onto item. Camera links are
+// handled separately by the ephemera service.
+func (r *EphemeraRequest) ApplyTo(e *Ephemera) {
+	e.Type = r.Type
+	e.Title = r.Title
+	e.Year = r.Year
+	e.Pages = r.Pages
+	e.Description = r.Description
+	e.ScanURL = r.ScanURL
+	e.ThumbnailURL = r.ThumbnailURL
+}
+
+type EphemeraResponse struct {
+	ID             uint            `json:"id"`
+	Type           string          `json:"type"`
+	Title          string          `json:"title"`
+	Year           int             `json:"year"`
+	Pages          *int            `json:"pages,omitempty"`
+	Description    string          `json:"description"`
+	ScanURL        string          `json:"scan_url"`
+	ThumbnailURL   string          `json:"thumbnail_url"`
+	RelatedCameras []RelatedCamera `json:"related_cameras"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// ToEphemeraResponse converts an Ephemera model to the client-friendly
+// EphemeraResponse. CameraLinks with their Camera and its Manufacturer must
+// be preloaded for related cameras to appear.
+func (e *Ephemera) ToEphemeraResponse() EphemeraResponse {
+	resp := EphemeraResponse{
+		ID:             e.ID,
+		Type:           e.Type,
+		Title:          e.Title,
+		Year:           e.Year,
+		Pages:          e.Pages,
+		Description:    e.Description,
+		ScanURL:        e.ScanURL,
+		ThumbnailURL:   e.ThumbnailURL,
+		RelatedCameras: make([]RelatedCamera, 0, len(e.CameraLinks)),
+		CreatedAt:      e.CreatedAt,
+		UpdatedAt:      e.UpdatedAt,
+	}
+
+	for _, link := range e.CameraLinks {
+		if link.Camera == nil {
+			continue
+		}
+		related := RelatedCamera{
+			ID:   link.CameraID,
+			Name: link.Camera.Name,
+			Page: link.Page,
+			Note: link.Note,
+		}
+		if link.Camera.Manufacturer != nil {
+			summary := link.Camera.Manufacturer.ToSummary()
+			related.Manufacturer = &summary
+		}
+		resp.RelatedCameras = append(resp.RelatedCameras, related)
+	}
+
+	return resp
+}
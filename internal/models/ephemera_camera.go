@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// EphemeraCamera links an ephemera item to a camera it mentions, optionally
+// recording the page and a note about the mention.
+type EphemeraCamera struct {
+	EphemeraID uint      `gorm:"primaryKey" json:"ephemera_id"`
+	CameraID   uint      `gorm:"primaryKey;index" json:"camera_id"`
+	Page       *int      `json:"page,omitempty"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+	Ephemera   *Ephemera `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Camera     *Camera   `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// EphemeraCameraLink is the writable shape of a link, as sent in
+// EphemeraRequest.RelatedCameras.
+type EphemeraCameraLink struct {
+	CameraID uint   `json:"camera_id" binding:"required"`
+	Page     *int   `json:"page,omitempty"`
+	Note     string `json:"note"`
+}
+
+// RelatedCamera is a linked camera as embedded in ephemera responses.
+type RelatedCamera struct {
+	ID           uint                 `json:"id"`
+	Name         string               `json:"name"`
+	Manufacturer *ManufacturerSummary `json:"manufacturer,omitempty"`
+	Page         *int                 `json:"page,omitempty"`
+	Note         string               `json:"note,omitempty"`
+}
+
+// RelatedEphemera is a linked ephemera item as embedded in camera responses.
+type RelatedEphemera struct {
+	ID    uint   `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	Year  int    `json:"year"`
+	Page  *int   `json:"page,omitempty"`
+	Note  string `json:"note,omitempty"`
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+)
+
+// Example is a specific surviving camera of a model: its serial number,
+// condition, whereabouts and history. Owner and PrivateNotes are private to
+// the user who recorded the example and to admins; Owner is shown to
+// everyone only when OwnerPublic is set, as for museums.
+type Example struct {
+	ID       uint    `gorm:"primaryKey" json:"id"`
+	CameraID uint    `gorm:"not null;index" json:"camera_id"`
+	Camera   *Camera `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	// AddedByID is the user who recorded the example and may change it
+	AddedByID    uint   `gorm:"not null;index" json:"-"`
+	SerialNumber string `gorm:"index" json:"serial_number"`
+	Condition    string `json:"condition"`
+	// Location is where the example is, as precisely as may be made public,
+	// e.g. "Science Museum, London" or "Private collection, Yorkshire"
+	Location     string         `json:"location"`
+	Owner        string         `json:"owner"`
+	OwnerPublic  bool           `gorm:"not null;default:false" json:"owner_public"`
+	Provenance   string         `gorm:"type:text" json:"provenance"`
+	PrivateNotes string         `gorm:"type:text" json:"private_notes"`
+	Images       []ExampleImage `gorm:"constraint:OnDelete:CASCADE" json:"images"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// ExampleImage is a photograph of a surviving example, ordered by Position.
+type ExampleImage struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ExampleID uint      `gorm:"not null;index" json:"example_id"`
+	URL       string    `gorm:"not null" json:"url"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExampleRequest is the writable shape of an example. ImageURLs replaces
+// all existing photos.
+type ExampleRequest struct {
+	SerialNumber string   `json:"serial_number"`
+	Condition    string   `json:"condition"`
+	Location     string   `json:"location"`
+	Owner        string   `json:"owner"`
+	OwnerPublic  bool     `json:"owner_public"`
+	Provenance   string   `json:"provenance"`
+	PrivateNotes string   `json:"private_notes"`
+	ImageURLs    []string `json:"image_urls"`
+}
+
+// ApplyTo copies the scalar fields of the request onto example. Photos are
+// handled separately by the example service.
+func (r *ExampleRequest) ApplyTo(e *Example) {
+	e.SerialNumber = r.SerialNumber
+	e.Condition = r.Condition
+	e.Location = r.Location
+	e.Owner = r.Owner
+	e.OwnerPublic = r.OwnerPublic
+	e.Provenance = r.Provenance
+	e.PrivateNotes = r.PrivateNotes
+}
+
+// ExampleResponse is an example as shown to one reader. The private fields
+// are left out unless the reader may see them.
+type ExampleResponse struct {
+	ID           uint      `json:"id"`
+	CameraID     uint      `json:"camera_id"`
+	SerialNumber string    `json:"serial_number,omitempty"`
+	Condition    string    `json:"condition,omitempty"`
+	Location     string    `json:"location,omitempty"`
+	Owner        string    `json:"owner,omitempty"`
+	OwnerPublic  *bool     `json:"owner_public,omitempty"`
+	Provenance   string    `json:"provenance,omitempty"`
+	PrivateNotes string    `json:"private_notes,omitempty"`
+	ImageURLs    []string  `json:"image_urls"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ToExampleResponse converts an Example to the response for a reader who
+// may or may not see its private fields. Images must be preloaded.
+func (e *Example) ToExampleResponse(private bool) ExampleResponse {
+	resp := ExampleResponse{
+		ID:           e.ID,
+		CameraID:     e.CameraID,
+		SerialNumber: e.SerialNumber,
+		Condition:    e.Condition,
+		Location:     e.Location,
+		Provenance:   e.Provenance,
+		ImageURLs:    make([]string, len(e.Images)),
+		CreatedAt:    e.CreatedAt,
+		UpdatedAt:    e.UpdatedAt,
+	}
+	for i, image := range e.Images {
+		resp.ImageURLs[i] = image.URL
+	}
+
+	if e.OwnerPublic || private {
+		resp.Owner = e.Owner
+	}
+	if private {
+		ownerPublic := e.OwnerPublic
+		resp.OwnerPublic = &ownerPublic
+		resp.PrivateNotes = e.PrivateNotes
+	}
+	return resp
+}
+
+// ManagedBy reports whether the user may change the example and see its
+// private fields: the user who recorded it, or one allowed to manage
+// examples.
+func (e *Example) ManagedBy(userID uint, permissions PermissionSet) bool {
+	return permissions.Has(PermExamplesManage) || (userID != 0 && userID == e.AddedByID)
+}
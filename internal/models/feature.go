@@ -0,0 +1,7 @@
+package models
+
+// Feature is a notable design feature shared across cameras (e.g. "Rising front").
+type Feature struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"not null;uniqueIndex" json:"name"`
+}
@@ -1,5 +1,12 @@
 package models
 
+import (
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
 type Manufacturer struct {
 	ID          uint   `gorm:"primaryKey" json:"id"`
 	Name        string `gorm:"not null;unique" json:"name"`
@@ -7,4 +14,73 @@ type Manufacturer struct {
 	Defunct     *int   `json:"defunct,omitempty"`
 	Country     string `json:"country"`
 	Description string `gorm:"type:text" json:"description"`
-}
\ No newline at end of file
+	// NormalizedName is Name as NormalizeManufacturerName reduces it, kept
+	// unique so spelling variants cannot become separate manufacturers
+	NormalizedName *string `gorm:"uniqueIndex" json:"-"`
+}
+
+// BeforeSave keeps NormalizedName in step with Name.
+func (m *Manufacturer) BeforeSave(tx *gorm.DB) error {
+	normalized := NormalizeManufacturerName(m.Name)
+	m.NormalizedName = &normalized
+	return nil
+}
+
+// ManufacturerRequest is the writable shape of a manufacturer.
+type ManufacturerRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Founded     int    `json:"founded"`
+	Defunct     *int   `json:"defunct"`
+	Country     string `json:"country"`
+	Description string `json:"description"`
+}
+
+// ToRequest returns the writable fields of m, used as the base for partial
+// updates.
+func (m *Manufacturer) ToRequest() ManufacturerRequest {
+	return ManufacturerRequest{
+		Name:        m.Name,
+		Founded:     m.Founded,
+		Defunct:     m.Defunct,
+		Country:     m.Country,
+		Description: m.Description,
+	}
+}
+
+// ApplyTo copies the request onto manufacturer.
+func (r *ManufacturerRequest) ApplyTo(m *Manufacturer) {
+	m.Name = strings.TrimSpace(r.Name)
+	m.Founded = r.Founded
+	m.Defunct = r.Defunct
+	m.Country = r.Country
+	m.Description = r.Description
+}
+
+// ManufacturerSummary is the short form of a manufacturer embedded in other
+// resources.
+type ManufacturerSummary struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Country string `json:"country,omitempty"`
+}
+
+func (m *Manufacturer) ToSummary() ManufacturerSummary {
+	return ManufacturerSummary{
+		ID:      m.ID,
+		Name:    m.Name,
+		Country: m.Country,
+	}
+}
+
+// NormalizeManufacturerName reduces a name to lower-case letters and digits so
+// that spelling variants such as "Thornton-Pickard" and "Thornton Pickard"
+// compare equal.
+func NormalizeManufacturerName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
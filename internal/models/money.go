@@ -0,0 +1,50 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// currencySymbols are the symbols written before amounts in the currencies
+// collectors commonly price in. Other currencies are written with their ISO
+// code after the amount.
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"GBP": "£",
+	"EUR": "€",
+	"JPY": "¥",
+	"AUD": "A$",
+	"CAD": "C$",
+}
+
+// NormalizeCurrency returns the upper-case ISO 4217 code of currency, or ok
+// false if it is not a three-letter code.
+func NormalizeCurrency(currency string) (code string, ok bool) {
+	code = strings.ToUpper(strings.TrimSpace(currency))
+	if len(code) != 3 {
+		return "", false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", false
+		}
+	}
+	return code, true
+}
+
+// FormatMoney writes a whole amount in currency, e.g. "£500" or "500 CHF".
+func FormatMoney(amount float64, currency string) string {
+	if symbol, ok := currencySymbols[currency]; ok {
+		return fmt.Sprintf("%s%.0f", symbol, amount)
+	}
+	return fmt.Sprintf("%.0f %s", amount, currency)
+}
+
+// FormatMoneyRange writes a price range in currency, e.g. "£500 - £800", or
+// a single amount when both ends are equal.
+func FormatMoneyRange(min, max float64, currency string) string {
+	if min == max {
+		return FormatMoney(min, currency)
+	}
+	return FormatMoney(min, currency) + " - " + FormatMoney(max, currency)
+}
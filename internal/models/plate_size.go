@@ -0,0 +1,7 @@
+package models
+
+// PlateSize is a negative/plate format a camera accepts (e.g. "Half-plate").
+type PlateSize struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"not null;uniqueIndex" json:"name"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strings"
+)
+
+// Rarity is one step of the rarity scale. Rank orders the scale from most
+// to least common; cameras with no rarity have rank 0.
+type Rarity struct {
+	Rank int    `json:"rank"`
+	Name string `json:"name"`
+}
+
+// RarityScale lists the accepted rarities, most common first.
+var RarityScale = []Rarity{
+	{Rank: 1, Name: "Common"},
+	{Rank: 2, Name: "Uncommon"},
+	{Rank: 3, Name: "Scarce"},
+	{Rank: 4, Name: "Rare"},
+	{Rank: 5, Name: "Very Rare"},
+	{Rank: 6, Name: "Extremely Rare"},
+}
+
+// rarityAliases maps other wordings found in collector guides and older
+// records onto the scale.
+var rarityAliases = map[string]string{
+	"very common":      "Common",
+	"fairly common":    "Common",
+	"semi rare":        "Scarce",
+	"quite rare":       "Rare",
+	"ultra rare":       "Extremely Rare",
+	"exceedingly rare": "Extremely Rare",
+}
+
+// ParseRarity returns the rarity named by value, ignoring case, spacing and
+// hyphens. A blank value is the zero Rarity; ok is false if value names no
+// rarity on the scale.
+func ParseRarity(value string) (rarity Rarity, ok bool) {
+	key := strings.Join(strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	}), " ")
+	if key == "" {
+		return Rarity{}, true
+	}
+
+	if alias, found := rarityAliases[key]; found {
+		key = strings.ToLower(alias)
+	}
+	for _, rarity := range RarityScale {
+		if strings.ToLower(rarity.Name) == key {
+			return rarity, true
+		}
+	}
+	return Rarity{}, false
+}
+
+// RarityNames returns the names of the scale in order, for error messages.
+func RarityNames() string {
+	names := make([]string, len(RarityScale))
+	for i, rarity := range RarityScale {
+		names[i] = rarity.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Entity types that keep a revision history.
+const (
+	RevisionCamera       = "camera"
+	RevisionEphemera     = "ephemera"
+	RevisionManufacturer = "manufacturer"
+)
+
+// Revision actions.
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+)
+
+// Revision is an immutable record of one change to a catalogue record.
+// Snapshot holds the record's writable fields (its request shape) after the
+// change, or before it for deletions, and Changes the field-level diff from
+// the previous state. Both are JSON.
+type Revision struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	EntityType string    `gorm:"not null;uniqueIndex:idx_revisions_entity" json:"entity_type"`
+	EntityID   uint      `gorm:"not null;uniqueIndex:idx_revisions_entity" json:"entity_id"`
+	Number     int       `gorm:"not null;uniqueIndex:idx_revisions_entity" json:"revision"`
+	Action     string    `gorm:"not null" json:"action"`
+	UserID     *uint     `gorm:"index" json:"user_id"`
+	Snapshot   string    `gorm:"type:text" json:"-"`
+	Changes    string    `gorm:"type:text" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FieldChange is one field's value before and after a revision.
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type RevisionResponse struct {
+	Revision  int                    `json:"revision"`
+	Action    string                 `json:"action"`
+	UserID    *uint                  `json:"user_id"`
+	CreatedAt time.Time              `json:"created_at"`
+	Changes   map[string]FieldChange `json:"changes"`
+	Snapshot  json.RawMessage        `json:"snapshot,omitempty"`
+}
+
+// ToRevisionResponse converts a Revision to its client-friendly form. The
+// snapshot is only included when requested, as it repeats the whole record.
+func (r *Revision) ToRevisionResponse(withSnapshot bool) RevisionResponse {
+	resp := RevisionResponse{
+		Revision:  r.Number,
+		Action:    r.Action,
+		UserID:    r.UserID,
+		CreatedAt: r.CreatedAt,
+		Changes:   map[string]FieldChange{},
+	}
+
+	if r.Changes != "" {
+		json.Unmarshal([]byte(r.Changes), &resp.Changes)
+	}
+	if withSnapshot && r.Snapshot != "" {
+		resp.Snapshot = json.RawMessage(r.Snapshot)
+	}
+
+	return resp
+}
@@ -0,0 +1,95 @@
+package models
+
+// Roles, from least to most trusted. Admins always have every permission;
+// what the other roles may do is stored in role_permissions.
+const (
+	RoleViewer      = "viewer"
+	RoleContributor = "contributor"
+	RoleEditor      = "editor"
+	RoleModerator   = "moderator"
+	RoleAdmin       = "admin"
+)
+
+// Permissions checked by the API.
+const (
+	PermCamerasWrite        = "cameras:write"
+	PermCamerasDelete       = "cameras:delete"
+	PermEphemeraWrite       = "ephemera:write"
+	PermEphemeraDelete      = "ephemera:delete"
+	PermManufacturersWrite  = "manufacturers:write"
+	PermManufacturersDelete = "manufacturers:delete"
+	PermSerialsWrite        = "serials:write"
+	PermSerialsDelete       = "serials:delete"
+	PermValuationsWrite     = "valuations:write"
+	PermValuationsDelete    = "valuations:delete"
+	PermSalesWrite          = "sales:write"
+	PermSalesDelete         = "sales:delete"
+	PermExamplesWrite       = "examples:write"
+	// PermExamplesManage lets a user change and see the private fields of
+	// examples recorded by others
+	PermExamplesManage   = "examples:manage"
+	PermRevisionsRestore = "revisions:restore"
+	PermUploadsWrite     = "uploads:write"
+	PermImportRun        = "import:run"
+	PermTrashManage      = "trash:manage"
+	// PermUsersManage covers viewing, suspending, resetting and deleting
+	// users; giving them roles needs PermRolesAssign
+	PermUsersManage = "users:manage"
+	PermRolesAssign = "roles:assign"
+	PermRolesManage = "roles:manage"
+)
+
+// Permissions lists every permission, in the order they are shown.
+var Permissions = []string{
+	PermCamerasWrite, PermCamerasDelete,
+	PermEphemeraWrite, PermEphemeraDelete,
+	PermManufacturersWrite, PermManufacturersDelete,
+	PermSerialsWrite, PermSerialsDelete,
+	PermValuationsWrite, PermValuationsDelete,
+	PermSalesWrite, PermSalesDelete,
+	PermExamplesWrite, PermExamplesManage,
+	PermRevisionsRestore,
+	PermUploadsWrite,
+	PermImportRun,
+	PermTrashManage,
+	PermUsersManage, PermRolesAssign, PermRolesManage,
+}
+
+// RolePermission grants one permission to every user with a role.
+type RolePermission struct {
+	Role       string `gorm:"primaryKey" json:"role"`
+	Permission string `gorm:"primaryKey" json:"permission"`
+}
+
+// PermissionSet is the set of permissions a user has. The zero value has
+// none.
+type PermissionSet map[string]bool
+
+// Has reports whether the set includes permission.
+func (p PermissionSet) Has(permission string) bool {
+	return p[permission]
+}
+
+// List returns the permissions in the set, in the order of Permissions.
+func (p PermissionSet) List() []string {
+	list := []string{}
+	for _, permission := range Permissions {
+		if p[permission] {
+			list = append(list, permission)
+		}
+	}
+	return list
+}
+
+type RoleResponse struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	// Editable is false for the admin role, which always has every
+	// permission
+	Editable bool `json:"editable"`
+}
+
+// RolePermissionsRequest replaces the permissions of a role.
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+)
+
+// SaleRecord is an observed sale of a camera, typically an auction result,
+// with the price it fetched.
+type SaleRecord struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CameraID  uint      `gorm:"not null;index" json:"camera_id"`
+	Camera    *Camera   `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	SoldOn    time.Time `gorm:"not null;index" json:"sold_on"`
+	// Venue is the auction house, dealer or marketplace
+	Venue     string    `json:"venue"`
+	Price     float64   `gorm:"not null" json:"price"`
+	// Currency is an ISO 4217 code, e.g. "GBP"
+	Currency  string    `gorm:"size:3;not null" json:"currency"`
+	Condition string    `json:"condition"`
+	LotURL    string    `json:"lot_url"`
+	Notes     string    `gorm:"type:text" json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SaleRecordRequest is the writable shape of a sale record.
+type SaleRecordRequest struct {
+	SoldOn    Date    `json:"sold_on"`
+	Venue     string  `json:"venue"`
+	Price     float64 `json:"price"`
+	Currency  string  `json:"currency" binding:"required"`
+	Condition string  `json:"condition"`
+	LotURL    string  `json:"lot_url"`
+	Notes     string  `json:"notes"`
+}
+
+// ApplyTo copies the request onto sale.
+func (r *SaleRecordRequest) ApplyTo(s *SaleRecord) {
+	s.SoldOn = r.SoldOn.Time
+	s.Venue = r.Venue
+	s.Price = r.Price
+	s.Currency = r.Currency
+	s.Condition = r.Condition
+	s.LotURL = r.LotURL
+	s.Notes = r.Notes
+}
+
+type SaleRecordResponse struct {
+	ID             uint      `json:"id"`
+	CameraID       uint      `json:"camera_id"`
+	SoldOn         Date      `json:"sold_on"`
+	Venue          string    `json:"venue,omitempty"`
+	Price          float64   `json:"price"`
+	Currency       string    `json:"currency"`
+	FormattedPrice string    `json:"formatted_price"`
+	Condition      string    `json:"condition,omitempty"`
+	LotURL         string    `json:"lot_url,omitempty"`
+	Notes          string    `json:"notes,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (s *SaleRecord) ToSaleRecordResponse() SaleRecordResponse {
+	return SaleRecordResponse{
+		ID:             s.ID,
+		CameraID:       s.CameraID,
+		SoldOn:         NewDate(s.SoldOn),
+		Venue:          s.Venue,
+		Price:          s.Price,
+		Currency:       s.Currency,
+		FormattedPrice: FormatMoney(s.Price, s.Currency),
+		Condition:      s.Condition,
+		LotURL:         s.LotURL,
+		Notes:          s.Notes,
+		CreatedAt:      s.CreatedAt,
+		UpdatedAt:      s.UpdatedAt,
+	}
+}
+
+// PriceHistory summarises a camera's sales in one currency per calendar
+// year, for charting.
+type PriceHistory struct {
+	CameraID uint   `json:"camera_id"`
+	Currency string `json:"currency"`
+	// Currencies lists every currency the camera has sold in
+	Currencies []string    `json:"currencies"`
+	Years      []PriceYear `json:"years"`
+}
+
+// PriceYear is the spread of one year's sale prices.
+type PriceYear struct {
+	Year   int     `json:"year"`
+	Sales  int     `json:"sales"`
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	Max    float64 `json:"max"`
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"time"
+)
+
+// SerialRange records that serial numbers StartSerial to EndSerial of a
+// camera or shutter model were made between DateFrom and DateTo, as given
+// by Source.
+type SerialRange struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// Model is the model the serials were stamped on, as the source names it
+	Model string `gorm:"not null;index" json:"model"`
+	// CameraID links the range to the catalogue entry for the model, if any
+	CameraID    *uint     `gorm:"index" json:"camera_id"`
+	Camera      *Camera   `gorm:"constraint:OnDelete:SET NULL" json:"-"`
+	StartSerial int       `gorm:"not null;index" json:"start_serial"`
+	EndSerial   int       `gorm:"not null;index" json:"end_serial"`
+	DateFrom    time.Time `gorm:"not null" json:"date_from"`
+	DateTo      time.Time `gorm:"not null" json:"date_to"`
+	Source      string    `json:"source"`
+	Notes       string    `gorm:"type:text" json:"notes"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SerialRangeRequest is the writable shape of a serial range. DateTo
+// defaults to DateFrom when a range is dated to a single day.
+type SerialRangeRequest struct {
+	Model       string `json:"model" binding:"required"`
+	CameraID    *uint  `json:"camera_id"`
+	StartSerial int    `json:"start_serial"`
+	EndSerial   int    `json:"end_serial"`
+	DateFrom    Date   `json:"date_from"`
+	DateTo      Date   `json:"date_to"`
+	Source      string `json:"source"`
+	Notes       string `json:"notes"`
+}
+
+// ApplyTo copies the request onto serial range.
+func (r *SerialRangeRequest) ApplyTo(s *SerialRange) {
+	s.Model = r.Model
+	s.CameraID = r.CameraID
+	s.StartSerial = r.StartSerial
+	s.EndSerial = r.EndSerial
+	s.DateFrom = r.DateFrom.Time
+	s.DateTo = r.DateTo.Time
+	s.Source = r.Source
+	s.Notes = r.Notes
+}
+
+type SerialRangeResponse struct {
+	ID          uint           `json:"id"`
+	Model       string         `json:"model"`
+	Camera      *CameraSummary `json:"camera,omitempty"`
+	StartSerial int            `json:"start_serial"`
+	EndSerial   int            `json:"end_serial"`
+	DateFrom    Date           `json:"date_from"`
+	DateTo      Date           `json:"date_to"`
+	Source      string         `json:"source,omitempty"`
+	Notes       string         `json:"notes,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+func (s *SerialRange) ToSerialRangeResponse() SerialRangeResponse {
+	response := SerialRangeResponse{
+		ID:          s.ID,
+		Model:       s.Model,
+		StartSerial: s.StartSerial,
+		EndSerial:   s.EndSerial,
+		DateFrom:    NewDate(s.DateFrom),
+		DateTo:      NewDate(s.DateTo),
+		Source:      s.Source,
+		Notes:       s.Notes,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+	if s.Camera != nil {
+		summary := s.Camera.ToSummary()
+		response.Camera = &summary
+	}
+	return response
+}
+
+// EstimateDate places serial within the range's dates, assuming serials
+// were issued at a steady rate, and returns the day it was most likely made.
+func (s *SerialRange) EstimateDate(serial int) Date {
+	if s.EndSerial <= s.StartSerial || !s.DateTo.After(s.DateFrom) {
+		return NewDate(s.DateFrom)
+	}
+
+	fraction := float64(serial-s.StartSerial) / float64(s.EndSerial-s.StartSerial)
+	span := s.DateTo.Sub(s.DateFrom)
+	return NewDate(s.DateFrom.Add(time.Duration(fraction * float64(span))))
+}
+
+// SerialMatch is a serial range a looked-up serial falls in, with the date
+// it gives for the serial.
+type SerialMatch struct {
+	EstimatedDate Date                `json:"estimated_date"`
+	Model         string              `json:"model"`
+	Camera        *CameraSummary      `json:"camera,omitempty"`
+	Range         SerialRangeResponse `json:"range"`
+}
+
+// SerialLookup answers a serial number lookup. Matches are ordered with the
+// narrowest, and so most precise, range first.
+type SerialLookup struct {
+	Serial  int           `json:"serial"`
+	Model   string        `json:"model,omitempty"`
+	Matches []SerialMatch `json:"matches"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is one sign-in of a user. It lasts through a chain of refresh
+// tokens, each replacing the one before, and every access token issued in
+// it carries its ID. Revoking the session logs all of them out.
+type Session struct {
+	ID     string `gorm:"primaryKey;size:36" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	User   *User  `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	// ExpiresAt moves forward each time the session is refreshed
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// RevokedReason is why the session ended, e.g. "logout" or "reuse"
+	RevokedReason string    `json:"revoked_reason,omitempty"`
+	UserAgent     string    `json:"user_agent"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// RefreshToken is one link in a session's chain of refresh tokens. Only a
+// SHA-256 hash of the token is stored. A token is used once; presenting a
+// used token again means it was stolen, and ends the session.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	SessionID string     `gorm:"size:36;not null;index"`
+	Session   *Session   `gorm:"constraint:OnDelete:CASCADE"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// RefreshRequest exchanges a refresh token for a new access and refresh
+// token.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -21,10 +22,32 @@ type User struct {
 	FirstName string         `json:"first_name"`
 	LastName  string         `json:"last_name"`
 	
-	Role      string         `gorm:"default:'user'" json:"role"` 
+	Role      string         `gorm:"default:'contributor'" json:"role"` 
+
+	// EmailVerifiedAt is when the user proved they own Email, or nil
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// SuspendedAt is when an admin suspended the account, or nil. Suspended
+	// users cannot sign in.
+	SuspendedAt     *time.Time `json:"suspended_at"`
+	SuspendedReason string     `json:"suspended_reason"`
+	// ResetRequired keeps the user from signing in until they choose a new
+	// password through a reset link.
+	ResetRequired bool `gorm:"not null;default:false" json:"reset_required"`
+}
+
+// EmailVerified reports whether the user has verified their email address.
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 
+// NormalizeEmail returns email as accounts store it: trimmed and lower case,
+// so addresses match whatever case they are typed in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 // HashPassword hashes the user's password
 func (u *User) HashPassword(password string) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -53,9 +76,14 @@ type RegisterRequest struct {
 	LastName  string `json:"last_name" binding:"required"`
 }
 
+// AuthResponse answers a sign-in or refresh. Token is the short-lived
+// access token to send as a bearer token; RefreshToken obtains the next one
+// from /auth/refresh and is replaced every time it is used.
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string    `json:"token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+	User         User      `json:"user"`
 }
 
 type UserResponse struct {
@@ -64,14 +92,40 @@ type UserResponse struct {
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 	Role      string `json:"role"`
+	EmailVerified bool `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	SuspendedAt   *time.Time `json:"suspended_at,omitempty"`
+	SuspendedReason string `json:"suspended_reason,omitempty"`
+	ResetRequired bool `json:"reset_required,omitempty"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
 }
 
 func (u *User) ToUserResponse() UserResponse {
-	return UserResponse{
+	resp := UserResponse{
 		ID:        u.ID,
 		Email:     u.Email,
 		FirstName: u.FirstName, 
 		LastName:  u.LastName,
 		Role:      u.Role,
+		EmailVerified: u.EmailVerified(),
+		CreatedAt: u.CreatedAt,
+		SuspendedAt: u.SuspendedAt,
+		SuspendedReason: u.SuspendedReason,
+		ResetRequired: u.ResetRequired,
 	}
+	if u.DeletedAt.Valid {
+		deletedAt := u.DeletedAt.Time
+		resp.DeletedAt = &deletedAt
+	}
+	return resp
+}
+
+// UpdateRoleRequest changes a user's role.
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// SuspendUserRequest suspends a user, optionally saying why.
+type SuspendUserRequest struct {
+	Reason string `json:"reason"`
 }
\ No newline at end of file
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// Purposes of a UserToken.
+const (
+	TokenEmailVerification = "email_verification"
+	TokenPasswordReset     = "password_reset"
+)
+
+// UserToken is a single-use, expiring token mailed to a user to prove they
+// control their email address. Only a SHA-256 hash of the token is stored.
+type UserToken struct {
+	ID      uint   `gorm:"primaryKey"`
+	UserID  uint   `gorm:"not null;index"`
+	User    *User  `gorm:"constraint:OnDelete:CASCADE"`
+	Purpose string `gorm:"size:32;not null;index"`
+	// Email is the address the token was sent to; a verification token only
+	// verifies that address
+	Email     string     `gorm:"not null"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// UpdateProfileRequest changes the fields sent. Changing the email address
+// needs the current password and a fresh verification.
+type UpdateProfileRequest struct {
+	FirstName       *string `json:"first_name"`
+	LastName        *string `json:"last_name"`
+	Email           *string `json:"email" binding:"omitempty,email"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+)
+
+// EstimateBasis is how many of the latest valuations the current estimate
+// of a camera averages.
+const EstimateBasis = 3
+
+// Valuation is a dated estimate of what a camera in a given condition is
+// worth, with the guide, dealer or auction house it came from.
+type Valuation struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CameraID  uint      `gorm:"not null;index" json:"camera_id"`
+	AmountMin float64   `gorm:"not null" json:"amount_min"`
+	AmountMax float64   `gorm:"not null" json:"amount_max"`
+	// Currency is an ISO 4217 code, e.g. "GBP"
+	Currency  string    `gorm:"size:3;not null" json:"currency"`
+	Condition string    `json:"condition"`
+	ValuedOn  time.Time `gorm:"not null;index" json:"valued_on"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ValuationRequest is the writable shape of a valuation. AmountMax defaults
+// to AmountMin for a single-figure valuation.
+type ValuationRequest struct {
+	AmountMin float64 `json:"amount_min"`
+	AmountMax float64 `json:"amount_max"`
+	Currency  string  `json:"currency" binding:"required"`
+	Condition string  `json:"condition"`
+	ValuedOn  Date    `json:"valued_on"`
+	Source    string  `json:"source"`
+}
+
+// ApplyTo copies the request onto valuation.
+func (r *ValuationRequest) ApplyTo(v *Valuation) {
+	v.AmountMin = r.AmountMin
+	v.AmountMax = r.AmountMax
+	v.Currency = r.Currency
+	v.Condition = r.Condition
+	v.ValuedOn = r.ValuedOn.Time
+	v.Source = r.Source
+}
+
+type ValuationResponse struct {
+	ID        uint      `json:"id"`
+	CameraID  uint      `json:"camera_id"`
+	AmountMin float64   `json:"amount_min"`
+	AmountMax float64   `json:"amount_max"`
+	Currency  string    `json:"currency"`
+	Range     string    `json:"range"`
+	Condition string    `json:"condition,omitempty"`
+	ValuedOn  Date      `json:"valued_on"`
+	Source    string    `json:"source,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (v *Valuation) ToValuationResponse() ValuationResponse {
+	return ValuationResponse{
+		ID:        v.ID,
+		CameraID:  v.CameraID,
+		AmountMin: v.AmountMin,
+		AmountMax: v.AmountMax,
+		Currency:  v.Currency,
+		Range:     FormatMoneyRange(v.AmountMin, v.AmountMax, v.Currency),
+		Condition: v.Condition,
+		ValuedOn:  NewDate(v.ValuedOn),
+		Source:    v.Source,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+	}
+}
+
+// ValuationEstimate is the current estimate of a camera's value, averaged
+// from its latest valuations.
+type ValuationEstimate struct {
+	AmountMin float64 `json:"amount_min"`
+	AmountMax float64 `json:"amount_max"`
+	Currency  string  `json:"currency"`
+	Range     string  `json:"range"`
+	// AsOf is the date of the newest valuation
+	AsOf      Date    `json:"as_of"`
+	// Basis is how many valuations were averaged
+	Basis     int     `json:"basis"`
+}
+
+// EstimateValue averages the latest EstimateBasis valuations in the currency
+// of the newest one, so amounts in different currencies are never mixed.
+// valuations must be sorted newest first; it returns nil when there are none.
+func EstimateValue(valuations []Valuation) *ValuationEstimate {
+	if len(valuations) == 0 {
+		return nil
+	}
+
+	estimate := &ValuationEstimate{
+		Currency: valuations[0].Currency,
+		AsOf:     NewDate(valuations[0].ValuedOn),
+	}
+	for _, valuation := range valuations {
+		if estimate.Basis == EstimateBasis {
+			break
+		}
+		if valuation.Currency != estimate.Currency {
+			continue
+		}
+		estimate.AmountMin += valuation.AmountMin
+		estimate.AmountMax += valuation.AmountMax
+		estimate.Basis++
+	}
+
+	estimate.AmountMin /= float64(estimate.Basis)
+	estimate.AmountMax /= float64(estimate.Basis)
+	estimate.Range = FormatMoneyRange(estimate.AmountMin, estimate.AmountMax, estimate.Currency)
+	return estimate
+}
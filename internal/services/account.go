@@ -0,0 +1,346 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultEmailVerificationTTL is how long a verification link works
+	// when EMAIL_VERIFICATION_EXPIRY is not set.
+	DefaultEmailVerificationTTL = 48 * time.Hour
+	// DefaultPasswordResetTTL is how long a password reset link works when
+	// PASSWORD_RESET_EXPIRY is not set.
+	DefaultPasswordResetTTL = time.Hour
+)
+
+// ErrInvalidUserToken means an emailed token is unknown, expired, already
+// used or for another purpose.
+var ErrInvalidUserToken = errors.New("invalid or expired token")
+
+// RequireVerifiedEmail reports whether unverified accounts are kept from
+// write routes, from REQUIRE_VERIFIED_EMAIL.
+func RequireVerifiedEmail() bool {
+	return os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true"
+}
+
+// frontendLink returns the URL of a page of the frontend, from
+// FRONTEND_URL, carrying token.
+func frontendLink(page, token string) string {
+	base := strings.TrimRight(envOr("FRONTEND_URL", "http://localhost:3000"), "/")
+	return fmt.Sprintf("%s/%s?token=%s", base, page, url.QueryEscape(token))
+}
+
+// issueUserToken stores a new token for purpose, sent to email, and returns
+// it. Earlier unused tokens of user for the same purpose stop working.
+func issueUserToken(db *gorm.DB, user *models.User, purpose, email string, ttl time.Duration) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			Email:     email,
+			TokenHash: hashToken(token),
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	return token, err
+}
+
+// redeemUserToken marks a token for purpose used and returns it. A token
+// can be redeemed once, before it expires.
+func redeemUserToken(tx *gorm.DB, token, purpose string) (models.UserToken, error) {
+	var userToken models.UserToken
+	err := tx.Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hashToken(token), purpose, time.Now()).
+		First(&userToken).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return userToken, ErrInvalidUserToken
+	}
+	if err != nil {
+		return userToken, err
+	}
+
+	result := tx.Model(&userToken).Where("used_at IS NULL").Update("used_at", time.Now())
+	if result.Error != nil {
+		return userToken, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return userToken, ErrInvalidUserToken
+	}
+	return userToken, nil
+}
+
+// SendVerificationEmail mails user a link to verify their email address.
+func SendVerificationEmail(db *gorm.DB, mailer Mailer, user *models.User) error {
+	token, err := issueUserToken(db, user, models.TokenEmailVerification, user.Email,
+		durationSetting("EMAIL_VERIFICATION_EXPIRY", DefaultEmailVerificationTTL))
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease confirm this is your email address by opening the link below:\n\n%s\n\nIf you did not create an account, you can ignore this email.\n",
+			user.FirstName, frontendLink("verify-email", token)),
+	})
+}
+
+// VerifyEmail redeems a verification token and marks its address verified.
+// A token sent to an address the user has since changed does nothing.
+func VerifyEmail(db *gorm.DB, token string) (models.User, error) {
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		userToken, err := redeemUserToken(tx, token, models.TokenEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, userToken.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidUserToken
+			}
+			return err
+		}
+		if !strings.EqualFold(user.Email, userToken.Email) {
+			return ErrInvalidUserToken
+		}
+
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+			return tx.Model(&user).Update("email_verified_at", now).Error
+		}
+		return nil
+	})
+	return user, err
+}
+
+// RequestPasswordReset mails a password reset link to the user with email,
+// if there is one. Callers should answer the same either way, so the
+// endpoint does not reveal which addresses have accounts.
+func RequestPasswordReset(db *gorm.DB, mailer Mailer, email string) error {
+	var user models.User
+	if err := db.Where("LOWER(email) = ?", models.NormalizeEmail(email)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return sendPasswordReset(db, mailer, &user,
+		"Someone asked to reset the password of your account. To choose a new password, open the link below:",
+		"If it was not you, you can ignore this email; your password has not changed.")
+}
+
+// sendPasswordReset mails user a password reset link between intro and
+// outro.
+func sendPasswordReset(db *gorm.DB, mailer Mailer, user *models.User, intro, outro string) error {
+	token, err := issueUserToken(db, user, models.TokenPasswordReset, user.Email,
+		durationSetting("PASSWORD_RESET_EXPIRY", DefaultPasswordResetTTL))
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\n%s\n\n%s\n\n%s\n",
+			user.FirstName, intro, frontendLink("reset-password", token), outro),
+	})
+}
+
+// ResetPassword redeems a password reset token and sets a new password.
+// Every session of the user is revoked, a reset required by an admin is
+// satisfied, and, since the user has shown they
+// can read mail sent to it, their email address counts as verified.
+func ResetPassword(db *gorm.DB, token, password string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		userToken, err := redeemUserToken(tx, token, models.TokenPasswordReset)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, userToken.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidUserToken
+			}
+			return err
+		}
+
+		if err := user.HashPassword(password); err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"password": user.Password, "reset_required": false}
+		if user.EmailVerifiedAt == nil && strings.EqualFold(user.Email, userToken.Email) {
+			updates["email_verified_at"] = time.Now()
+		}
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		return RevokeUserSessions(tx, user.ID, RevokedPassword, "")
+	})
+}
+
+var (
+	// ErrIncorrectPassword means the current password given to confirm an
+	// account change is wrong.
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	// ErrEmailTaken means another account already uses the email address.
+	ErrEmailTaken = errors.New("email address is already in use")
+	// ErrInvalidProfile is wrapped by errors caused by bad profile input.
+	ErrInvalidProfile = errors.New("invalid profile")
+)
+
+// UpdateProfile applies the fields of req that were sent to user. A new
+// email address must be confirmed with the current password; it starts out
+// unverified and is sent a verification link, and the old address is told
+// of the change.
+func UpdateProfile(db *gorm.DB, mailer Mailer, user *models.User, req *models.UpdateProfileRequest) error {
+	updates := map[string]interface{}{}
+	for column, value := range map[string]*string{"first_name": req.FirstName, "last_name": req.LastName} {
+		if value == nil {
+			continue
+		}
+		if strings.TrimSpace(*value) == "" {
+			return fmt.Errorf("%w: %s cannot be blank", ErrInvalidProfile, column)
+		}
+		updates[column] = strings.TrimSpace(*value)
+	}
+
+	oldEmail := user.Email
+	var newEmail string
+	if req.Email != nil {
+		newEmail = models.NormalizeEmail(*req.Email)
+	}
+	emailChanged := req.Email != nil && newEmail != models.NormalizeEmail(user.Email)
+	if emailChanged {
+		if !user.CheckPassword(req.CurrentPassword) {
+			return ErrIncorrectPassword
+		}
+
+		// Deleted accounts keep their address until purged
+		var count int64
+		if err := db.Unscoped().Model(&models.User{}).Where("LOWER(email) = ? AND id <> ?", newEmail, user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrEmailTaken
+		}
+		updates["email"] = newEmail
+		updates["email_verified_at"] = nil
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := db.Model(user).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	if emailChanged {
+		if err := SendVerificationEmail(db, mailer, user); err != nil {
+			return err
+		}
+		return mailer.Send(Message{
+			To:      oldEmail,
+			Subject: "Your email address was changed",
+			Body: fmt.Sprintf("Hello %s,\n\nThe email address of your account was changed to %s. If you did not do this, reset your password straight away.\n",
+				user.FirstName, user.Email),
+		})
+	}
+	return nil
+}
+
+// ChangePassword sets a new password for user after checking the current
+// one, and logs out every other session. The session making the change,
+// keepSession, stays signed in.
+func ChangePassword(db *gorm.DB, mailer Mailer, user *models.User, keepSession, current, password string) error {
+	if !user.CheckPassword(current) {
+		return ErrIncorrectPassword
+	}
+	if err := user.HashPassword(password); err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password", user.Password).Error; err != nil {
+			return err
+		}
+		return RevokeUserSessions(tx, user.ID, RevokedPasswordChange, keepSession)
+	})
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: fmt.Sprintf("Hello %s,\n\nThe password of your account was changed and your other sessions were logged out. If you did not do this, reset your password straight away.\n",
+			user.FirstName),
+	})
+}
+
+// DeleteAccount permanently deletes user after checking their password.
+// What they contributed stays, without their name on it.
+func DeleteAccount(db *gorm.DB, user *models.User, password string) error {
+	if !user.CheckPassword(password) {
+		return ErrIncorrectPassword
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := eraseUserData(tx, user.ID); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(user).Error
+	})
+}
+
+// eraseUserData removes a user's sessions and tokens and detaches them from
+// the content they authored: revisions and audited admin actions lose their
+// author, and surviving examples they recorded lose the private details only
+// they could see.
+func eraseUserData(tx *gorm.DB, userID uint) error {
+	if err := tx.Model(&models.Revision{}).Where("user_id = ?", userID).Update("user_id", nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.AuditLog{}).Where("actor_id = ?", userID).Update("actor_id", nil).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.Example{}).Where("added_by_id = ? AND owner_public = ?", userID, false).Update("owner", "").Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Example{}).Where("added_by_id = ?", userID).
+		Updates(map[string]interface{}{"added_by_id": 0, "private_notes": ""}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.UserToken{}).Error; err != nil {
+		return err
+	}
+	sessions := tx.Model(&models.Session{}).Select("id").Where("user_id = ?", userID)
+	if err := tx.Where("session_id IN (?)", sessions).Delete(&models.RefreshToken{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&models.Session{}).Error
+}
@@ -2,41 +2,77 @@ package services
 
 import (
 	"errors"
+	"log"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/Candoo/thornton-pickard-api/internal/models"
 )
 
+// DefaultAccessTokenTTL is how long access tokens last when JWT_EXPIRY is
+// not set. They are kept short because the refresh token renews them.
+const DefaultAccessTokenTTL = 15 * time.Minute
+
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
 	Role   string `json:"role"`
+	// SessionID is the session the token was issued in
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(user *models.User) (string, error) {
+// AccessTokenTTL returns how long access tokens last, from JWT_EXPIRY.
+func AccessTokenTTL() time.Duration {
+	return durationSetting("JWT_EXPIRY", DefaultAccessTokenTTL)
+}
+
+// durationSetting reads a duration such as "15m" from the environment
+// variable name, falling back to fallback when it is unset or invalid.
+func durationSetting(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("Warning: invalid %s %q, using %s", name, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// GenerateToken issues an access token for user in the session sessionID,
+// with a unique ID, and returns it with its expiry.
+func GenerateToken(user *models.User, sessionID string) (string, time.Time, error) {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
 		secret = "your-secret-key-change-this-in-production"
 	}
 
-	expirationTime := time.Now().Add(24 * time.Hour)
+	expirationTime := time.Now().Add(AccessTokenTTL())
 	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	signed, err := token.SignedString([]byte(secret))
+	return signed, expirationTime, err
 }
 
+// ValidateToken checks the signature and expiry of an access token. Whether
+// its session is still live is checked separately by CheckSession.
 func ValidateToken(tokenString string) (*Claims, error) {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
@@ -46,7 +82,7 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
@@ -56,5 +92,10 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	// Tokens from before sessions existed cannot be revoked
+	if claims.SessionID == "" {
+		return nil, errors.New("token has no session")
+	}
+
 	return claims, nil
-}
\ No newline at end of file
+}
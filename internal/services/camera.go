@@ -0,0 +1,349 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCamera is wrapped by errors caused by the camera input rather than
+// the database, so handlers can answer 400 instead of 500.
+var ErrInvalidCamera = errors.New("invalid camera")
+
+// PreloadCameraRelations is a query scope that loads everything
+// ToCameraResponse needs.
+func PreloadCameraRelations(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Manufacturer").
+		Preload("PlateSizes", func(db *gorm.DB) *gorm.DB { return db.Order("plate_sizes.name") }).
+		Preload("Features", func(db *gorm.DB) *gorm.DB { return db.Order("features.name") }).
+		Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("camera_images.position") }).
+		Preload("Valuations", func(db *gorm.DB) *gorm.DB { return db.Order("valuations.valued_on DESC, valuations.id DESC") })
+}
+
+// FindCamera loads a camera with its relations.
+func FindCamera(db *gorm.DB, id interface{}) (models.Camera, error) {
+	var camera models.Camera
+	err := db.Scopes(PreloadCameraRelations).First(&camera, id).Error
+	return camera, err
+}
+
+// FindCameras loads cameras with their relations, keyed by ID.
+func FindCameras(db *gorm.DB, ids []uint) (map[uint]models.Camera, error) {
+	var cameras []models.Camera
+	if err := db.Scopes(PreloadCameraRelations).Where("id IN ?", ids).Find(&cameras).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[uint]models.Camera, len(cameras))
+	for _, camera := range cameras {
+		found[camera.ID] = camera
+	}
+	return found, nil
+}
+
+// FindManufacturerCameras returns the cameras made by each of the
+// manufacturers, oldest first.
+func FindManufacturerCameras(db *gorm.DB, manufacturerIDs []uint) (map[uint][]models.CameraSummary, error) {
+	var cameras []models.Camera
+	err := db.
+		Where("manufacturer_id IN ?", manufacturerIDs).
+		Order("year_introduced, name").
+		Find(&cameras).Error
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[uint][]models.CameraSummary, len(manufacturerIDs))
+	for _, camera := range cameras {
+		found[*camera.ManufacturerID] = append(found[*camera.ManufacturerID], camera.ToSummary())
+	}
+	return found, nil
+}
+
+// CreateCamera inserts a camera and its plate sizes, features and images in
+// a single transaction, recording the first revision.
+func CreateCamera(db *gorm.DB, req *models.CameraRequest) (models.Camera, error) {
+	var camera models.Camera
+	if err := validateRarity(req); err != nil {
+		return camera, err
+	}
+	req.ApplyTo(&camera)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		manufacturerID, err := resolveCameraManufacturer(tx, req)
+		if err != nil {
+			return err
+		}
+		camera.ManufacturerID = &manufacturerID
+
+		if err := tx.Omit(clause.Associations).Create(&camera).Error; err != nil {
+			return err
+		}
+		if err := SetCameraRelations(tx, &camera, req.PlateSizes, req.Features, req.ImageList()); err != nil {
+			return err
+		}
+
+		camera, err = FindCamera(tx, camera.ID)
+		if err != nil {
+			return err
+		}
+		after := camera.ToRequest()
+		return RecordRevision(tx, models.RevisionCamera, camera.ID, models.RevisionCreate, nil, &after)
+	})
+
+	return camera, err
+}
+
+// UpdateCamera overwrites camera with the contents of req, replacing its
+// plate sizes, features and images. It fails with ErrVersionConflict if the
+// camera was updated since it was read.
+func UpdateCamera(db *gorm.DB, camera *models.Camera, req *models.CameraRequest) error {
+	return updateCamera(db, camera, req, models.RevisionUpdate)
+}
+
+// RestoreCameraRevision puts camera back to the state recorded in revision.
+// The restore is itself recorded as a new revision.
+func RestoreCameraRevision(db *gorm.DB, camera *models.Camera, revision *models.Revision) error {
+	var req models.CameraRequest
+	if err := json.Unmarshal([]byte(revision.Snapshot), &req); err != nil {
+		return err
+	}
+	return updateCamera(db, camera, &req, models.RevisionRestore)
+}
+
+func updateCamera(db *gorm.DB, camera *models.Camera, req *models.CameraRequest, action string) error {
+	if err := validateRarity(req); err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		current, err := FindCamera(tx, camera.ID)
+		if err != nil {
+			return err
+		}
+		before := current.ToRequest()
+
+		// Fail if the camera changed since the caller read it
+		if err := claimVersion(tx, &models.Camera{}, camera.ID, camera.Version); err != nil {
+			return err
+		}
+		camera.Version++
+
+		req.ApplyTo(camera)
+		manufacturerID, err := resolveCameraManufacturer(tx, req)
+		if err != nil {
+			return err
+		}
+		camera.ManufacturerID = &manufacturerID
+		camera.Manufacturer = nil
+
+		if err := tx.Omit(clause.Associations).Save(camera).Error; err != nil {
+			return err
+		}
+		if err := SetCameraRelations(tx, camera, req.PlateSizes, req.Features, req.ImageList()); err != nil {
+			return err
+		}
+		if err := touchLinkedEphemera(tx, camera.ID); err != nil {
+			return err
+		}
+
+		updated, err := FindCamera(tx, camera.ID)
+		if err != nil {
+			return err
+		}
+		*camera = updated
+		after := camera.ToRequest()
+		return RecordRevision(tx, models.RevisionCamera, camera.ID, action, &before, &after)
+	})
+	return err
+}
+
+// validateRarity rejects a rarity that is not on the rarity scale.
+func validateRarity(req *models.CameraRequest) error {
+	if _, ok := models.ParseRarity(req.Rarity); !ok {
+		return fmt.Errorf("%w: unknown rarity %q (use %s)", ErrInvalidCamera, req.Rarity, models.RarityNames())
+	}
+	return nil
+}
+
+// resolveCameraManufacturer returns the ID of the manufacturer named in req,
+// preferring an explicit manufacturer_id over a name match.
+func resolveCameraManufacturer(tx *gorm.DB, req *models.CameraRequest) (uint, error) {
+	if req.ManufacturerID != nil {
+		var manufacturer models.Manufacturer
+		if err := tx.First(&manufacturer, *req.ManufacturerID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, fmt.Errorf("%w: unknown manufacturer_id %d", ErrInvalidCamera, *req.ManufacturerID)
+			}
+			return 0, err
+		}
+		return manufacturer.ID, nil
+	}
+
+	if strings.TrimSpace(req.Manufacturer) == "" {
+		return 0, fmt.Errorf("%w: manufacturer or manufacturer_id is required", ErrInvalidCamera)
+	}
+
+	manufacturer, err := FindManufacturerByName(tx, req.Manufacturer)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("%w: unknown manufacturer %q", ErrInvalidCamera, req.Manufacturer)
+		}
+		return 0, err
+	}
+	return manufacturer.ID, nil
+}
+
+// SetCameraRelations replaces the plate sizes, features and images of camera.
+// Unknown plate size and feature names are created on the fly. Images are
+// matched by URL, so those kept keep their ID and caption unless a new
+// caption is given.
+func SetCameraRelations(tx *gorm.DB, camera *models.Camera, plateSizes, features []string, images []models.CameraImageRequest) error {
+	sizes, err := FindOrCreatePlateSizes(tx, plateSizes)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(camera).Association("PlateSizes").Replace(sizes); err != nil {
+		return err
+	}
+
+	feats, err := FindOrCreateFeatures(tx, features)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(camera).Association("Features").Replace(feats); err != nil {
+		return err
+	}
+
+	return setCameraImages(tx, camera.ID, images)
+}
+
+// setCameraImages updates the images of a camera to match images, in order.
+// Blank and repeated URLs are dropped.
+func setCameraImages(tx *gorm.DB, cameraID uint, images []models.CameraImageRequest) error {
+	var existing []models.CameraImage
+	if err := tx.Where("camera_id = ?", cameraID).Order("position, id").Find(&existing).Error; err != nil {
+		return err
+	}
+	stored := make(map[string]models.CameraImage, len(existing))
+	for _, image := range existing {
+		if _, ok := stored[image.URL]; !ok {
+			stored[image.URL] = image
+		}
+	}
+
+	kept := make(map[uint]bool, len(existing))
+	seen := make(map[string]bool, len(images))
+	position := 0
+	for _, request := range images {
+		url := strings.TrimSpace(request.URL)
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+
+		image, ok := stored[url]
+
+		caption := image.Caption
+		if request.Caption != nil {
+			caption = strings.TrimSpace(*request.Caption)
+		}
+
+		if !ok {
+			image = models.CameraImage{CameraID: cameraID, URL: url, Caption: caption, Position: position}
+			if err := tx.Create(&image).Error; err != nil {
+				return err
+			}
+		} else if image.Position != position || image.Caption != caption {
+			if err := tx.Model(&image).Updates(map[string]interface{}{"position": position, "caption": caption}).Error; err != nil {
+				return err
+			}
+		}
+		kept[image.ID] = true
+		position++
+	}
+
+	removed := make([]uint, 0, len(existing))
+	for _, image := range existing {
+		if !kept[image.ID] {
+			removed = append(removed, image.ID)
+		}
+	}
+	if len(removed) > 0 {
+		return tx.Delete(&models.CameraImage{}, removed).Error
+	}
+	return nil
+}
+
+// FindOrCreatePlateSizes returns the plate sizes with the given names,
+// creating any that do not exist yet.
+func FindOrCreatePlateSizes(tx *gorm.DB, names []string) ([]models.PlateSize, error) {
+	sizes := make([]models.PlateSize, 0, len(names))
+	for _, name := range uniqueNames(names) {
+		size := models.PlateSize{Name: name}
+		if err := tx.Where("name = ?", name).FirstOrCreate(&size).Error; err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// FindOrCreateFeatures returns the features with the given names, creating
+// any that do not exist yet.
+func FindOrCreateFeatures(tx *gorm.DB, names []string) ([]models.Feature, error) {
+	features := make([]models.Feature, 0, len(names))
+	for _, name := range uniqueNames(names) {
+		feature := models.Feature{Name: name}
+		if err := tx.Where("name = ?", name).FirstOrCreate(&feature).Error; err != nil {
+			return nil, err
+		}
+		features = append(features, feature)
+	}
+	return features, nil
+}
+
+// uniqueNames trims names and drops blanks and duplicates, keeping order.
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// DeleteCamera soft-deletes a camera and records its final state as a
+// revision. Its ephemera links are kept, hidden, for a restore. Deleting a
+// missing camera is a no-op.
+func DeleteCamera(db *gorm.DB, id interface{}) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		camera, err := FindCamera(tx, id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		before := camera.ToRequest()
+
+		if err := touchLinkedEphemera(tx, camera.ID); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Camera{}, camera.ID).Error; err != nil {
+			return err
+		}
+		return RecordRevision(tx, models.RevisionCamera, camera.ID, models.RevisionDelete, &before, nil)
+	})
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidEphemera is wrapped by errors caused by bad ephemera input.
+var ErrInvalidEphemera = errors.New("invalid ephemera")
+
+// PreloadEphemeraRelations is a query scope that loads everything
+// ToEphemeraResponse needs. Links to cameras in the trash are left out.
+func PreloadEphemeraRelations(db *gorm.DB) *gorm.DB {
+	return db.
+		// Links by page, with unpaged links last on every dialect
+		Preload("CameraLinks", func(db *gorm.DB) *gorm.DB {
+			return db.
+				Where("ephemera_cameras.camera_id IN (?)", liveIDs(db, &models.Camera{})).
+				Order("ephemera_cameras.page IS NULL, ephemera_cameras.page, ephemera_cameras.camera_id")
+		}).
+		Preload("CameraLinks.Camera").
+		Preload("CameraLinks.Camera.Manufacturer")
+}
+
+// FindEphemera loads an ephemera item with its linked cameras.
+func FindEphemera(db *gorm.DB, id interface{}) (models.Ephemera, error) {
+	var item models.Ephemera
+	err := db.Scopes(PreloadEphemeraRelations).First(&item, id).Error
+	return item, err
+}
+
+// SaveEphemera applies req to item and creates or updates it, replacing its
+// camera links and recording a revision, in a single transaction. Updates
+// fail with ErrVersionConflict if the item changed since it was read.
+func SaveEphemera(db *gorm.DB, item *models.Ephemera, req *models.EphemeraRequest) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		action := models.RevisionCreate
+		var before *models.EphemeraRequest
+		if item.ID != 0 {
+			current, err := FindEphemera(tx, item.ID)
+			if err != nil {
+				return err
+			}
+			currentReq := current.ToRequest()
+			before = &currentReq
+			action = models.RevisionUpdate
+
+			// Fail if the item changed since the caller read it
+			if err := claimVersion(tx, &models.Ephemera{}, item.ID, item.Version); err != nil {
+				return err
+			}
+			item.Version++
+		}
+
+		req.ApplyTo(item)
+		item.CameraLinks = nil
+
+		if err := tx.Omit(clause.Associations).Save(item).Error; err != nil {
+			return err
+		}
+		// Cameras embed the item under "ephemera", both those it was linked
+		// to and those it is linked to now
+		if err := touchLinkedCameras(tx, item.ID); err != nil {
+			return err
+		}
+		if err := SetEphemeraCameras(tx, item.ID, req.RelatedCameras); err != nil {
+			return err
+		}
+		if err := touchLinkedCameras(tx, item.ID); err != nil {
+			return err
+		}
+
+		saved, err := FindEphemera(tx, item.ID)
+		if err != nil {
+			return err
+		}
+		*item = saved
+		after := item.ToRequest()
+		return RecordRevision(tx, models.RevisionEphemera, item.ID, action, before, &after)
+	})
+}
+
+// liveIDs is a subquery of the IDs of the records of model that are not in
+// the trash. Links to trashed records are kept so that restoring them brings
+// the links back, so reads of links filter on it.
+func liveIDs(db *gorm.DB, model interface{}) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(model).Select("id")
+}
+
+// SetEphemeraCameras replaces the camera links of an ephemera item. Every
+// camera must exist and may be linked only once. Links to cameras in the
+// trash are not shown, so they are kept.
+func SetEphemeraCameras(tx *gorm.DB, ephemeraID uint, links []models.EphemeraCameraLink) error {
+	rows := make([]models.EphemeraCamera, 0, len(links))
+	ids := make([]uint, 0, len(links))
+	seen := make(map[uint]bool, len(links))
+	for _, link := range links {
+		if seen[link.CameraID] {
+			return fmt.Errorf("%w: camera %d is linked more than once", ErrInvalidEphemera, link.CameraID)
+		}
+		seen[link.CameraID] = true
+		ids = append(ids, link.CameraID)
+		rows = append(rows, models.EphemeraCamera{
+			EphemeraID: ephemeraID,
+			CameraID:   link.CameraID,
+			Page:       link.Page,
+			Note:       link.Note,
+		})
+	}
+
+	if len(ids) > 0 {
+		var count int64
+		if err := tx.Model(&models.Camera{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != int64(len(ids)) {
+			return fmt.Errorf("%w: related_cameras contains unknown camera IDs", ErrInvalidEphemera)
+		}
+	}
+
+	if err := tx.Where("ephemera_id = ? AND camera_id IN (?)", ephemeraID, liveIDs(tx, &models.Camera{})).Delete(&models.EphemeraCamera{}).Error; err != nil {
+		return err
+	}
+	if len(rows) > 0 {
+		return tx.Create(&rows).Error
+	}
+	return nil
+}
+
+// DeleteEphemera soft-deletes an ephemera item and records its final state
+// as a revision. Its camera links are kept, hidden, for a restore.
+// CameraLinks must be preloaded. It fails with ErrVersionConflict if the
+// item changed since it was read.
+func DeleteEphemera(db *gorm.DB, item *models.Ephemera) error {
+	before := item.ToRequest()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := claimVersion(tx, &models.Ephemera{}, item.ID, item.Version); err != nil {
+			return err
+		}
+		if err := touchLinkedCameras(tx, item.ID); err != nil {
+			return err
+		}
+		if err := tx.Delete(item).Error; err != nil {
+			return err
+		}
+		return RecordRevision(tx, models.RevisionEphemera, item.ID, models.RevisionDelete, &before, nil)
+	})
+}
+
+// FindRelatedEphemera returns the ephemera linked to each of the cameras,
+// oldest first. Items in the trash are left out.
+func FindRelatedEphemera(db *gorm.DB, cameraIDs []uint) (map[uint][]models.RelatedEphemera, error) {
+	var links []models.EphemeraCamera
+	err := db.
+		Joins("Ephemera").
+		Where("ephemera_cameras.camera_id IN ?", cameraIDs).
+		Where("ephemera_cameras.ephemera_id IN (?)", liveIDs(db, &models.Ephemera{})).
+		// The join alias is quoted, so the columns must be too for Postgres
+		Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+			{Column: clause.Column{Table: "Ephemera", Name: "year"}},
+			{Column: clause.Column{Table: "Ephemera", Name: "title"}},
+		}}).
+		Find(&links).Error
+	if err != nil {
+		return nil, err
+	}
+
+	related := make(map[uint][]models.RelatedEphemera, len(cameraIDs))
+	for _, link := range links {
+		related[link.CameraID] = append(related[link.CameraID], models.RelatedEphemera{
+			ID:    link.EphemeraID,
+			Type:  link.Ephemera.Type,
+			Title: link.Ephemera.Title,
+			Year:  link.Ephemera.Year,
+			Page:  link.Page,
+			Note:  link.Note,
+		})
+	}
+	return related, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidExample is wrapped by errors caused by bad example input.
+var ErrInvalidExample = errors.New("invalid example")
+
+// ListExamples returns a query for the recorded surviving examples of a
+// camera, oldest record first, with their photos.
+func ListExamples(db *gorm.DB, cameraID interface{}) *gorm.DB {
+	return db.Model(&models.Example{}).
+		Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Where("camera_id = ?", cameraID).
+		Order("id")
+}
+
+// FindExample loads one example of a camera with its photos.
+func FindExample(db *gorm.DB, cameraID, id interface{}) (models.Example, error) {
+	var example models.Example
+	err := ListExamples(db, cameraID).Where("id = ?", id).First(&example).Error
+	return example, err
+}
+
+// SaveExample validates req, applies it to example and creates or updates
+// the row and its photos.
+func SaveExample(db *gorm.DB, example *models.Example, req *models.ExampleRequest) error {
+	if err := validateExample(req); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		req.ApplyTo(example)
+		if err := tx.Omit("Camera", "Images").Save(example).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("example_id = ?", example.ID).Delete(&models.ExampleImage{}).Error; err != nil {
+			return err
+		}
+		example.Images = make([]models.ExampleImage, 0, len(req.ImageURLs))
+		for i, url := range req.ImageURLs {
+			example.Images = append(example.Images, models.ExampleImage{ExampleID: example.ID, URL: url, Position: i})
+		}
+		if len(example.Images) > 0 {
+			return tx.Create(&example.Images).Error
+		}
+		return nil
+	})
+}
+
+// DeleteExample removes an example and its photos.
+func DeleteExample(db *gorm.DB, example *models.Example) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("example_id = ?", example.ID).Delete(&models.ExampleImage{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(example).Error
+	})
+}
+
+// validateExample checks req and normalises its condition and photos.
+func validateExample(req *models.ExampleRequest) error {
+	req.SerialNumber = strings.TrimSpace(req.SerialNumber)
+	req.Location = strings.TrimSpace(req.Location)
+	req.Owner = strings.TrimSpace(req.Owner)
+
+	condition, ok := models.ParseCondition(req.Condition)
+	if !ok {
+		return fmt.Errorf("%w: unknown condition %q (use %s)", ErrInvalidExample, req.Condition, strings.Join(models.ConditionGrades, ", "))
+	}
+	req.Condition = condition
+
+	if req.OwnerPublic && req.Owner == "" {
+		return fmt.Errorf("%w: owner_public needs an owner", ErrInvalidExample)
+	}
+
+	urls := make([]string, 0, len(req.ImageURLs))
+	for _, url := range req.ImageURLs {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	req.ImageURLs = urls
+
+	return nil
+}
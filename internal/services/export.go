@@ -0,0 +1,261 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many records are loaded per query while exporting.
+const exportBatchSize = 500
+
+// Export formats share their names with the import formats, so a CSV camera
+// export can be imported again.
+const (
+	ExportCSV    = ImportCSV
+	ExportJSON   = ImportJSON
+	ExportNDJSON = ImportNDJSON
+)
+
+// ErrInvalidExport means the requested export format is not supported.
+var ErrInvalidExport = errors.New("invalid export")
+
+var exportContentTypes = map[string]string{
+	ExportCSV:    "text/csv; charset=utf-8",
+	ExportJSON:   "application/json; charset=utf-8",
+	ExportNDJSON: "application/x-ndjson",
+}
+
+// Columns of the CSV exports. Camera columns are the ones accepted by the
+// CSV importer.
+var (
+	CameraExportColumns = []string{
+		"id", "name", "manufacturer", "year_introduced", "year_discontinued", "format",
+		"plate_sizes", "lens", "shutter", "features", "description", "image_urls",
+		"rarity",
+	}
+	EphemeraExportColumns = []string{
+		"id", "type", "title", "year", "pages", "description", "scan_url", "thumbnail_url", "related_camera_ids",
+	}
+	ManufacturerExportColumns = []string{
+		"id", "name", "founded", "defunct", "country", "description",
+	}
+)
+
+// ExportContentType returns the Content-Type of an export format.
+func ExportContentType(format string) (string, error) {
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return "", fmt.Errorf("%w: unsupported format %q (use csv, json or ndjson)", ErrInvalidExport, format)
+	}
+	return contentType, nil
+}
+
+// ExportWriter writes exported records to w as they are loaded, flushing
+// after every batch so large exports never sit in memory.
+type ExportWriter struct {
+	w       io.Writer
+	format  string
+	csv     *csv.Writer
+	columns []string
+	rows    int
+}
+
+// NewExportWriter starts an export in format. columns are the CSV header.
+func NewExportWriter(w io.Writer, format string, columns []string) (*ExportWriter, error) {
+	if _, err := ExportContentType(format); err != nil {
+		return nil, err
+	}
+
+	export := &ExportWriter{w: w, format: format, columns: columns}
+	if format == ExportCSV {
+		export.csv = csv.NewWriter(w)
+	}
+	return export, nil
+}
+
+// Rows returns how many records have been written.
+func (e *ExportWriter) Rows() int {
+	return e.rows
+}
+
+// Write adds one record: value is marshalled for JSON formats and record,
+// matching the columns, is written for CSV.
+func (e *ExportWriter) Write(value interface{}, record []string) error {
+	if e.format == ExportCSV {
+		if e.rows == 0 {
+			if err := e.csv.Write(e.columns); err != nil {
+				return err
+			}
+		}
+		e.rows++
+		return e.csv.Write(record)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case e.format == ExportNDJSON:
+		data = append(data, '\n')
+	case e.rows == 0:
+		data = append([]byte("[\n"), data...)
+	default:
+		data = append([]byte(",\n"), data...)
+	}
+
+	e.rows++
+	_, err = e.w.Write(data)
+	return err
+}
+
+// Flush sends everything written so far to the client.
+func (e *ExportWriter) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// Close ends the export, writing the header of an empty CSV file or closing
+// the JSON array.
+func (e *ExportWriter) Close() error {
+	switch e.format {
+	case ExportCSV:
+		if e.rows == 0 {
+			if err := e.csv.Write(e.columns); err != nil {
+				return err
+			}
+		}
+	case ExportJSON:
+		closing := "\n]\n"
+		if e.rows == 0 {
+			closing = "[]\n"
+		}
+		if _, err := io.WriteString(e.w, closing); err != nil {
+			return err
+		}
+	}
+	return e.Flush()
+}
+
+// ExportCameras writes every camera matched by query, in ID order.
+func ExportCameras(query *gorm.DB, w *ExportWriter) error {
+	var cameras []models.Camera
+	return query.Scopes(PreloadCameraRelations).FindInBatches(&cameras, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range cameras {
+			camera := &cameras[i]
+			if err := w.Write(camera.ToCameraResponse(), cameraExportRecord(camera)); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	}).Error
+}
+
+// ExportEphemera writes every ephemera item matched by query, in ID order.
+func ExportEphemera(query *gorm.DB, w *ExportWriter) error {
+	var items []models.Ephemera
+	return query.Scopes(PreloadEphemeraRelations).FindInBatches(&items, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range items {
+			item := &items[i]
+			if err := w.Write(item.ToEphemeraResponse(), ephemeraExportRecord(item)); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	}).Error
+}
+
+// ExportManufacturers writes every manufacturer matched by query, in ID
+// order.
+func ExportManufacturers(query *gorm.DB, w *ExportWriter) error {
+	var manufacturers []models.Manufacturer
+	return query.FindInBatches(&manufacturers, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range manufacturers {
+			manufacturer := &manufacturers[i]
+			if err := w.Write(manufacturer, manufacturerExportRecord(manufacturer)); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	}).Error
+}
+
+func cameraExportRecord(c *models.Camera) []string {
+	req := c.ToRequest()
+
+	manufacturer := ""
+	if c.Manufacturer != nil {
+		manufacturer = c.Manufacturer.Name
+	}
+
+	return []string{
+		strconv.FormatUint(uint64(c.ID), 10),
+		req.Name,
+		manufacturer,
+		strconv.Itoa(req.YearIntroduced),
+		formatOptionalInt(req.YearDiscontinued),
+		req.Format,
+		strings.Join(req.PlateSizes, ImportListSeparator),
+		req.Lens,
+		req.Shutter,
+		strings.Join(req.Features, ImportListSeparator),
+		req.Description,
+		strings.Join(req.ImageURLs, ImportListSeparator),
+		req.Rarity,
+	}
+}
+
+func ephemeraExportRecord(e *models.Ephemera) []string {
+	cameraIDs := make([]string, len(e.CameraLinks))
+	for i, link := range e.CameraLinks {
+		cameraIDs[i] = strconv.FormatUint(uint64(link.CameraID), 10)
+	}
+
+	return []string{
+		strconv.FormatUint(uint64(e.ID), 10),
+		e.Type,
+		e.Title,
+		strconv.Itoa(e.Year),
+		formatOptionalInt(e.Pages),
+		e.Description,
+		e.ScanURL,
+		e.ThumbnailURL,
+		strings.Join(cameraIDs, ImportListSeparator),
+	}
+}
+
+func manufacturerExportRecord(m *models.Manufacturer) []string {
+	return []string{
+		strconv.FormatUint(uint64(m.ID), 10),
+		m.Name,
+		strconv.Itoa(m.Founded),
+		formatOptionalInt(m.Defunct),
+		m.Country,
+		m.Description,
+	}
+}
+
+func formatOptionalInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
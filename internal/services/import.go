@@ -0,0 +1,398 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Import formats accepted by ParseCameraImport.
+const (
+	ImportCSV    = "csv"
+	ImportJSON   = "json"
+	ImportNDJSON = "ndjson"
+)
+
+// ImportListSeparator separates the values of list columns (plate_sizes,
+// features, image_urls) in CSV imports.
+const ImportListSeparator = ";"
+
+// ErrInvalidImport is wrapped by errors that make a whole import unreadable,
+// as opposed to errors in individual rows.
+var ErrInvalidImport = errors.New("invalid import")
+
+// errImportRolledBack aborts the import transaction for dry runs and failed
+// all-or-nothing imports.
+var errImportRolledBack = errors.New("import rolled back")
+
+// ImportRow is one camera read from an import file. Err is set when the row
+// itself could not be parsed.
+type ImportRow struct {
+	Line   int
+	Camera models.CameraRequest
+	Err    error
+}
+
+func (r ImportRow) describe() (int, string, error) { return r.Line, r.Camera.Name, r.Err }
+
+// importRecord is a row read from an import file: its line, the name it is
+// reported under and the error from parsing it, if any.
+type importRecord interface {
+	describe() (line int, name string, err error)
+}
+
+// ImportOptions controls how ImportCameras writes rows.
+type ImportOptions struct {
+	// DryRun validates every row against the database and writes nothing
+	DryRun bool
+	// Atomic writes nothing unless every row succeeds
+	Atomic bool
+}
+
+// ImportError is a row that could not be imported.
+type ImportError struct {
+	Line  int    `json:"line"`
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportResult reports the outcome of an import. Created and Updated count
+// the rows that were written, or would have been for dry runs and rolled
+// back imports.
+type ImportResult struct {
+	Rows      int           `json:"rows"`
+	Created   int           `json:"created"`
+	Updated   int           `json:"updated"`
+	Failed    int           `json:"failed"`
+	DryRun    bool          `json:"dry_run"`
+	Committed bool          `json:"committed"`
+	Errors    []ImportError `json:"errors"`
+}
+
+// ParseCameraImport reads cameras in the given format. CSV files need a
+// header row naming CameraRequest fields, with list values separated by
+// ImportListSeparator; JSON is an array and NDJSON one object per line, both
+// in the CameraRequest shape. Rows that cannot be parsed are returned with
+// Err set; an error is returned only when the file as a whole is unreadable.
+func ParseCameraImport(data []byte, format string) ([]ImportRow, error) {
+	switch format {
+	case ImportCSV:
+		return parseCSVImport(data)
+	case ImportJSON:
+		return parseJSONImport(data)
+	case ImportNDJSON:
+		return parseNDJSONImport(data)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q (use csv, json or ndjson)", ErrInvalidImport, format)
+	}
+}
+
+// csvImportColumns maps each accepted CSV header to the field it sets.
+var csvImportColumns = map[string]func(req *models.CameraRequest, value string) error{
+	// Rows are matched by their natural key, so the id column of an export is
+	// ignored
+	"id":           func(req *models.CameraRequest, value string) error { return nil },
+	"name":         func(req *models.CameraRequest, value string) error { req.Name = value; return nil },
+	"manufacturer": func(req *models.CameraRequest, value string) error { req.Manufacturer = value; return nil },
+	"manufacturer_id": func(req *models.CameraRequest, value string) error {
+		id, err := parseOptionalUint(value)
+		req.ManufacturerID = id
+		return err
+	},
+	"year_introduced": func(req *models.CameraRequest, value string) error {
+		year, err := parseOptionalInt(value)
+		if year != nil {
+			req.YearIntroduced = *year
+		}
+		return err
+	},
+	"year_discontinued": func(req *models.CameraRequest, value string) (err error) {
+		req.YearDiscontinued, err = parseOptionalInt(value)
+		return err
+	},
+	"format":      func(req *models.CameraRequest, value string) error { req.Format = value; return nil },
+	"plate_sizes": func(req *models.CameraRequest, value string) error { req.PlateSizes = splitImportList(value); return nil },
+	"lens":        func(req *models.CameraRequest, value string) error { req.Lens = value; return nil },
+	"shutter":     func(req *models.CameraRequest, value string) error { req.Shutter = value; return nil },
+	"features":    func(req *models.CameraRequest, value string) error { req.Features = splitImportList(value); return nil },
+	"description": func(req *models.CameraRequest, value string) error { req.Description = value; return nil },
+	"image_urls":  func(req *models.CameraRequest, value string) error { req.ImageURLs = splitImportList(value); return nil },
+	"rarity":      func(req *models.CameraRequest, value string) error { req.Rarity = value; return nil },
+}
+
+func parseCSVImport(data []byte) ([]ImportRow, error) {
+	records, err := parseCSVRecords(data, csvImportColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]ImportRow, len(records))
+	for i, record := range records {
+		rows[i] = ImportRow{Line: record.Line, Camera: record.Value, Err: record.Err}
+	}
+	return rows, nil
+}
+
+// csvRecord is one data row of a CSV import. Err is set when the row could
+// not be read into Value.
+type csvRecord[T any] struct {
+	Line  int
+	Value T
+	Err   error
+}
+
+// parseCSVRecords reads a CSV file whose header row names the columns, each
+// of which must be a key of columns, and sets every row's values through
+// them.
+func parseCSVRecords[T any](data []byte, columns map[string]func(*T, string) error) ([]csvRecord[T], error) {
+	// Spreadsheet exports often start with a byte order mark
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	setters := make([]func(*T, string) error, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		setter, ok := columns[column]
+		if !ok {
+			return nil, fmt.Errorf("%w: line 1: unknown column %q", ErrInvalidImport, column)
+		}
+		header[i] = column
+		setters[i] = setter
+	}
+
+	var records []csvRecord[T]
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		line, _ := reader.FieldPos(0)
+		record := csvRecord[T]{Line: line}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+			record.Err = fmt.Errorf("expected %d fields, found %d", len(header), len(fields))
+			records = append(records, record)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+
+		for i, value := range fields {
+			if err := setters[i](&record.Value, strings.TrimSpace(value)); err != nil {
+				record.Err = fmt.Errorf("%s: %v", header[i], err)
+				break
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func parseJSONImport(data []byte) ([]ImportRow, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("%w: expected a JSON array of cameras", ErrInvalidImport)
+	}
+
+	var rows []ImportRow
+	for decoder.More() {
+		line := lineAt(data, decoder.InputOffset())
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, line, err)
+		}
+
+		row := ImportRow{Line: line}
+		row.Err = decodeImportObject(raw, &row.Camera)
+		rows = append(rows, row)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	return rows, nil
+}
+
+func parseNDJSONImport(data []byte) ([]ImportRow, error) {
+	var rows []ImportRow
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		row := ImportRow{Line: line}
+		row.Err = decodeImportObject(text, &row.Camera)
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	return rows, nil
+}
+
+// decodeImportObject decodes one JSON camera, rejecting unknown fields.
+func decodeImportObject(data []byte, req *models.CameraRequest) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(req)
+}
+
+// lineAt returns the line of the first value at or after offset, skipping
+// the whitespace and comma between array elements.
+func lineAt(data []byte, offset int64) int {
+	for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n,", rune(data[offset])) {
+		offset++
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+func splitImportList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ImportListSeparator)
+}
+
+func parseOptionalInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a whole number", value)
+	}
+	return &n, nil
+}
+
+func parseOptionalUint(value string) (*uint, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid ID", value)
+	}
+	id := uint(n)
+	return &id, nil
+}
+
+// ImportCameras creates or updates cameras from parsed rows. A row updates
+// the camera with the same manufacturer, name (ignoring case) and year
+// introduced, and creates one otherwise. Each row is written in its own
+// savepoint so a failing row leaves the others intact, unless opts asks for
+// a dry run or an all-or-nothing import.
+func ImportCameras(db *gorm.DB, rows []ImportRow, opts ImportOptions) (ImportResult, error) {
+	return importRows(db, rows, opts, func(tx *gorm.DB, row *ImportRow) (bool, error) {
+		return importCamera(tx, &row.Camera)
+	})
+}
+
+// importRows writes the rows that parsed through write, which reports
+// whether it created a record, and counts and reports the outcome of every
+// row. The rows share one transaction, rolled back for a dry run or a failed
+// all-or-nothing import.
+func importRows[R importRecord](db *gorm.DB, rows []R, opts ImportOptions, write func(tx *gorm.DB, row *R) (bool, error)) (ImportResult, error) {
+	result := ImportResult{Rows: len(rows), DryRun: opts.DryRun, Errors: []ImportError{}}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i := range rows {
+			line, name, err := rows[i].describe()
+			if err == nil {
+				var created bool
+				created, err = write(tx, &rows[i])
+				if err == nil && created {
+					result.Created++
+				} else if err == nil {
+					result.Updated++
+				}
+			}
+
+			if err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, ImportError{
+					Line:  line,
+					Name:  name,
+					Error: err.Error(),
+				})
+			}
+		}
+
+		if opts.DryRun || (opts.Atomic && result.Failed > 0) {
+			return errImportRolledBack
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRolledBack) {
+		return result, err
+	}
+
+	result.Committed = err == nil
+	return result, nil
+}
+
+// importCamera upserts one camera by its natural key, reporting whether it
+// was created.
+func importCamera(db *gorm.DB, req *models.CameraRequest) (bool, error) {
+	created := false
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if strings.TrimSpace(req.Name) == "" {
+			return fmt.Errorf("%w: name is required", ErrInvalidCamera)
+		}
+
+		manufacturerID, err := resolveCameraManufacturer(tx, req)
+		if err != nil {
+			return err
+		}
+
+		var existing models.Camera
+		err = tx.Where("manufacturer_id = ? AND LOWER(name) = LOWER(?) AND year_introduced = ?",
+			manufacturerID, strings.TrimSpace(req.Name), req.YearIntroduced).
+			First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			created = true
+			_, err = CreateCamera(tx, req)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		return UpdateCamera(tx, &existing, req)
+	})
+
+	return created, err
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email. NewMailer picks the implementation from the
+// environment.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewMailer returns the mailer named by MAIL_DRIVER: "smtp", "file" or, by
+// default, "log".
+func NewMailer() Mailer {
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     envOr("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     mailFrom(),
+		}
+	case "file":
+		return &FileMailer{Dir: envOr("MAIL_DIR", "./mail")}
+	case "", "log":
+		return LogMailer{}
+	default:
+		log.Printf("Warning: unknown MAIL_DRIVER %q, logging email instead", driver)
+		return LogMailer{}
+	}
+}
+
+// envOr returns the environment variable name, or fallback when it is unset.
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// mailFrom returns the sender address of outgoing email, from MAIL_FROM.
+func mailFrom() string {
+	return envOr("MAIL_FROM", "Thornton-Pickard API <no-reply@thorntonpickard.com>")
+}
+
+// formatMessage renders msg as an RFC 5322 message.
+func formatMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// SMTPMailer sends email through an SMTP server, authenticating with PLAIN
+// auth when a username is set.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	from := m.From
+	if start, end := strings.LastIndex(from, "<"), strings.LastIndex(from, ">"); start >= 0 && end > start {
+		from = from[start+1 : end]
+	}
+	return smtp.SendMail(m.Host+":"+m.Port, auth, from, []string{msg.To}, formatMessage(m.From, msg))
+}
+
+// unsafeFilename matches the characters left out of FileMailer file names.
+var unsafeFilename = regexp.MustCompile(`[^A-Za-z0-9@._-]+`)
+
+// FileMailer writes each email to its own .eml file in Dir instead of
+// sending it, for local development.
+type FileMailer struct {
+	Dir string
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000000"), unsafeFilename.ReplaceAllString(msg.To, "_"))
+	return os.WriteFile(filepath.Join(m.Dir, name), formatMessage(mailFrom(), msg), 0644)
+}
+
+// LogMailer writes email to the log instead of sending it.
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	log.Printf("📧 Email to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// FindManufacturerByName returns the manufacturer whose name matches, ignoring
+// case, spacing and punctuation. It returns gorm.ErrRecordNotFound when there
+// is no match.
+func FindManufacturerByName(db *gorm.DB, name string) (models.Manufacturer, error) {
+	var manufacturer models.Manufacturer
+	normalized := models.NormalizeManufacturerName(name)
+	if normalized == "" {
+		return manufacturer, gorm.ErrRecordNotFound
+	}
+
+	err := db.Where("normalized_name = ?", normalized).First(&manufacturer).Error
+	return manufacturer, err
+}
+
+var (
+	// ErrInvalidManufacturer is wrapped by errors caused by bad manufacturer input.
+	ErrInvalidManufacturer = errors.New("invalid manufacturer")
+	// ErrDuplicateManufacturer means another manufacturer already has an
+	// equivalent name.
+	ErrDuplicateManufacturer = errors.New("manufacturer already exists")
+	// ErrManufacturerInUse means cameras still reference the manufacturer.
+	ErrManufacturerInUse = errors.New("manufacturer is still referenced by cameras")
+)
+
+// SaveManufacturer validates req, applies it to manufacturer and creates or
+// updates the row, recording a revision. Names that differ only in case,
+// spacing or punctuation from another manufacturer are rejected.
+func SaveManufacturer(db *gorm.DB, manufacturer *models.Manufacturer, req *models.ManufacturerRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidManufacturer)
+	}
+	if req.Defunct != nil && req.Founded != 0 && *req.Defunct < req.Founded {
+		return fmt.Errorf("%w: defunct (%d) is before founded (%d)", ErrInvalidManufacturer, *req.Defunct, req.Founded)
+	}
+
+	existing, err := FindManufacturerByName(db, req.Name)
+	if err == nil && existing.ID != manufacturer.ID {
+		return fmt.Errorf("%w: %q", ErrDuplicateManufacturer, existing.Name)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		action := models.RevisionCreate
+		var before *models.ManufacturerRequest
+		if manufacturer.ID != 0 {
+			current := manufacturer.ToRequest()
+			before = &current
+			action = models.RevisionUpdate
+		}
+
+		req.ApplyTo(manufacturer)
+		if err := tx.Save(manufacturer).Error; err != nil {
+			return err
+		}
+		if action == models.RevisionUpdate {
+			if err := touchManufacturerCameras(tx, manufacturer.ID); err != nil {
+				return err
+			}
+		}
+
+		after := manufacturer.ToRequest()
+		return RecordRevision(tx, models.RevisionManufacturer, manufacturer.ID, action, before, &after)
+	})
+
+	// A concurrent save of an equivalent name loses on the unique index
+	if err != nil {
+		if existing, findErr := FindManufacturerByName(db, req.Name); findErr == nil && existing.ID != manufacturer.ID {
+			return fmt.Errorf("%w: %q", ErrDuplicateManufacturer, existing.Name)
+		}
+	}
+	return err
+}
+
+// DeleteManufacturer removes a manufacturer that no camera references,
+// including soft-deleted cameras which may still be restored. Its final state
+// is kept as a revision.
+func DeleteManufacturer(db *gorm.DB, manufacturer *models.Manufacturer) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(&models.Camera{}).Where("manufacturer_id = ?", manufacturer.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w (%d cameras)", ErrManufacturerInUse, count)
+		}
+		if err := tx.Delete(manufacturer).Error; err != nil {
+			return err
+		}
+
+		before := manufacturer.ToRequest()
+		return RecordRevision(tx, models.RevisionManufacturer, manufacturer.ID, models.RevisionDelete, &before, nil)
+	})
+}
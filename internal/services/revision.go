@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type authorKey struct{}
+
+// WithAuthor returns a context naming the user responsible for changes made
+// through a *gorm.DB carrying it; RecordRevision stores them as the author.
+func WithAuthor(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, authorKey{}, userID)
+}
+
+func authorFrom(db *gorm.DB) *uint {
+	if db.Statement.Context == nil {
+		return nil
+	}
+	if userID, ok := db.Statement.Context.Value(authorKey{}).(uint); ok {
+		return &userID
+	}
+	return nil
+}
+
+// RecordRevision appends a revision for a record, diffing the before and
+// after snapshots (request structs, nil for a record that does not exist on
+// that side). Updates that change nothing are not recorded.
+func RecordRevision(tx *gorm.DB, entityType string, entityID uint, action string, before, after interface{}) error {
+	beforeFields, err := snapshotFields(before)
+	if err != nil {
+		return err
+	}
+	afterFields, err := snapshotFields(after)
+	if err != nil {
+		return err
+	}
+
+	changes := diffFields(beforeFields, afterFields)
+	if len(changes) == 0 && action == models.RevisionUpdate {
+		return nil
+	}
+
+	// Deletions keep the final state so it can be restored
+	snapshot := after
+	if isNilSnapshot(after) {
+		snapshot = before
+	}
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	var last int
+	if err := tx.Model(&models.Revision{}).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Select("COALESCE(MAX(number), 0)").
+		Scan(&last).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&models.Revision{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Number:     last + 1,
+		Action:     action,
+		UserID:     authorFrom(tx),
+		Snapshot:   string(snapshotJSON),
+		Changes:    string(changesJSON),
+	}).Error
+}
+
+// ListRevisions returns a query for the revisions of one record, newest first.
+func ListRevisions(db *gorm.DB, entityType string, entityID interface{}) *gorm.DB {
+	return db.Model(&models.Revision{}).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("number DESC")
+}
+
+// FindRevision loads one revision of a record by its number.
+func FindRevision(db *gorm.DB, entityType string, entityID, number interface{}) (models.Revision, error) {
+	var revision models.Revision
+	err := db.Where("entity_type = ? AND entity_id = ? AND number = ?", entityType, entityID, number).
+		First(&revision).Error
+	return revision, err
+}
+
+// snapshotFields flattens a request struct to its JSON fields.
+func snapshotFields(snapshot interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if isNilSnapshot(snapshot) {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return fields, json.Unmarshal(data, &fields)
+}
+
+// isNilSnapshot reports whether snapshot is nil or a nil pointer.
+func isNilSnapshot(snapshot interface{}) bool {
+	if snapshot == nil {
+		return true
+	}
+	value := reflect.ValueOf(snapshot)
+	return value.Kind() == reflect.Ptr && value.IsNil()
+}
+
+// diffFields lists the fields whose values differ between two snapshots.
+func diffFields(before, after map[string]interface{}) map[string]models.FieldChange {
+	changes := map[string]models.FieldChange{}
+	for name, value := range before {
+		if !reflect.DeepEqual(value, after[name]) {
+			changes[name] = models.FieldChange{From: value, To: after[name]}
+		}
+	}
+	for name, value := range after {
+		if _, ok := before[name]; !ok && value != nil {
+			changes[name] = models.FieldChange{From: nil, To: value}
+		}
+	}
+	return changes
+}
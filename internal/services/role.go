@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Roles are the roles a user can be given.
+var Roles = []string{models.RoleViewer, models.RoleContributor, models.RoleEditor, models.RoleModerator, models.RoleAdmin}
+
+// DefaultRole is the role of newly registered users.
+const DefaultRole = models.RoleContributor
+
+// ErrInvalidRolePermissions is wrapped by errors caused by a bad change to
+// the permissions of a role.
+var ErrInvalidRolePermissions = errors.New("invalid role permissions")
+
+var (
+	contributorPermissions = []string{
+		models.PermCamerasWrite,
+		models.PermEphemeraWrite,
+		models.PermSerialsWrite,
+		models.PermValuationsWrite,
+		models.PermSalesWrite,
+		models.PermExamplesWrite,
+		models.PermUploadsWrite,
+	}
+	editorPermissions = append(slices.Clone(contributorPermissions),
+		models.PermCamerasDelete,
+		models.PermEphemeraDelete,
+		models.PermManufacturersWrite,
+		models.PermSerialsDelete,
+		models.PermValuationsDelete,
+		models.PermSalesDelete,
+		models.PermRevisionsRestore,
+		models.PermImportRun,
+	)
+	moderatorPermissions = append(slices.Clone(editorPermissions),
+		models.PermManufacturersDelete,
+		models.PermExamplesManage,
+		models.PermTrashManage,
+		models.PermUsersManage,
+	)
+)
+
+// DefaultRolePermissions are the permissions each role starts out with.
+// Contributors can do what every signed-in user could before roles existed.
+var DefaultRolePermissions = map[string][]string{
+	models.RoleViewer:      {},
+	models.RoleContributor: contributorPermissions,
+	models.RoleEditor:      editorPermissions,
+	models.RoleModerator:   moderatorPermissions,
+}
+
+// SeedRolePermissions stores DefaultRolePermissions.
+func SeedRolePermissions(db *gorm.DB) error {
+	grants := []models.RolePermission{}
+	for _, role := range Roles {
+		for _, permission := range DefaultRolePermissions[role] {
+			grants = append(grants, models.RolePermission{Role: role, Permission: permission})
+		}
+	}
+	return db.Create(&grants).Error
+}
+
+// RolePermissions returns the permissions of role. Admins have them all;
+// unknown roles have none.
+func RolePermissions(db *gorm.DB, role string) (models.PermissionSet, error) {
+	set := models.PermissionSet{}
+	if role == models.RoleAdmin {
+		for _, permission := range models.Permissions {
+			set[permission] = true
+		}
+		return set, nil
+	}
+
+	var permissions []string
+	if err := db.Model(&models.RolePermission{}).Where("role = ?", role).Pluck("permission", &permissions).Error; err != nil {
+		return nil, err
+	}
+	for _, permission := range permissions {
+		set[permission] = true
+	}
+	return set, nil
+}
+
+// ListRoles returns every role with its permissions.
+func ListRoles(db *gorm.DB) ([]models.RoleResponse, error) {
+	roles := make([]models.RoleResponse, 0, len(Roles))
+	for _, role := range Roles {
+		set, err := RolePermissions(db, role)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, models.RoleResponse{
+			Role:        role,
+			Permissions: set.List(),
+			Editable:    role != models.RoleAdmin,
+		})
+	}
+	return roles, nil
+}
+
+// SetRolePermissions replaces the permissions of role. It applies to the
+// current sessions of users with the role straight away.
+func SetRolePermissions(db *gorm.DB, role string, permissions []string) error {
+	if role == models.RoleAdmin {
+		return fmt.Errorf("%w: the admin role always has every permission", ErrInvalidRolePermissions)
+	}
+	if !slices.Contains(Roles, role) {
+		return fmt.Errorf("%w: role must be one of %s", ErrInvalidRolePermissions, strings.Join(Roles, ", "))
+	}
+
+	grants := []models.RolePermission{}
+	granted := map[string]bool{}
+	for _, permission := range permissions {
+		if !slices.Contains(models.Permissions, permission) {
+			return fmt.Errorf("%w: unknown permission %q", ErrInvalidRolePermissions, permission)
+		}
+		if !granted[permission] {
+			granted[permission] = true
+			grants = append(grants, models.RolePermission{Role: role, Permission: permission})
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if len(grants) == 0 {
+			return nil
+		}
+		return tx.Create(&grants).Error
+	})
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidSale is wrapped by errors caused by bad sale record input.
+var ErrInvalidSale = errors.New("invalid sale record")
+
+// ListSales returns a query for the recorded sales of a camera, most recent
+// first.
+func ListSales(db *gorm.DB, cameraID interface{}) *gorm.DB {
+	return db.Model(&models.SaleRecord{}).
+		Where("camera_id = ?", cameraID).
+		Order("sold_on DESC, id DESC")
+}
+
+// FindSale loads one sale record of a camera.
+func FindSale(db *gorm.DB, cameraID, id interface{}) (models.SaleRecord, error) {
+	var sale models.SaleRecord
+	err := db.Where("camera_id = ? AND id = ?", cameraID, id).First(&sale).Error
+	return sale, err
+}
+
+// SaveSale validates req, applies it to sale and creates or updates the row.
+func SaveSale(db *gorm.DB, sale *models.SaleRecord, req *models.SaleRecordRequest) error {
+	if err := validateSale(req); err != nil {
+		return err
+	}
+
+	req.ApplyTo(sale)
+	return db.Save(sale).Error
+}
+
+// DeleteSale removes a sale record.
+func DeleteSale(db *gorm.DB, sale *models.SaleRecord) error {
+	return db.Delete(sale).Error
+}
+
+// validateSale checks req and normalises its currency and condition.
+func validateSale(req *models.SaleRecordRequest) error {
+	if req.Price <= 0 {
+		return fmt.Errorf("%w: price must be greater than 0", ErrInvalidSale)
+	}
+
+	currency, ok := models.NormalizeCurrency(req.Currency)
+	if !ok {
+		return fmt.Errorf("%w: currency must be a three-letter ISO 4217 code, e.g. GBP", ErrInvalidSale)
+	}
+	req.Currency = currency
+
+	condition, ok := models.ParseCondition(req.Condition)
+	if !ok {
+		return fmt.Errorf("%w: unknown condition %q (use %s)", ErrInvalidSale, req.Condition, strings.Join(models.ConditionGrades, ", "))
+	}
+	req.Condition = condition
+
+	if req.SoldOn.IsZero() {
+		return fmt.Errorf("%w: sold_on is required", ErrInvalidSale)
+	}
+	if req.SoldOn.After(time.Now()) {
+		return fmt.Errorf("%w: sold_on is in the future", ErrInvalidSale)
+	}
+
+	req.LotURL = strings.TrimSpace(req.LotURL)
+	if req.LotURL != "" {
+		lotURL, err := url.Parse(req.LotURL)
+		if err != nil || (lotURL.Scheme != "http" && lotURL.Scheme != "https") || lotURL.Host == "" {
+			return fmt.Errorf("%w: lot_url must be an http or https URL", ErrInvalidSale)
+		}
+	}
+
+	return nil
+}
+
+// GetPriceHistory summarises a camera's sales per year in currency, or in
+// the currency it has most often sold in when currency is empty. Amounts in
+// other currencies are never converted or mixed in.
+func GetPriceHistory(db *gorm.DB, cameraID uint, currency string) (models.PriceHistory, error) {
+	history := models.PriceHistory{CameraID: cameraID, Currencies: []string{}, Years: []models.PriceYear{}}
+
+	var counts []struct {
+		Currency string
+		Sales    int
+	}
+	if err := db.Model(&models.SaleRecord{}).
+		Select("currency, COUNT(*) AS sales").
+		Where("camera_id = ?", cameraID).
+		Group("currency").
+		Order("sales DESC, currency").
+		Scan(&counts).Error; err != nil {
+		return history, err
+	}
+	for _, count := range counts {
+		history.Currencies = append(history.Currencies, count.Currency)
+	}
+
+	if currency != "" {
+		code, ok := models.NormalizeCurrency(currency)
+		if !ok {
+			return history, fmt.Errorf("%w: currency must be a three-letter ISO 4217 code, e.g. GBP", ErrInvalidSale)
+		}
+		history.Currency = code
+	} else if len(counts) > 0 {
+		history.Currency = counts[0].Currency
+	}
+	if history.Currency == "" {
+		return history, nil
+	}
+
+	var sales []models.SaleRecord
+	if err := db.
+		Where("camera_id = ? AND currency = ?", cameraID, history.Currency).
+		Order("sold_on").
+		Find(&sales).Error; err != nil {
+		return history, err
+	}
+
+	prices := map[int][]float64{}
+	var years []int
+	for _, sale := range sales {
+		year := sale.SoldOn.Year()
+		if _, ok := prices[year]; !ok {
+			years = append(years, year)
+		}
+		prices[year] = append(prices[year], sale.Price)
+	}
+
+	for _, year := range years {
+		history.Years = append(history.Years, priceYear(year, prices[year]))
+	}
+	return history, nil
+}
+
+// priceYear returns the spread of a year's prices.
+func priceYear(year int, prices []float64) models.PriceYear {
+	sort.Float64s(prices)
+
+	median := prices[len(prices)/2]
+	if len(prices)%2 == 0 {
+		median = (prices[len(prices)/2-1] + prices[len(prices)/2]) / 2
+	}
+
+	return models.PriceYear{
+		Year:   year,
+		Sales:  len(prices),
+		Min:    prices[0],
+		Median: median,
+		Max:    prices[len(prices)-1],
+	}
+}
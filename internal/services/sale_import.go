@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// SaleImport is a sale read from an import of auction results, with the
+// camera it belongs to given by ID or by name. The manufacturer is only
+// needed when several cameras share the name.
+type SaleImport struct {
+	CameraID     *uint
+	Camera       string
+	Manufacturer string
+	models.SaleRecordRequest
+}
+
+// SaleImportRow is one sale read from an import file. Err is set when the
+// row itself could not be parsed.
+type SaleImportRow struct {
+	Line int
+	Sale SaleImport
+	Err  error
+}
+
+func (r SaleImportRow) describe() (int, string, error) { return r.Line, r.Sale.Camera, r.Err }
+
+// csvSaleImportColumns maps each accepted CSV header to the field it sets.
+var csvSaleImportColumns = map[string]func(sale *SaleImport, value string) error{
+	"camera_id": func(sale *SaleImport, value string) (err error) {
+		sale.CameraID, err = parseOptionalUint(value)
+		return err
+	},
+	"camera":       func(sale *SaleImport, value string) error { sale.Camera = value; return nil },
+	"manufacturer": func(sale *SaleImport, value string) error { sale.Manufacturer = value; return nil },
+	"sold_on": func(sale *SaleImport, value string) error {
+		if value == "" {
+			return nil
+		}
+		soldOn, err := time.Parse(models.DateLayout, value)
+		if err != nil {
+			return fmt.Errorf("%q is not a YYYY-MM-DD date", value)
+		}
+		sale.SoldOn = models.NewDate(soldOn)
+		return nil
+	},
+	"venue": func(sale *SaleImport, value string) error { sale.Venue = value; return nil },
+	"price": func(sale *SaleImport, value string) error {
+		if value == "" {
+			return nil
+		}
+		// Spreadsheets often keep thousands separators
+		price, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		sale.Price = price
+		return nil
+	},
+	"currency":  func(sale *SaleImport, value string) error { sale.Currency = value; return nil },
+	"condition": func(sale *SaleImport, value string) error { sale.Condition = value; return nil },
+	"lot_url":   func(sale *SaleImport, value string) error { sale.LotURL = value; return nil },
+	"notes":     func(sale *SaleImport, value string) error { sale.Notes = value; return nil },
+}
+
+// ParseSaleImport reads sales from a CSV file with a header row naming the
+// columns of csvSaleImportColumns. Rows that cannot be parsed are returned
+// with Err set; an error is returned only when the file as a whole is
+// unreadable.
+func ParseSaleImport(data []byte, format string) ([]SaleImportRow, error) {
+	if format != ImportCSV {
+		return nil, fmt.Errorf("%w: unsupported format %q (sales are imported from csv)", ErrInvalidImport, format)
+	}
+
+	records, err := parseCSVRecords(data, csvSaleImportColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]SaleImportRow, len(records))
+	for i, record := range records {
+		rows[i] = SaleImportRow{Line: record.Line, Sale: record.Value, Err: record.Err}
+	}
+	return rows, nil
+}
+
+// ImportSales records sales from parsed rows. A row matching a recorded sale
+// of the same camera on the same date, at the same venue and for the same
+// price updates it, so auction results can be imported again safely. Rows
+// are written and reported as in ImportCameras.
+func ImportSales(db *gorm.DB, rows []SaleImportRow, opts ImportOptions) (ImportResult, error) {
+	return importRows(db, rows, opts, func(tx *gorm.DB, row *SaleImportRow) (bool, error) {
+		return importSale(tx, &row.Sale)
+	})
+}
+
+// importSale upserts one sale, reporting whether it was created.
+func importSale(db *gorm.DB, row *SaleImport) (bool, error) {
+	created := false
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		cameraID, err := resolveSaleCamera(tx, row)
+		if err != nil {
+			return err
+		}
+		if err := validateSale(&row.SaleRecordRequest); err != nil {
+			return err
+		}
+
+		sale := models.SaleRecord{CameraID: cameraID}
+		err = tx.Where("camera_id = ? AND sold_on = ? AND venue = ? AND price = ? AND currency = ?",
+			cameraID, row.SoldOn.Time, row.Venue, row.Price, row.Currency).
+			First(&sale).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			created = true
+		} else if err != nil {
+			return err
+		}
+
+		return SaveSale(tx, &sale, &row.SaleRecordRequest)
+	})
+
+	return created, err
+}
+
+// resolveSaleCamera returns the ID of the camera a sale row names.
+func resolveSaleCamera(tx *gorm.DB, row *SaleImport) (uint, error) {
+	if row.CameraID != nil {
+		var camera models.Camera
+		if err := tx.First(&camera, *row.CameraID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, fmt.Errorf("%w: unknown camera_id %d", ErrInvalidSale, *row.CameraID)
+			}
+			return 0, err
+		}
+		return camera.ID, nil
+	}
+
+	name := strings.TrimSpace(row.Camera)
+	if name == "" {
+		return 0, fmt.Errorf("%w: camera or camera_id is required", ErrInvalidSale)
+	}
+
+	query := tx.Model(&models.Camera{}).Where("LOWER(name) = LOWER(?)", name)
+	if row.Manufacturer != "" {
+		manufacturer, err := FindManufacturerByName(tx, row.Manufacturer)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, fmt.Errorf("%w: unknown manufacturer %q", ErrInvalidSale, row.Manufacturer)
+			}
+			return 0, err
+		}
+		query = query.Where("manufacturer_id = ?", manufacturer.ID)
+	}
+
+	var ids []uint
+	if err := query.Limit(2).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("%w: unknown camera %q", ErrInvalidSale, name)
+	case 1:
+		return ids[0], nil
+	default:
+		return 0, fmt.Errorf("%w: several cameras are named %q; add a manufacturer or camera_id column", ErrInvalidSale, name)
+	}
+}
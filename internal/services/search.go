@@ -0,0 +1,113 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CameraSearch builds dialect-specific full-text search clauses for the
+// cameras table. The index itself is maintained by the database package.
+type CameraSearch struct {
+	dialect string
+	query   string
+}
+
+// NewCameraSearch prepares a search for the user-supplied term. It returns
+// nil when the term contains nothing searchable.
+func NewCameraSearch(db *gorm.DB, term string) *CameraSearch {
+	tokens := strings.FieldsFunc(strings.ToLower(term), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	// Every token must match, and the last one may be a prefix so results
+	// appear while the user is still typing.
+	search := &CameraSearch{dialect: db.Dialector.Name()}
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		switch search.dialect {
+		case "postgres":
+			terms[i] = token + ":*"
+		default:
+			terms[i] = `"` + token + `"*`
+		}
+	}
+
+	switch search.dialect {
+	case "postgres":
+		search.query = strings.Join(terms, " & ")
+	default:
+		search.query = strings.Join(terms, " ")
+	}
+
+	return search
+}
+
+// Filter restricts query to cameras matching the search.
+func (s *CameraSearch) Filter(query *gorm.DB) *gorm.DB {
+	switch s.dialect {
+	case "postgres":
+		return query.Where("cameras.search_vector @@ to_tsquery('english', ?)", s.query)
+	default:
+		return query.
+			Joins("JOIN camera_search ON camera_search.rowid = cameras.id").
+			Where("camera_search MATCH ?", s.query)
+	}
+}
+
+// Select adds the relevance score and highlighted fields to a filtered query,
+// scanned into Camera's read-only Search* fields. Matches are wrapped in
+// models.SearchMatchStart and SearchMatchEnd rather than markup, as the text
+// around them still has to be escaped.
+func (s *CameraSearch) Select(query *gorm.DB) *gorm.DB {
+	switch s.dialect {
+	case "postgres":
+		return query.Select(
+			"cameras.*, "+s.score()+" AS search_score, "+
+				"ts_headline('english', cameras.name, to_tsquery('english', ?), ?) AS search_name_highlight, "+
+				"ts_headline('english', cameras.description, to_tsquery('english', ?), ?) AS search_snippet",
+			s.query,
+			s.query, "StartSel="+models.SearchMatchStart+", StopSel="+models.SearchMatchEnd+", HighlightAll=true",
+			s.query, "StartSel="+models.SearchMatchStart+", StopSel="+models.SearchMatchEnd+", MaxWords=24, MinWords=8, MaxFragments=2",
+		)
+	default:
+		return query.Select(
+			"cameras.*, "+s.score()+" AS search_score, "+
+				"highlight(camera_search, 0, ?, ?) AS search_name_highlight, "+
+				"snippet(camera_search, 2, ?, ?, '…', 16) AS search_snippet",
+			models.SearchMatchStart, models.SearchMatchEnd,
+			models.SearchMatchStart, models.SearchMatchEnd,
+		)
+	}
+}
+
+// OrderByRelevance sorts the most relevant cameras first.
+func (s *CameraSearch) OrderByRelevance(query *gorm.DB) *gorm.DB {
+	var vars []interface{}
+	if s.dialect == "postgres" {
+		vars = append(vars, s.query)
+	}
+	return query.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                s.score() + " DESC, cameras.id",
+		Vars:               vars,
+		WithoutParentheses: true,
+	}})
+}
+
+// score returns the relevance expression; higher is better on both dialects.
+// Postgres takes the tsquery as a bind variable.
+func (s *CameraSearch) score() string {
+	switch s.dialect {
+	case "postgres":
+		return "ts_rank_cd(cameras.search_vector, to_tsquery('english', ?))"
+	default:
+		// bm25 is lower-is-better; weights favour name over manufacturer over description
+		return "-bm25(camera_search, 10.0, 5.0, 1.0)"
+	}
+}
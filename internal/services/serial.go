@@ -0,0 +1,167 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidSerialRange is wrapped by errors caused by bad serial range or
+// serial lookup input.
+var ErrInvalidSerialRange = errors.New("invalid serial range")
+
+// ListSerialRanges returns a query for the serial ranges, optionally only
+// those of models whose name or catalogue camera contains model, ordered by
+// model and first serial.
+func ListSerialRanges(db *gorm.DB, model string) *gorm.DB {
+	return serialRangeQuery(db, model).Order("serial_ranges.model, serial_ranges.start_serial")
+}
+
+// serialRangeQuery returns an unordered query for the serial ranges
+// matching model.
+func serialRangeQuery(db *gorm.DB, model string) *gorm.DB {
+	query := db.Model(&models.SerialRange{}).Preload("Camera")
+	if model = strings.TrimSpace(model); model != "" {
+		pattern := "%" + strings.ToLower(model) + "%"
+		query = query.
+			Joins("LEFT JOIN cameras ON cameras.id = serial_ranges.camera_id").
+			Where("(LOWER(serial_ranges.model) LIKE ? OR LOWER(cameras.name) LIKE ?)", pattern, pattern)
+	}
+	return query
+}
+
+// FindSerialRange loads a serial range with its camera.
+func FindSerialRange(db *gorm.DB, id interface{}) (models.SerialRange, error) {
+	var serialRange models.SerialRange
+	err := db.Preload("Camera").First(&serialRange, id).Error
+	return serialRange, err
+}
+
+// SaveSerialRange validates req, applies it to serialRange and creates or
+// updates the row.
+func SaveSerialRange(db *gorm.DB, serialRange *models.SerialRange, req *models.SerialRangeRequest) error {
+	if err := validateSerialRange(db, serialRange.ID, req); err != nil {
+		return err
+	}
+
+	req.ApplyTo(serialRange)
+	if err := db.Omit("Camera").Save(serialRange).Error; err != nil {
+		return err
+	}
+
+	serialRange.Camera = nil
+	if serialRange.CameraID != nil {
+		var camera models.Camera
+		if err := db.First(&camera, *serialRange.CameraID).Error; err != nil {
+			return err
+		}
+		serialRange.Camera = &camera
+	}
+	return nil
+}
+
+// DeleteSerialRange removes a serial range.
+func DeleteSerialRange(db *gorm.DB, serialRange *models.SerialRange) error {
+	return db.Delete(serialRange).Error
+}
+
+// validateSerialRange checks req and that it does not overlap another range
+// of the same model, which would date the same serial twice.
+func validateSerialRange(db *gorm.DB, id uint, req *models.SerialRangeRequest) error {
+	req.Model = strings.TrimSpace(req.Model)
+	if req.Model == "" {
+		return fmt.Errorf("%w: model is required", ErrInvalidSerialRange)
+	}
+
+	if req.StartSerial <= 0 {
+		return fmt.Errorf("%w: start_serial must be greater than 0", ErrInvalidSerialRange)
+	}
+	if req.EndSerial == 0 {
+		req.EndSerial = req.StartSerial
+	}
+	if req.EndSerial < req.StartSerial {
+		return fmt.Errorf("%w: end_serial must not be below start_serial", ErrInvalidSerialRange)
+	}
+
+	if req.DateFrom.IsZero() {
+		return fmt.Errorf("%w: date_from is required", ErrInvalidSerialRange)
+	}
+	if req.DateTo.IsZero() {
+		req.DateTo = req.DateFrom
+	}
+	if req.DateTo.Before(req.DateFrom.Time) {
+		return fmt.Errorf("%w: date_to must not be before date_from", ErrInvalidSerialRange)
+	}
+
+	if req.CameraID != nil {
+		var count int64
+		if err := db.Model(&models.Camera{}).Where("id = ?", *req.CameraID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: camera %d does not exist", ErrInvalidSerialRange, *req.CameraID)
+		}
+	}
+
+	var overlap models.SerialRange
+	err := db.Where("LOWER(model) = LOWER(?) AND start_serial <= ? AND end_serial >= ? AND id <> ?",
+		req.Model, req.EndSerial, req.StartSerial, id).
+		First(&overlap).Error
+	if err == nil {
+		return fmt.Errorf("%w: serials %d-%d of %s overlap range %d (%d-%d)", ErrInvalidSerialRange,
+			req.StartSerial, req.EndSerial, req.Model, overlap.ID, overlap.StartSerial, overlap.EndSerial)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// ParseSerial reads a serial number as it is stamped or written, accepting
+// a "No." prefix and digit grouping, e.g. "No. 12,345".
+func ParseSerial(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	lower := strings.ToLower(value)
+	for _, prefix := range []string{"no.", "no", "#"} {
+		if strings.HasPrefix(lower, prefix) {
+			value = value[len(prefix):]
+			break
+		}
+	}
+	value = strings.NewReplacer(",", "", " ", "").Replace(value)
+
+	serial, err := strconv.Atoi(value)
+	if err != nil || serial <= 0 {
+		return 0, fmt.Errorf("%w: serial must be a whole number, e.g. 12345", ErrInvalidSerialRange)
+	}
+	return serial, nil
+}
+
+// LookupSerial finds the ranges serial falls in, optionally only those of
+// model, and estimates when it was made from each.
+func LookupSerial(db *gorm.DB, model string, serial int) (models.SerialLookup, error) {
+	lookup := models.SerialLookup{Serial: serial, Model: strings.TrimSpace(model), Matches: []models.SerialMatch{}}
+
+	var ranges []models.SerialRange
+	if err := serialRangeQuery(db, model).
+		Where("serial_ranges.start_serial <= ? AND serial_ranges.end_serial >= ?", serial, serial).
+		Order("serial_ranges.end_serial - serial_ranges.start_serial, serial_ranges.id").
+		Find(&ranges).Error; err != nil {
+		return lookup, err
+	}
+
+	for i := range ranges {
+		match := models.SerialMatch{
+			EstimatedDate: ranges[i].EstimateDate(serial),
+			Model:         ranges[i].Model,
+			Range:         ranges[i].ToSerialRangeResponse(),
+		}
+		match.Camera = match.Range.Camera
+		lookup.Matches = append(lookup.Matches, match)
+	}
+	return lookup, nil
+}
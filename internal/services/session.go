@@ -0,0 +1,206 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultRefreshTokenTTL is how long a session lasts without being
+// refreshed when REFRESH_TOKEN_EXPIRY is not set.
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+// Reasons a session was revoked, kept on the session.
+const (
+	RevokedLogout         = "logout"
+	RevokedLogoutAll      = "logout_all"
+	RevokedReuse          = "reuse"
+	RevokedPassword       = "password_reset"
+	RevokedPasswordChange = "password_change"
+	RevokedSuspended      = "suspended"
+	RevokedResetRequired  = "reset_required"
+	RevokedDeleted        = "account_deleted"
+)
+
+var (
+	// ErrInvalidRefreshToken means the refresh token is unknown, expired or
+	// belongs to a session that has ended.
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused means an already used refresh token was
+	// presented again. Its session has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrSessionRevoked means an access token's session has ended or its
+	// user no longer exists.
+	ErrSessionRevoked = errors.New("session has been revoked")
+	// ErrAccountSuspended means the user of an access token has been
+	// suspended.
+	ErrAccountSuspended = errors.New("account suspended")
+)
+
+// RefreshTokenTTL returns how long a session lasts without being
+// refreshed, from REFRESH_TOKEN_EXPIRY.
+func RefreshTokenTTL() time.Duration {
+	return durationSetting("REFRESH_TOKEN_EXPIRY", DefaultRefreshTokenTTL)
+}
+
+// newToken returns a random opaque token for a user to hold.
+func newToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashToken returns the stored form of an opaque token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// StartSession signs user in: it opens a session and answers with its
+// first access and refresh tokens.
+func StartSession(db *gorm.DB, user *models.User, userAgent string) (models.AuthResponse, error) {
+	session := models.Session{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL()),
+		UserAgent: userAgent,
+	}
+
+	var response models.AuthResponse
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		response, err = issueTokens(tx, user, &session)
+		return err
+	})
+	return response, err
+}
+
+// RefreshSession exchanges a refresh token for new tokens in the same
+// session. Each refresh token works once: presenting one again revokes the
+// whole session, since either the holder or a thief has the newer token.
+func RefreshSession(db *gorm.DB, token string) (models.AuthResponse, error) {
+	var response models.AuthResponse
+
+	var refresh models.RefreshToken
+	if err := db.Preload("Session").Where("token_hash = ?", hashToken(token)).First(&refresh).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response, ErrInvalidRefreshToken
+		}
+		return response, err
+	}
+
+	session := refresh.Session
+	if session == nil || session.RevokedAt != nil {
+		return response, ErrInvalidRefreshToken
+	}
+
+	// Claim the token; only one request can, however many race
+	now := time.Now()
+	result := db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", refresh.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return response, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := RevokeSession(db, session.ID, RevokedReuse); err != nil {
+			return response, err
+		}
+		return response, ErrRefreshTokenReused
+	}
+
+	if now.After(refresh.ExpiresAt) || now.After(session.ExpiresAt) {
+		return response, ErrInvalidRefreshToken
+	}
+
+	var user models.User
+	if err := db.First(&user, session.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response, ErrInvalidRefreshToken
+		}
+		return response, err
+	}
+	if user.SuspendedAt != nil {
+		return response, ErrInvalidRefreshToken
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		session.ExpiresAt = now.Add(RefreshTokenTTL())
+		if err := tx.Model(session).Update("expires_at", session.ExpiresAt).Error; err != nil {
+			return err
+		}
+
+		var err error
+		response, err = issueTokens(tx, &user, session)
+		return err
+	})
+	return response, err
+}
+
+// issueTokens stores a new refresh token for session and signs an access
+// token to go with it.
+func issueTokens(tx *gorm.DB, user *models.User, session *models.Session) (models.AuthResponse, error) {
+	response := models.AuthResponse{User: *user}
+
+	var err error
+	if response.RefreshToken, err = newToken(); err != nil {
+		return response, err
+	}
+
+	refresh := models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: hashToken(response.RefreshToken),
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := tx.Create(&refresh).Error; err != nil {
+		return response, err
+	}
+
+	response.Token, response.ExpiresAt, err = GenerateToken(user, session.ID)
+	return response, err
+}
+
+// CheckSession returns the user of an access token whose session is still
+// live, as currently stored, so role changes and suspensions apply at once.
+func CheckSession(db *gorm.DB, claims *Claims) (models.User, error) {
+	var user models.User
+	err := db.Joins("JOIN sessions ON sessions.user_id = users.id").
+		Where("sessions.id = ? AND users.id = ? AND sessions.revoked_at IS NULL AND sessions.expires_at > ?",
+			claims.SessionID, claims.UserID, time.Now()).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, ErrSessionRevoked
+	}
+	if err == nil && user.SuspendedAt != nil {
+		return user, ErrAccountSuspended
+	}
+	return user, err
+}
+
+// RevokeSession ends one session, logging out every token issued in it.
+func RevokeSession(db *gorm.DB, sessionID, reason string) error {
+	return db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// RevokeUserSessions ends every session of a user, optionally sparing one,
+// such as the session making the request.
+func RevokeUserSessions(db *gorm.DB, userID uint, reason string, except string) error {
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, except).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultTrashRetentionDays is how long soft-deleted records are kept when
+// TRASH_RETENTION_DAYS is not set.
+const DefaultTrashRetentionDays = 30
+
+// trashPurgeInterval is how often the background purger runs.
+const trashPurgeInterval = 24 * time.Hour
+
+var (
+	// ErrUnknownTrash means the trash endpoints were asked for a resource that
+	// is not soft-deleted.
+	ErrUnknownTrash = errors.New("unknown trash type")
+	// ErrNotInTrash means the record does not exist or is not deleted.
+	ErrNotInTrash = errors.New("record is not in the trash")
+)
+
+// TrashItem is a soft-deleted record as listed in the trash.
+type TrashItem struct {
+	ID        uint      `json:"id"`
+	Label     string    `json:"label"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// trashBin describes one soft-deleted resource.
+type trashBin struct {
+	model func() interface{}
+	// label is the column shown when listing the trash
+	label string
+	// revision is the entity type whose history records restores, if any
+	revision string
+	snapshot func(tx *gorm.DB, id uint) (interface{}, error)
+	// restored records a restore of a record without revisions
+	restored func(tx *gorm.DB, id uint) error
+	// relinked moves on the versions of records whose responses embed the
+	// record, which show it again once it is restored
+	relinked func(tx *gorm.DB, id uint) error
+	// purge removes rows that depend on the record before it is hard-deleted
+	purge func(tx *gorm.DB, id uint) error
+}
+
+var trashBins = map[string]trashBin{
+	"cameras": {
+		model:    func() interface{} { return &models.Camera{} },
+		label:    "name",
+		revision: models.RevisionCamera,
+		relinked: touchLinkedEphemera,
+		snapshot: func(tx *gorm.DB, id uint) (interface{}, error) {
+			camera, err := FindCamera(tx, id)
+			if err != nil {
+				return nil, err
+			}
+			req := camera.ToRequest()
+			return &req, nil
+		},
+		purge: func(tx *gorm.DB, id uint) error {
+			camera := models.Camera{ID: id}
+			if err := tx.Model(&camera).Association("PlateSizes").Clear(); err != nil {
+				return err
+			}
+			if err := tx.Model(&camera).Association("Features").Clear(); err != nil {
+				return err
+			}
+			if err := tx.Where("camera_id = ?", id).Delete(&models.CameraImage{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("camera_id = ?", id).Delete(&models.Valuation{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("camera_id = ?", id).Delete(&models.SaleRecord{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("example_id IN (?)", tx.Model(&models.Example{}).Select("id").Where("camera_id = ?", id)).Delete(&models.ExampleImage{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("camera_id = ?", id).Delete(&models.Example{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.SerialRange{}).Where("camera_id = ?", id).Update("camera_id", nil).Error; err != nil {
+				return err
+			}
+			return tx.Where("camera_id = ?", id).Delete(&models.EphemeraCamera{}).Error
+		},
+	},
+	"ephemera": {
+		model:    func() interface{} { return &models.Ephemera{} },
+		label:    "title",
+		revision: models.RevisionEphemera,
+		relinked: touchLinkedCameras,
+		snapshot: func(tx *gorm.DB, id uint) (interface{}, error) {
+			item, err := FindEphemera(tx, id)
+			if err != nil {
+				return nil, err
+			}
+			req := item.ToRequest()
+			return &req, nil
+		},
+		purge: func(tx *gorm.DB, id uint) error {
+			return tx.Where("ephemera_id = ?", id).Delete(&models.EphemeraCamera{}).Error
+		},
+	},
+	"users": {
+		model: func() interface{} { return &models.User{} },
+		label: "email",
+		restored: func(tx *gorm.DB, id uint) error {
+			return RecordAudit(tx, id, models.AuditRestore, nil)
+		},
+		purge: func(tx *gorm.DB, id uint) error {
+			if err := eraseUserData(tx, id); err != nil {
+				return err
+			}
+			return RecordAudit(tx, id, models.AuditPurge, nil)
+		},
+	},
+}
+
+func findTrashBin(kind string) (trashBin, error) {
+	bin, ok := trashBins[kind]
+	if !ok {
+		return bin, ErrUnknownTrash
+	}
+	return bin, nil
+}
+
+// ListTrash returns a query over the soft-deleted records of kind, most
+// recently deleted first, to be scanned into TrashItem.
+func ListTrash(db *gorm.DB, kind string) (*gorm.DB, error) {
+	bin, err := findTrashBin(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Unscoped().
+		Model(bin.model()).
+		Select("id, " + bin.label + " AS label, deleted_at").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC, id"), nil
+}
+
+// RestoreTrash undeletes a soft-deleted record. Camera and ephemera restores
+// are recorded in their revision history, and come back with the links
+// between them, and user restores in the audit trail.
+func RestoreTrash(db *gorm.DB, kind string, id uint) error {
+	bin, err := findTrashBin(kind)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().
+			Model(bin.model()).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotInTrash
+		}
+
+		if bin.relinked != nil {
+			if err := bin.relinked(tx, id); err != nil {
+				return err
+			}
+		}
+		if bin.restored != nil {
+			return bin.restored(tx, id)
+		}
+		if bin.revision == "" {
+			return nil
+		}
+		after, err := bin.snapshot(tx, id)
+		if err != nil {
+			return err
+		}
+		return RecordRevision(tx, bin.revision, id, models.RevisionRestore, nil, after)
+	})
+}
+
+// PurgeTrash permanently deletes a soft-deleted record and the rows that
+// depend on it. Revision history is kept.
+func PurgeTrash(db *gorm.DB, kind string, id uint) error {
+	bin, err := findTrashBin(kind)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(bin.model()).Where("id = ? AND deleted_at IS NOT NULL", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotInTrash
+		}
+
+		if bin.purge != nil {
+			if err := bin.purge(tx, id); err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id = ?", id).Delete(bin.model()).Error
+	})
+}
+
+// PurgeExpiredTrash permanently deletes every record soft-deleted before
+// cutoff and returns how many were removed.
+func PurgeExpiredTrash(db *gorm.DB, cutoff time.Time) (int, error) {
+	purged := 0
+	for kind, bin := range trashBins {
+		var ids []uint
+		if err := db.Unscoped().Model(bin.model()).Where("deleted_at < ?", cutoff).Pluck("id", &ids).Error; err != nil {
+			return purged, err
+		}
+		for _, id := range ids {
+			if err := PurgeTrash(db, kind, id); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// TrashRetention returns how long soft-deleted records are kept, from
+// TRASH_RETENTION_DAYS. Zero disables purging.
+func TrashRetention() time.Duration {
+	days := DefaultTrashRetentionDays
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			log.Printf("Warning: invalid TRASH_RETENTION_DAYS %q, using %d", value, days)
+		} else {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// StartTrashPurger hard-deletes records that have been in the trash longer
+// than retention, once at start-up and then daily, until the process exits.
+func StartTrashPurger(db *gorm.DB, retention time.Duration) {
+	if retention <= 0 {
+		log.Println("Trash purging disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := PurgeExpiredTrash(db, time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Warning: trash purge failed: %v", err)
+			} else if purged > 0 {
+				log.Printf("✓ Purged %d records from the trash", purged)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// UserStatuses are the account states users can be listed by.
+var UserStatuses = []string{"active", "suspended", "deleted"}
+
+// ErrInvalidUserAction is wrapped by errors caused by an admin action that
+// cannot be applied to the user.
+var ErrInvalidUserAction = errors.New("invalid user action")
+
+// ListUsers returns a query for the users matching search (in email or
+// name), role and status, oldest first. Deleted users are only listed when
+// status is "deleted".
+func ListUsers(db *gorm.DB, search, role, status string) *gorm.DB {
+	query := db.Model(&models.User{})
+	switch status {
+	case "active":
+		query = query.Where("suspended_at IS NULL")
+	case "suspended":
+		query = query.Where("suspended_at IS NOT NULL")
+	case "deleted":
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+
+	if search = strings.TrimSpace(search); search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where("(LOWER(email) LIKE ? OR LOWER(first_name || ' ' || last_name) LIKE ?)", pattern, pattern)
+	}
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+	return query.Order("id")
+}
+
+// FindUser loads a user, including a deleted one.
+func FindUser(db *gorm.DB, id interface{}) (models.User, error) {
+	var user models.User
+	err := db.Unscoped().First(&user, id).Error
+	return user, err
+}
+
+// RecordAudit appends an entry to the audit trail of the user userID. The
+// author of db, if any, is recorded as the actor.
+func RecordAudit(tx *gorm.DB, userID uint, action string, details map[string]interface{}) error {
+	detailsJSON := ""
+	if len(details) > 0 {
+		data, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		detailsJSON = string(data)
+	}
+
+	return tx.Create(&models.AuditLog{
+		UserID:  userID,
+		ActorID: authorFrom(tx),
+		Action:  action,
+		Details: detailsJSON,
+	}).Error
+}
+
+// ListAudit returns a query for the audit trail of a user, newest first.
+func ListAudit(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&models.AuditLog{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC")
+}
+
+// checkNotSelf refuses actions an admin could lock themselves out with.
+func checkNotSelf(db *gorm.DB, user *models.User, action string) error {
+	if actor := authorFrom(db); actor != nil && *actor == user.ID {
+		return fmt.Errorf("%w: you cannot %s your own account", ErrInvalidUserAction, action)
+	}
+	return nil
+}
+
+// checkNotDeleted refuses actions on deleted users, which must be restored
+// first.
+func checkNotDeleted(user *models.User) error {
+	if user.DeletedAt.Valid {
+		return fmt.Errorf("%w: the account is deleted", ErrInvalidUserAction)
+	}
+	return nil
+}
+
+// ChangeRole gives user a new role. It applies to their current sessions
+// straight away.
+func ChangeRole(db *gorm.DB, user *models.User, role string) error {
+	if !slices.Contains(Roles, role) {
+		return fmt.Errorf("%w: role must be one of %s", ErrInvalidUserAction, strings.Join(Roles, ", "))
+	}
+	if err := checkNotDeleted(user); err != nil {
+		return err
+	}
+	if err := checkNotSelf(db, user, "change the role of"); err != nil {
+		return err
+	}
+	if user.Role == role {
+		return nil
+	}
+
+	from := user.Role
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("role", role).Error; err != nil {
+			return err
+		}
+		return RecordAudit(tx, user.ID, models.AuditRoleChange, map[string]interface{}{"from": from, "to": role})
+	})
+}
+
+// SuspendUser stops user from signing in and logs out all their sessions.
+func SuspendUser(db *gorm.DB, user *models.User, reason string) error {
+	if err := checkNotDeleted(user); err != nil {
+		return err
+	}
+	if err := checkNotSelf(db, user, "suspend"); err != nil {
+		return err
+	}
+	if user.SuspendedAt != nil {
+		return fmt.Errorf("%w: the account is already suspended", ErrInvalidUserAction)
+	}
+
+	now := time.Now()
+	reason = strings.TrimSpace(reason)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{"suspended_at": now, "suspended_reason": reason}).Error; err != nil {
+			return err
+		}
+		if err := RevokeUserSessions(tx, user.ID, RevokedSuspended, ""); err != nil {
+			return err
+		}
+		return RecordAudit(tx, user.ID, models.AuditSuspend, map[string]interface{}{"reason": reason})
+	})
+}
+
+// UnsuspendUser lets a suspended user sign in again.
+func UnsuspendUser(db *gorm.DB, user *models.User) error {
+	if user.SuspendedAt == nil {
+		return fmt.Errorf("%w: the account is not suspended", ErrInvalidUserAction)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(user).Updates(map[string]interface{}{"suspended_at": nil, "suspended_reason": ""}).Error; err != nil {
+			return err
+		}
+		return RecordAudit(tx, user.ID, models.AuditUnsuspend, nil)
+	})
+}
+
+// ForcePasswordReset logs user out everywhere and keeps them from signing
+// in until they choose a new password through the reset link it mails them.
+func ForcePasswordReset(db *gorm.DB, mailer Mailer, user *models.User) error {
+	if err := checkNotDeleted(user); err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("reset_required", true).Error; err != nil {
+			return err
+		}
+		if err := RevokeUserSessions(tx, user.ID, RevokedResetRequired, ""); err != nil {
+			return err
+		}
+		return RecordAudit(tx, user.ID, models.AuditPasswordReset, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendPasswordReset(db, mailer, user,
+		"An administrator has asked you to choose a new password before you sign in again. To choose one, open the link below:",
+		"You have been signed out of every device until you do.")
+}
+
+// DeleteUser moves user to the trash and logs out all their sessions. They
+// can be restored with RestoreUser until the trash is purged.
+func DeleteUser(db *gorm.DB, user *models.User) error {
+	if err := checkNotDeleted(user); err != nil {
+		return err
+	}
+	if err := checkNotSelf(db, user, "delete"); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(user).Error; err != nil {
+			return err
+		}
+		if err := RevokeUserSessions(tx, user.ID, RevokedDeleted, ""); err != nil {
+			return err
+		}
+		return RecordAudit(tx, user.ID, models.AuditDelete, nil)
+	})
+}
+
+// RestoreUser undeletes a user deleted with DeleteUser.
+func RestoreUser(db *gorm.DB, id uint) error {
+	return RestoreTrash(db, "users", id)
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidValuation is wrapped by errors caused by bad valuation input.
+var ErrInvalidValuation = errors.New("invalid valuation")
+
+// ListValuations returns a query for the valuations of a camera, newest
+// first.
+func ListValuations(db *gorm.DB, cameraID interface{}) *gorm.DB {
+	return db.Model(&models.Valuation{}).
+		Where("camera_id = ?", cameraID).
+		Order("valued_on DESC, id DESC")
+}
+
+// FindValuation loads one valuation of a camera.
+func FindValuation(db *gorm.DB, cameraID, id interface{}) (models.Valuation, error) {
+	var valuation models.Valuation
+	err := db.Where("camera_id = ? AND id = ?", cameraID, id).First(&valuation).Error
+	return valuation, err
+}
+
+// SaveValuation validates req, applies it to valuation and creates or
+// updates the row. The camera's version moves on, as its current estimate
+// may have changed.
+func SaveValuation(db *gorm.DB, valuation *models.Valuation, req *models.ValuationRequest) error {
+	if err := validateValuation(req); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		req.ApplyTo(valuation)
+		if err := tx.Save(valuation).Error; err != nil {
+			return err
+		}
+		return touchVersion(tx, &models.Camera{}, valuation.CameraID)
+	})
+}
+
+// DeleteValuation removes a valuation from its camera.
+func DeleteValuation(db *gorm.DB, valuation *models.Valuation) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(valuation).Error; err != nil {
+			return err
+		}
+		return touchVersion(tx, &models.Camera{}, valuation.CameraID)
+	})
+}
+
+// validateValuation checks req and normalises its currency and condition.
+func validateValuation(req *models.ValuationRequest) error {
+	if req.AmountMax == 0 {
+		req.AmountMax = req.AmountMin
+	}
+	if req.AmountMin <= 0 {
+		return fmt.Errorf("%w: amount_min must be greater than 0", ErrInvalidValuation)
+	}
+	if req.AmountMax < req.AmountMin {
+		return fmt.Errorf("%w: amount_max (%g) is below amount_min (%g)", ErrInvalidValuation, req.AmountMax, req.AmountMin)
+	}
+
+	currency, ok := models.NormalizeCurrency(req.Currency)
+	if !ok {
+		return fmt.Errorf("%w: currency must be a three-letter ISO 4217 code, e.g. GBP", ErrInvalidValuation)
+	}
+	req.Currency = currency
+
+	condition, ok := models.ParseCondition(req.Condition)
+	if !ok {
+		return fmt.Errorf("%w: unknown condition %q (use %s)", ErrInvalidValuation, req.Condition, strings.Join(models.ConditionGrades, ", "))
+	}
+	req.Condition = condition
+
+	if req.ValuedOn.IsZero() {
+		return fmt.Errorf("%w: valued_on is required", ErrInvalidValuation)
+	}
+	if req.ValuedOn.After(time.Now()) {
+		return fmt.Errorf("%w: valued_on is in the future", ErrInvalidValuation)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrVersionConflict means a record was changed by another request after the
+// caller read it.
+var ErrVersionConflict = errors.New("record was changed by another request")
+
+// claimVersion moves a record from version to the next one, failing with
+// ErrVersionConflict if it is no longer at version. Run inside the write's
+// transaction, it holds the row until commit so concurrent writers of the
+// same version cannot both succeed.
+func claimVersion(tx *gorm.DB, model interface{}, id uint, version uint) error {
+	result := tx.Model(model).
+		Where("id = ? AND version = ?", id, version).
+		UpdateColumn("version", gorm.Expr("version + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// touchVersion moves a record to its next version without checking the
+// current one, for changes to data embedded in its representation, such as
+// a camera's valuations or manufacturer, so clients holding the old ETag
+// refetch it.
+func touchVersion(tx *gorm.DB, model interface{}, id uint) error {
+	return tx.Model(model).
+		Where("id = ?", id).
+		UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
+
+// touchVersions moves every record of model whose ID is in ids (a list or
+// subquery) to its next version, like touchVersion. Trashed records are
+// included so they do not come back with a stale version when restored.
+func touchVersions(tx *gorm.DB, model interface{}, ids interface{}) error {
+	return tx.Unscoped().Model(model).
+		Where("id IN (?)", ids).
+		UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
+
+// linkedCameraIDs is a subquery of the cameras linked to the ephemera items
+// matched by query and args.
+func linkedCameraIDs(tx *gorm.DB, query string, args ...interface{}) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&models.EphemeraCamera{}).Select("camera_id").Where(query, args...)
+}
+
+// linkedEphemeraIDs is a subquery of the ephemera items linked to the
+// cameras matched by query and args.
+func linkedEphemeraIDs(tx *gorm.DB, query string, args ...interface{}) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&models.EphemeraCamera{}).Select("ephemera_id").Where(query, args...)
+}
+
+// touchLinkedCameras moves the cameras linked to an ephemera item to their
+// next version, as their "ephemera" include embeds it.
+func touchLinkedCameras(tx *gorm.DB, ephemeraID uint) error {
+	return touchVersions(tx, &models.Camera{}, linkedCameraIDs(tx, "ephemera_id = ?", ephemeraID))
+}
+
+// touchLinkedEphemera moves the ephemera items linked to a camera to their
+// next version, as their related_cameras embed it.
+func touchLinkedEphemera(tx *gorm.DB, cameraID uint) error {
+	return touchVersions(tx, &models.Ephemera{}, linkedEphemeraIDs(tx, "camera_id = ?", cameraID))
+}
+
+// touchManufacturerCameras moves the cameras of a manufacturer, and the
+// ephemera items linked to them, to their next version, as both embed the
+// manufacturer.
+func touchManufacturerCameras(tx *gorm.DB, manufacturerID uint) error {
+	cameraIDs := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.Camera{}).Select("id").Where("manufacturer_id = ?", manufacturerID)
+	if err := touchVersions(tx, &models.Ephemera{}, linkedEphemeraIDs(tx, "camera_id IN (?)", cameraIDs)); err != nil {
+		return err
+	}
+	return touchVersions(tx, &models.Camera{}, cameraIDs)
+}
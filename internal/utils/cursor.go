@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CursorParam is the query parameter selecting cursor pagination. An empty
+// value asks for the first page.
+const CursorParam = "cursor"
+
+// ErrInvalidCursor means a cursor could not be decoded or was issued for a
+// different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// KeysetColumn is one column of the order that cursor pagination walks.
+type KeysetColumn struct {
+	// Column is the qualified column, e.g. "cameras.name"
+	Column string
+	Desc   bool
+}
+
+// Keyset is the order of a cursor-paginated listing. Its last column must be
+// unique, normally the primary key, so every row has its own position.
+type Keyset []KeysetColumn
+
+// signature identifies the order a cursor was issued for.
+func (k Keyset) signature() string {
+	columns := make([]string, len(k))
+	for i, column := range k {
+		columns[i] = column.Column
+		if column.Desc {
+			columns[i] += " desc"
+		}
+	}
+	return strings.Join(columns, ",")
+}
+
+// cursor is the decoded form of the opaque cursor strings handed to clients:
+// the keyset values of the last row of the previous page.
+type cursor struct {
+	Order  string        `json:"o"`
+	Values []interface{} `json:"v"`
+}
+
+func encodeCursor(c cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(value string, keyset Keyset) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// Numbers are kept exact so IDs compare correctly
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var c cursor
+	if err := decoder.Decode(&c); err != nil || len(c.Values) != len(keyset) {
+		return nil, ErrInvalidCursor
+	}
+	if c.Order != keyset.signature() {
+		return nil, fmt.Errorf("%w: it was issued for a different sort order", ErrInvalidCursor)
+	}
+
+	for i, value := range c.Values {
+		if number, ok := value.(json.Number); ok {
+			if n, err := number.Int64(); err == nil {
+				c.Values[i] = n
+			} else if f, err := number.Float64(); err == nil {
+				c.Values[i] = f
+			}
+		}
+	}
+	return c.Values, nil
+}
+
+// CursorPage is one page of a listing paginated by keyset rather than
+// offset, so it stays fast on deep pages and never skips or repeats rows
+// when records change in between.
+type CursorPage struct {
+	keyset Keyset
+	after  []interface{}
+	size   int
+}
+
+// NewCursorPage returns the page requested by the "cursor" query parameter,
+// or nil when the request uses page numbers instead.
+func NewCursorPage(c *gin.Context, keyset Keyset) (*CursorPage, error) {
+	value, ok := c.GetQuery(CursorParam)
+	if !ok {
+		return nil, nil
+	}
+
+	page := &CursorPage{keyset: keyset, size: PageSize(c)}
+	if value == "" {
+		return page, nil
+	}
+
+	after, err := decodeCursor(value, keyset)
+	if err != nil {
+		return nil, err
+	}
+	page.after = after
+	return page, nil
+}
+
+// scope orders query by the keyset, replacing any existing order, starts
+// after the cursor and fetches one extra row to tell whether a next page
+// exists.
+func (p *CursorPage) scope(db *gorm.DB) *gorm.DB {
+	order := clause.OrderBy{Columns: make([]clause.OrderByColumn, len(p.keyset))}
+	for i, column := range p.keyset {
+		order.Columns[i] = clause.OrderByColumn{
+			Column:  clause.Column{Name: column.Column, Raw: true},
+			Desc:    column.Desc,
+			Reorder: i == 0,
+		}
+	}
+	db = db.Order(order)
+
+	if p.after != nil {
+		// (a > ?) OR (a = ? AND b > ?) OR ... for each column in turn
+		var terms []string
+		var vars []interface{}
+		for i, column := range p.keyset {
+			var conditions []string
+			for j := 0; j < i; j++ {
+				conditions = append(conditions, p.keyset[j].Column+" = ?")
+				vars = append(vars, p.after[j])
+			}
+
+			operator := " > ?"
+			if column.Desc {
+				operator = " < ?"
+			}
+			conditions = append(conditions, column.Column+operator)
+			vars = append(vars, p.after[i])
+
+			terms = append(terms, "("+strings.Join(conditions, " AND ")+")")
+		}
+		db = db.Where("("+strings.Join(terms, " OR ")+")", vars...)
+	}
+
+	return db.Limit(p.size + 1)
+}
+
+// Find loads the page into dest, a pointer to a slice of models, and returns
+// the cursor of the next page, or "" on the last page.
+func (p *CursorPage) Find(query *gorm.DB, dest interface{}) (string, error) {
+	result := query.Scopes(p.scope).Find(dest)
+	if result.Error != nil {
+		return "", result.Error
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.Len() <= p.size {
+		return "", nil
+	}
+	rows.SetLen(p.size)
+	last := rows.Index(p.size - 1)
+
+	next := cursor{Order: p.keyset.signature(), Values: make([]interface{}, len(p.keyset))}
+	for i, column := range p.keyset {
+		name := column.Column[strings.LastIndex(column.Column, ".")+1:]
+		field := result.Statement.Schema.LookUpField(name)
+		if field == nil {
+			return "", fmt.Errorf("keyset column %q is not a field of %s", column.Column, result.Statement.Schema.Name)
+		}
+		next.Values[i], _ = field.ValueOf(result.Statement.Context, last)
+	}
+
+	return encodeCursor(next)
+}
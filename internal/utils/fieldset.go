@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidFieldset means "fields" or "include" named something the
+// resource does not have.
+var ErrInvalidFieldset = errors.New("invalid fieldset")
+
+// Fieldset is the response shape requested by the comma-separated "fields"
+// and "include" query parameters: which fields of a resource to return and
+// which related resources to embed.
+type Fieldset struct {
+	fields  map[string]bool
+	include map[string]bool
+}
+
+// ParseFieldset validates the request's fields against the JSON fields of
+// response and its includes against the relations the resource can embed.
+func ParseFieldset(c *gin.Context, response interface{}, relations ...string) (*Fieldset, error) {
+	allowedFields := jsonFieldNames(reflect.TypeOf(response))
+	allowedRelations := make(map[string]bool, len(relations))
+	for _, relation := range relations {
+		allowedRelations[relation] = true
+		allowedFields[relation] = true
+	}
+
+	fieldset := &Fieldset{}
+
+	include, err := parseList(c.Query("include"), "include", allowedRelations)
+	if err != nil {
+		return nil, err
+	}
+	fieldset.include = include
+
+	fields, err := parseList(c.Query("fields"), "fields", allowedFields)
+	if err != nil {
+		return nil, err
+	}
+	fieldset.fields = fields
+
+	return fieldset, nil
+}
+
+func parseList(value, param string, allowed map[string]bool) (map[string]bool, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	names := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !allowed[name] {
+			return nil, fmt.Errorf("%w: unknown %s %q (use %s)", ErrInvalidFieldset, param, name, strings.Join(sortedKeys(allowed), ", "))
+		}
+		names[name] = true
+	}
+	return names, nil
+}
+
+// jsonFieldNames returns the names the fields of a struct type marshal to.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Includes reports whether the relation was asked to be embedded.
+func (f *Fieldset) Includes(relation string) bool {
+	return f.include[relation]
+}
+
+// IsDefault reports whether the full response is wanted unchanged.
+func (f *Fieldset) IsDefault() bool {
+	return f.fields == nil && f.include == nil
+}
+
+// Shape returns response cut down to the requested fields, with the
+// embedded relations added. Embedded relations are kept whether or not they
+// are listed in fields. The default fieldset returns response unchanged.
+func (f *Fieldset) Shape(response interface{}, embedded map[string]interface{}) (interface{}, error) {
+	if f.IsDefault() {
+		return response, nil
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	object := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		if f.fields == nil || f.fields[name] {
+			object[name] = value
+		}
+	}
+	for relation, value := range embedded {
+		if f.include[relation] {
+			object[relation] = value
+		}
+	}
+
+	return object, nil
+}
+
+// String returns the fieldset in a canonical form, with names sorted, such
+// as "fields=id,name;include=images". The default fieldset is "".
+func (f *Fieldset) String() string {
+	var parts []string
+	if f.fields != nil {
+		parts = append(parts, "fields="+strings.Join(sortedKeys(f.fields), ","))
+	}
+	if f.include != nil {
+		parts = append(parts, "include="+strings.Join(sortedKeys(f.include), ","))
+	}
+	return strings.Join(parts, ";")
+}
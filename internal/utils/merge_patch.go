@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// MergePatchContentType is the media type of RFC 7396 JSON merge patches.
+const MergePatchContentType = "application/merge-patch+json"
+
+// ErrInvalidMergePatch is returned when a merge patch is not a JSON object.
+var ErrInvalidMergePatch = errors.New("merge patch must be a JSON object")
+
+// MergePatch applies an RFC 7396 JSON merge patch to document and returns the
+// result: members of the patch replace those of the document, objects are
+// merged recursively and null removes a member.
+func MergePatch(document, patch []byte) ([]byte, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+	if _, ok := patchValue.(map[string]interface{}); !ok {
+		return nil, ErrInvalidMergePatch
+	}
+
+	var target interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergePatch(target, patchValue))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+
+	return targetObject
+}
@@ -1,19 +1,61 @@
 package utils
 
 import (
+	"encoding/json"
+	"fmt"
 	"math"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type Pagination struct {
-	Page       int         `json:"page"`
-	PageSize   int         `json:"page_size"`
-	Total      int64       `json:"total"`
-	TotalPages int         `json:"total_pages"`
-	Data       interface{} `json:"data"`
+	Page       int                     `json:"page"`
+	PageSize   int                     `json:"page_size"`
+	Total      int64                   `json:"total"`
+	TotalPages int                     `json:"total_pages"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+	Data       interface{}             `json:"data"`
+	Facets     map[string][]FacetValue `json:"facets,omitempty"`
+
+	// cursor marks pages fetched by cursor, which have no page number or total
+	cursor bool
+}
+
+// MarshalJSON leaves out the page number and totals of cursor pages, which
+// are never counted.
+func (p Pagination) MarshalJSON() ([]byte, error) {
+	type pagination Pagination
+	if !p.cursor {
+		return json.Marshal(pagination(p))
+	}
+	return json.Marshal(struct {
+		pagination
+		Page       *int   `json:"page,omitempty"`
+		Total      *int64 `json:"total,omitempty"`
+		TotalPages *int   `json:"total_pages,omitempty"`
+	}{pagination: pagination(p)})
+}
+
+// FacetValue is the number of matching records sharing one value of a facet.
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// PageSize returns the requested page size, 10 by default and at most 100.
+func PageSize(c *gin.Context) int {
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	switch {
+	case pageSize > 100:
+		return 100
+	case pageSize <= 0:
+		return 10
+	}
+	return pageSize
 }
 
 func Paginate(c *gin.Context) func(db *gorm.DB) *gorm.DB {
@@ -23,32 +65,32 @@ func Paginate(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 			page = 1
 		}
 
-		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-		switch {
-		case pageSize > 100:
-			pageSize = 100
-		case pageSize <= 0:
-			pageSize = 10
-		}
-
+		pageSize := PageSize(c)
 		offset := (page - 1) * pageSize
 		return db.Offset(offset).Limit(pageSize)
 	}
 }
 
+// CreatePaginationResponse builds a numbered page and sets the Link header
+// to the first, previous, next and last pages.
 func CreatePaginationResponse(c *gin.Context, data interface{}, total int64) Pagination {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	
-	if pageSize > 100 {
-		pageSize = 100
-	}
-	if pageSize <= 0 {
-		pageSize = 10
-	}
+	pageSize := PageSize(c)
 
 	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
 
+	links := []string{pageLink(c, "first", "page", "1")}
+	if page > 1 {
+		links = append(links, pageLink(c, "prev", "page", strconv.Itoa(page-1)))
+	}
+	if page < totalPages {
+		links = append(links, pageLink(c, "next", "page", strconv.Itoa(page+1)))
+	}
+	if totalPages > 0 {
+		links = append(links, pageLink(c, "last", "page", strconv.Itoa(totalPages)))
+	}
+	c.Header("Link", strings.Join(links, ", "))
+
 	return Pagination{
 		Page:       page,
 		PageSize:   pageSize,
@@ -56,4 +98,31 @@ func CreatePaginationResponse(c *gin.Context, data interface{}, total int64) Pag
 		TotalPages: totalPages,
 		Data:       data,
 	}
-}
\ No newline at end of file
+}
+
+// CreateCursorResponse builds a page fetched by cursor and sets the Link
+// header to the first and, unless this is the last page, the next page.
+func CreateCursorResponse(c *gin.Context, data interface{}, nextCursor string) Pagination {
+	links := []string{pageLink(c, "first", CursorParam, "")}
+	if nextCursor != "" {
+		links = append(links, pageLink(c, "next", CursorParam, nextCursor))
+	}
+	c.Header("Link", strings.Join(links, ", "))
+
+	return Pagination{
+		PageSize:   PageSize(c),
+		NextCursor: nextCursor,
+		Data:       data,
+		cursor:     true,
+	}
+}
+
+// pageLink returns an RFC 8288 link to the current request with one query
+// parameter replaced.
+func pageLink(c *gin.Context, rel, param, value string) string {
+	query := c.Request.URL.Query()
+	query.Set(param, value)
+
+	link := url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf(`<%s>; rel="%s"`, link.String(), rel)
+}
@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/middleware"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+// mailbox is a Mailer that keeps what it sends.
+type mailbox struct {
+	mu   sync.Mutex
+	sent []services.Message
+}
+
+func (m *mailbox) Send(msg services.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// messages returns a copy of what has been sent so far.
+func (m *mailbox) messages() []services.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]services.Message(nil), m.sent...)
+}
+
+var mailedToken = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+// lastToken returns the token in the link of the last email sent.
+func (m *mailbox) lastToken() string {
+	sent := m.messages()
+	if len(sent) == 0 {
+		return ""
+	}
+	match := mailedToken.FindStringSubmatch(sent[len(sent)-1].Body)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func setupAccountRouter(mail *mailbox) func(method, url, body, token string) *httptest.ResponseRecorder {
+	db := setupTestDB()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/register", handlers.Register(db, mail))
+	router.POST("/auth/login", handlers.Login(db))
+	router.POST("/auth/forgot-password", handlers.ForgotPassword(db, mail))
+	router.POST("/auth/reset-password", handlers.ResetPassword(db))
+	router.POST("/auth/verify-email", handlers.VerifyEmail(db))
+	router.POST("/auth/verify-email/resend", middleware.AuthRequired(db), handlers.ResendVerification(db, mail))
+	router.GET("/auth/profile", middleware.AuthRequired(db), handlers.GetProfile(db))
+	router.POST("/cameras", middleware.AuthRequired(db), middleware.VerifiedEmailRequired(), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	send := func(method, url, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	return send
+}
+
+func TestEmailVerification(t *testing.T) {
+	mail := &mailbox{}
+	send := setupAccountRouter(mail)
+
+	w := send("POST", "/auth/register", `{"email": "new@example.com", "password": "password123", "first_name": "New", "last_name": "User"}`, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var auth models.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &auth)
+	assert.Nil(t, auth.User.EmailVerifiedAt)
+
+	if assert.Len(t, mail.sent, 1) {
+		assert.Equal(t, "new@example.com", mail.sent[0].To)
+		assert.Contains(t, mail.sent[0].Body, "/verify-email?token=")
+	}
+	firstToken := mail.lastToken()
+
+	// Unverified accounts are kept from write routes
+	assert.Equal(t, http.StatusForbidden, send("POST", "/cameras", "", auth.Token).Code)
+
+	// Asking again replaces the first link
+	assert.Equal(t, http.StatusAccepted, send("POST", "/auth/verify-email/resend", "", auth.Token).Code)
+	assert.Len(t, mail.sent, 2)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/auth/verify-email", `{"token": "`+firstToken+`"}`, "").Code)
+
+	w = send("POST", "/auth/verify-email", `{"token": "`+mail.lastToken()+`"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var profile models.UserResponse
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	assert.True(t, profile.EmailVerified)
+
+	// Tokens work once
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/auth/verify-email", `{"token": "`+mail.lastToken()+`"}`, "").Code)
+
+	assert.Equal(t, http.StatusCreated, send("POST", "/cameras", "", auth.Token).Code)
+	assert.Equal(t, http.StatusConflict, send("POST", "/auth/verify-email/resend", "", auth.Token).Code)
+}
+
+func TestPasswordReset(t *testing.T) {
+	mail := &mailbox{}
+	send := setupAccountRouter(mail)
+
+	w := send("POST", "/auth/register", `{"email": "forgetful@example.com", "password": "password123", "first_name": "Forgetful", "last_name": "User"}`, "")
+	var auth models.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &auth)
+	mail.sent = nil
+
+	// Addresses are the same account whatever their case
+	w = send("POST", "/auth/register", `{"email": "FORGETFUL@example.com", "password": "password123", "first_name": "Forgetful", "last_name": "Again"}`, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Unknown addresses get the same answer but no email
+	w = send("POST", "/auth/forgot-password", `{"email": "nobody@example.com"}`, "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	// The email is sent after answering; the address matches in any case
+	w = send("POST", "/auth/forgot-password", `{"email": "Forgetful@Example.com"}`, "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Eventually(t, func() bool { return len(mail.messages()) > 0 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	if sent := mail.messages(); assert.Len(t, sent, 1) {
+		assert.Equal(t, "forgetful@example.com", sent[0].To)
+		assert.Contains(t, sent[0].Body, "/reset-password?token=")
+	}
+	token := mail.lastToken()
+
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/auth/reset-password", `{"token": "wrong", "password": "newpassword"}`, "").Code)
+	assert.Equal(t, http.StatusNoContent, send("POST", "/auth/reset-password", `{"token": "`+token+`", "password": "newpassword"}`, "").Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/auth/reset-password", `{"token": "`+token+`", "password": "another1"}`, "").Code)
+
+	// Existing sessions are logged out and only the new password works
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/auth/profile", "", auth.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, send("POST", "/auth/login", `{"email": "forgetful@example.com", "password": "password123"}`, "").Code)
+
+	w = send("POST", "/auth/login", `{"email": "FORGETFUL@example.com", "password": "newpassword"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Reading the reset email proved the address
+	json.Unmarshal(w.Body.Bytes(), &auth)
+	assert.NotNil(t, auth.User.EmailVerifiedAt)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := &services.FileMailer{Dir: filepath.Join(dir, "mail")}
+
+	assert.NoError(t, mailer.Send(services.Message{To: "someone@example.com", Subject: "Hello", Body: "Line one\nLine two"}))
+
+	files, _ := os.ReadDir(filepath.Join(dir, "mail"))
+	if assert.Len(t, files, 1) {
+		content, _ := os.ReadFile(filepath.Join(dir, "mail", files[0].Name()))
+		assert.Contains(t, string(content), "To: someone@example.com\r\n")
+		assert.Contains(t, string(content), "Subject: Hello\r\n")
+		assert.Contains(t, string(content), "Line one\r\nLine two")
+	}
+}
@@ -20,7 +20,7 @@ func TestRegister(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	router.POST("/auth/register", handlers.Register(db))
+	router.POST("/auth/register", handlers.Register(db, &mailbox{}))
 
 	// Create request
 	reqBody := models.RegisterRequest{
@@ -55,7 +55,7 @@ func TestRegisterDuplicateEmail(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	router.POST("/auth/register", handlers.Register(db))
+	router.POST("/auth/register", handlers.Register(db, &mailbox{}))
 
 	// Try to register with same email
 	reqBody := models.RegisterRequest{
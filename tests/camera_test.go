@@ -10,29 +10,40 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/database"
 	"github.com/Candoo/thornton-pickard-api/internal/handlers"
 	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	
 	_ "modernc.org/sqlite"
 )
 
-func setupTestDB() *gorm.DB {
-	// Try different SQLite connection strings
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+// openTestDB opens an empty in-memory database. It uses the pure-Go
+// modernc.org/sqlite driver, which ships with FTS5 for camera search.
+func openTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: "sqlite", DSN: "file::memory:"}), &gorm.Config{})
 	if err != nil {
 		fmt.Printf("ERROR: %v\n", err)
 		panic("failed to connect to test database: " + err.Error())
 	}
+
+	// Every connection to ":memory:" is a separate database
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic("failed to access test database: " + err.Error())
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return db
+}
+
+func setupTestDB() *gorm.DB {
+	db := openTestDB()
 	
 	// Auto-migrate all models
-	err = db.AutoMigrate(
-		&models.Camera{},
-		&models.User{},
-		&models.Manufacturer{},
-		&models.Ephemera{},
-	)
+	err := database.Migrate(db)
 	if err != nil {
 		fmt.Printf("MIGRATION ERROR: %v\n", err)
 		panic("failed to migrate test database: " + err.Error())
@@ -41,13 +52,23 @@ func setupTestDB() *gorm.DB {
 	return db
 }
 
+// createTestManufacturer inserts a manufacturer for cameras to reference.
+func createTestManufacturer(db *gorm.DB, name string) models.Manufacturer {
+	manufacturer := models.Manufacturer{Name: name}
+	if err := db.Create(&manufacturer).Error; err != nil {
+		panic("failed to create test manufacturer: " + err.Error())
+	}
+	return manufacturer
+}
+
 func TestGetCameras(t *testing.T) {
 	db := setupTestDB()
+	manufacturer := createTestManufacturer(db, "Test Manufacturer")
 	
 	// Create test camera
 	camera := models.Camera{
 		Name: 	 		"Test Camera",
-		Manufacturer: 	"Test Manufacturer",
+		ManufacturerID: &manufacturer.ID,
 		YearIntroduced: 1900,
 	}
 	db.Create(&camera)
@@ -75,11 +96,12 @@ func TestGetCameras(t *testing.T) {
 
 func TestGetCamera(t *testing.T) {
 	db := setupTestDB()
+	manufacturer := createTestManufacturer(db, "Test Manufacturer")
 	
 	// Create test camera
 	camera := models.Camera{
 		Name: 	 		"Test Camera",
-		Manufacturer: 	"Test Manufacturer",
+		ManufacturerID: &manufacturer.ID,
 		YearIntroduced: 1900,
 	}
 	db.Create(&camera)
@@ -104,10 +126,12 @@ func TestGetCamera(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.Equal(t, "Test Camera", response["name"])
+	assert.Equal(t, "Test Manufacturer", response["manufacturer"].(map[string]interface{})["name"])
 }
 
 func TestCreateCamera(t *testing.T) {
 	db := setupTestDB()
+	createTestManufacturer(db, "New Manufacturer")
 
 	// Setup router
 	gin.SetMode(gin.TestMode)
@@ -117,7 +141,7 @@ func TestCreateCamera(t *testing.T) {
 	router.POST("/cameras", cameraHandler.CreateCamera)
 
 	// Create request body
-	camera := models.Camera{
+	camera := models.CameraRequest{
 		Name: 	 		"New Camera",
 		Manufacturer: 	"New Manufacturer",
 		YearIntroduced: 1910,
@@ -142,11 +166,12 @@ func TestCreateCamera(t *testing.T) {
 // Renamed from TestSearchCameras to reflect the combined endpoint functionality
 func TestSearchAndFilter(t *testing.T) {
 	db := setupTestDB()
+	manufacturer := createTestManufacturer(db, "Thornton-Pickard")
 	
 	// Create test cameras
 	cameras := []models.Camera{
-		{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard", YearIntroduced: 1909},
-		{Name: "Imperial", Manufacturer: "Thornton-Pickard", YearIntroduced: 1895},
+		{Name: "Ruby Reflex", ManufacturerID: &manufacturer.ID, YearIntroduced: 1909},
+		{Name: "Imperial", ManufacturerID: &manufacturer.ID, YearIntroduced: 1895},
 	}
 	db.Create(&cameras)
 
@@ -175,12 +200,13 @@ func TestSearchAndFilter(t *testing.T) {
 
 func TestPagination(t *testing.T) {
 	db := setupTestDB()
+	manufacturer := createTestManufacturer(db, "Test")
 	
 	// Create 15 test cameras
 	for i := 1; i <= 15; i++ {
 		camera := models.Camera{
 			Name: 	 		fmt.Sprintf("Camera %d", i),
-			Manufacturer: 	"Test",
+			ManufacturerID: &manufacturer.ID,
 			YearIntroduced: 1900 + i,
 		}
 		db.Create(&camera)
@@ -207,4 +233,282 @@ func TestPagination(t *testing.T) {
 	assert.Equal(t, float64(10), response["page_size"])
 	assert.Equal(t, float64(15), response["total"])
 	assert.Equal(t, float64(2), response["total_pages"])
-}
\ No newline at end of file
+}
+
+func TestFilterByPlateSizeAndFeature(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	// Create test cameras
+	requests := []models.CameraRequest{
+		{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard", PlateSizes: []string{"Quarter-plate"}, Features: []string{"Reflex viewing"}},
+		{Name: "Imperial", Manufacturer: "Thornton-Pickard", PlateSizes: []string{"Half-plate", "Whole-plate"}, Features: []string{"Rising front"}},
+		{Name: "Royal Ruby", Manufacturer: "Thornton-Pickard", PlateSizes: []string{"Half-plate"}, Features: []string{"Rising front", "Reflex viewing"}},
+	}
+	for i := range requests {
+		_, err := services.CreateCamera(db, &requests[i])
+		assert.NoError(t, err)
+	}
+
+	// Setup router
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.GET("/cameras", cameraHandler.GetCameras)
+
+	// Filter by plate size only
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cameras?plate_size=Half-plate", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data []models.CameraResponse `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 2)
+
+	// Combine plate size and feature
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/cameras?plate_size=Half-plate&feature=Reflex+viewing", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	if assert.Len(t, response.Data, 1) {
+		assert.Equal(t, "Royal Ruby", response.Data[0].Name)
+		assert.Equal(t, []string{"Half-plate"}, response.Data[0].PlateSizes)
+		assert.Equal(t, []string{"Reflex viewing", "Rising front"}, response.Data[0].Features)
+	}
+}
+
+func TestCreateCameraUnknownManufacturer(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.POST("/cameras", cameraHandler.CreateCamera)
+
+	// Spelling variants resolve to the existing manufacturer
+	jsonData, _ := json.Marshal(models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "thornton pickard"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cameras", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.CameraResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Thornton-Pickard", response.Manufacturer.Name)
+
+	// Unknown manufacturers are rejected
+	jsonData, _ = json.Marshal(models.CameraRequest{Name: "Brownie", Manufacturer: "Kodak"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/cameras", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetManufacturerCameras(t *testing.T) {
+	db := setupTestDB()
+	thorntonPickard := createTestManufacturer(db, "Thornton-Pickard")
+	kodak := createTestManufacturer(db, "Kodak")
+
+	cameras := []models.Camera{
+		{Name: "Ruby Reflex", ManufacturerID: &thorntonPickard.ID},
+		{Name: "Imperial", ManufacturerID: &thorntonPickard.ID},
+		{Name: "Brownie", ManufacturerID: &kodak.ID},
+	}
+	db.Create(&cameras)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.GET("/manufacturers/:id/cameras", cameraHandler.GetManufacturerCameras)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/manufacturers/%d/cameras?facets=manufacturer", thorntonPickard.ID), nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Total  int64                               `json:"total"`
+		Data   []models.CameraResponse             `json:"data"`
+		Facets map[string][]map[string]interface{} `json:"facets"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), response.Total)
+	assert.Len(t, response.Facets["manufacturer"], 1)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/manufacturers/999/cameras", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPatchCamera(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+	createTestManufacturer(db, "Sanderson")
+
+	yearDiscontinued := 1925
+	camera, err := services.CreateCamera(db, &models.CameraRequest{
+		Name:             "Ruby Reflex",
+		Manufacturer:     "Thornton-Pickard",
+		YearIntroduced:   1912,
+		YearDiscontinued: &yearDiscontinued,
+		Lens:             "Cooke",
+		PlateSizes:       []string{"Quarter-plate"},
+		Features:         []string{"Reflex viewing"},
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.PATCH("/cameras/:id", cameraHandler.PatchCamera)
+
+	patch := func(body, contentType string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PATCH", fmt.Sprintf("/cameras/%d", camera.ID), bytes.NewBufferString(body))
+		req.Header.Set("If-Match", "*")
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Absent fields are kept, null clears and arrays replace
+	w := patch(`{"year_discontinued": null, "plate_sizes": ["Half-plate", "Quarter-plate"], "manufacturer": "sanderson"}`, "application/merge-patch+json")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.CameraResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ruby Reflex", response.Name)
+	assert.Equal(t, "Cooke", response.Lens)
+	assert.Nil(t, response.YearDiscontinued)
+	assert.Equal(t, []string{"Half-plate", "Quarter-plate"}, response.PlateSizes)
+	assert.Equal(t, []string{"Reflex viewing"}, response.Features)
+	assert.Equal(t, "Sanderson", response.Manufacturer.Name)
+
+	// The document a client read can be patched and sent back whole
+	response.Lens = "Ross"
+	response.Manufacturer = &models.ManufacturerSummary{ID: camera.Manufacturer.ID}
+	body, _ := json.Marshal(response)
+	w = patch(string(body), "application/merge-patch+json")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "Ross", response.Lens)
+	assert.Equal(t, "Thornton-Pickard", response.Manufacturer.Name)
+
+	w = patch(`{"manufacturer": {"name": "Sanderson"}}`, "application/json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "Sanderson", response.Manufacturer.Name)
+
+	// Invalid patches are rejected without changes
+	for _, body := range []string{
+		`{"name": null}`,
+		`{"name": 12}`,
+		`{"colour": "red"}`,
+		`["not", "an", "object"]`,
+		`{"manufacturer_id": 999}`,
+		`{"manufacturer": {"id": 999}}`,
+		`{"manufacturer": {"country": "France"}}`,
+		`{"manufacturer": null}`,
+		`{"id": 12}`,
+		`{"rarity_rank": 3}`,
+		`{"created_at": "2001-01-01T00:00:00Z"}`,
+	} {
+		w = patch(body, "application/json")
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w = patch(`{"lens": "Dallmeyer"}`, "text/plain")
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	reloaded, err := services.FindCamera(db, camera.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ruby Reflex", reloaded.Name)
+	assert.Equal(t, "Ross", reloaded.Lens)
+	assert.Equal(t, "Sanderson", reloaded.Manufacturer.Name)
+}
+
+func TestCameraImagesKeepCaptions(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	front := "Front, with the hood raised"
+	camera, err := services.CreateCamera(db, &models.CameraRequest{
+		Name:         "Ruby Reflex",
+		Manufacturer: "Thornton-Pickard",
+		Images:       []models.CameraImageRequest{{URL: "/uploads/front.jpg", Caption: &front}, {URL: "/uploads/side.jpg"}},
+	})
+	assert.NoError(t, err)
+	frontID := camera.Images[0].ID
+
+	// Saving the URLs alone keeps the rows and their captions
+	req := camera.ToRequest()
+	req.Images = nil
+	req.ImageURLs = []string{"/uploads/side.jpg", "/uploads/front.jpg", "/uploads/back.jpg"}
+	assert.NoError(t, services.UpdateCamera(db, &camera, &req))
+
+	if assert.Len(t, camera.Images, 3) {
+		assert.Equal(t, "/uploads/front.jpg", camera.Images[1].URL)
+		assert.Equal(t, frontID, camera.Images[1].ID)
+		assert.Equal(t, front, camera.Images[1].Caption)
+		assert.Equal(t, 1, camera.Images[1].Position)
+	}
+
+	// Captions can be set through a patch in the include=images shape
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.GET("/cameras/:id", cameraHandler.GetCamera)
+	router.PATCH("/cameras/:id", cameraHandler.PatchCamera)
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", fmt.Sprintf("/cameras/%d?include=images", camera.ID), nil)
+	router.ServeHTTP(w, request)
+
+	var document struct {
+		Images []models.CameraImage `json:"images"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &document)
+	if assert.Len(t, document.Images, 3) {
+		assert.Equal(t, front, document.Images[1].Caption)
+	}
+
+	document.Images[0].Caption = "Side"
+	patch, _ := json.Marshal(map[string]interface{}{"images": document.Images[:2]})
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("PATCH", fmt.Sprintf("/cameras/%d", camera.ID), bytes.NewBuffer(patch))
+	request.Header.Set("If-Match", "*")
+	request.Header.Set("Content-Type", "application/merge-patch+json")
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	reloaded, err := services.FindCamera(db, camera.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, reloaded.Images, 2) {
+		assert.Equal(t, "Side", reloaded.Images[0].Caption)
+		assert.Equal(t, frontID, reloaded.Images[1].ID)
+		assert.Equal(t, front, reloaded.Images[1].Caption)
+	}
+}
@@ -0,0 +1,263 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// setupEphemeraRouter registers the ephemera routes without the auth
+// middleware.
+func setupEphemeraRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	router.GET("/ephemera", handlers.GetEphemera(db))
+	router.GET("/ephemera/:id", handlers.GetEphemeraItem(db))
+	router.POST("/ephemera", handlers.CreateEphemeraItem(db))
+	router.PUT("/ephemera/:id", handlers.UpdateEphemeraItem(db))
+	router.PATCH("/ephemera/:id", handlers.PatchEphemeraItem(db))
+	router.DELETE("/ephemera/:id", handlers.DeleteEphemeraItem(db))
+
+	return router
+}
+
+func TestGetEphemeraFilters(t *testing.T) {
+	db := setupTestDB()
+
+	items := []models.Ephemera{
+		{Type: "catalog", Title: "Thornton-Pickard Catalogue", Year: 1905, Description: "Full range of field cameras"},
+		{Type: "catalog", Title: "Thornton-Pickard Catalogue", Year: 1912, Description: "Introduces the Ruby Reflex"},
+		{Type: "manual", Title: "Ruby Reflex Instructions", Year: 1914},
+		{Type: "advertisement", Title: "Amateur Photographer advert", Year: 1925, Description: "Imperial camera"},
+	}
+	db.Create(&items)
+
+	router := setupEphemeraRouter(db)
+
+	tests := []struct {
+		query string
+		years []int
+	}{
+		{"", []int{1905, 1912, 1914, 1925}},
+		{"?type=Catalog", []int{1905, 1912}},
+		{"?year_from=1910&year_to=1920", []int{1912, 1914}},
+		{"?search=ruby", []int{1912, 1914}},
+		{"?search=REFLEX&type=manual", []int{1914}},
+		{"?page=2&page_size=3", []int{1925}},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/ephemera"+tt.query, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, tt.query)
+
+		var response struct {
+			Total int64                     `json:"total"`
+			Data  []models.EphemeraResponse `json:"data"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err, tt.query)
+
+		years := make([]int, len(response.Data))
+		for i, item := range response.Data {
+			years[i] = item.Year
+		}
+		assert.Equal(t, tt.years, years, tt.query)
+	}
+}
+
+func TestEphemeraCRUD(t *testing.T) {
+	db := setupTestDB()
+	router := setupEphemeraRouter(db)
+
+	// Create
+	body, _ := json.Marshal(models.EphemeraRequest{Type: "manual", Title: "Imperial Instructions", Year: 1910})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/ephemera", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created models.EphemeraResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.NotZero(t, created.ID)
+
+	// Patch leaves absent fields alone
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", fmt.Sprintf("/ephemera/%d", created.ID), bytes.NewBufferString(`{"year": 1911}`))
+	req.Header.Set("If-Match", "*")
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var patched models.EphemeraResponse
+	json.Unmarshal(w.Body.Bytes(), &patched)
+	assert.Equal(t, "Imperial Instructions", patched.Title)
+	assert.Equal(t, 1911, patched.Year)
+
+	// Put requires the full object
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("/ephemera/%d", created.ID), bytes.NewBufferString(`{"year": 1912}`))
+	req.Header.Set("If-Match", "*")
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("/ephemera/%d", created.ID), bytes.NewBufferString(`{"type": "catalog", "title": "Imperial Leaflet"}`))
+	req.Header.Set("If-Match", "*")
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated models.EphemeraResponse
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.Equal(t, "Imperial Leaflet", updated.Title)
+	assert.Zero(t, updated.Year)
+
+	// Delete
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/ephemera/%d", created.ID), nil)
+	req.Header.Set("If-Match", "*")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/ephemera/%d", created.ID), nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestEphemeraCameraLinks(t *testing.T) {
+	db := setupTestDB()
+	manufacturer := createTestManufacturer(db, "Thornton-Pickard")
+
+	cameras := []models.Camera{
+		{Name: "Ruby Reflex", ManufacturerID: &manufacturer.ID},
+		{Name: "Imperial", ManufacturerID: &manufacturer.ID},
+	}
+	db.Create(&cameras)
+
+	router := setupEphemeraRouter(db)
+	router.GET("/cameras/:id/ephemera", handlers.GetCameraEphemera(db))
+	router.DELETE("/cameras/:id", handlers.NewCameraHandler(db).DeleteCamera)
+
+	page := 12
+	body, _ := json.Marshal(models.EphemeraRequest{
+		Type:  "catalog",
+		Title: "1912 Catalogue",
+		RelatedCameras: []models.EphemeraCameraLink{
+			{CameraID: cameras[0].ID, Page: &page, Note: "Full-page illustration"},
+			{CameraID: cameras[1].ID},
+		},
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/ephemera", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created models.EphemeraResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if assert.Len(t, created.RelatedCameras, 2) {
+		assert.Equal(t, "Ruby Reflex", created.RelatedCameras[0].Name)
+		assert.Equal(t, "Thornton-Pickard", created.RelatedCameras[0].Manufacturer.Name)
+		assert.Equal(t, 12, *created.RelatedCameras[0].Page)
+		assert.Equal(t, "Full-page illustration", created.RelatedCameras[0].Note)
+	}
+
+	// Unknown cameras are rejected
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", fmt.Sprintf("/ephemera/%d", created.ID), bytes.NewBufferString(`{"related_cameras": [{"camera_id": 999}]}`))
+	req.Header.Set("If-Match", "*")
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Replacing a link does not carry over the page and note of the old one
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", fmt.Sprintf("/ephemera/%d", created.ID), bytes.NewBufferString(fmt.Sprintf(`{"related_cameras": [{"camera_id": %d}, {"camera_id": %d}]}`, cameras[1].ID, cameras[0].ID)))
+	req.Header.Set("If-Match", "*")
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var relinked models.EphemeraResponse
+	json.Unmarshal(w.Body.Bytes(), &relinked)
+	for _, related := range relinked.RelatedCameras {
+		assert.Nil(t, related.Page, related.Name)
+		assert.Empty(t, related.Note, related.Name)
+	}
+
+	// The camera side lists its ephemera
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/cameras/%d/ephemera", cameras[1].ID), nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Total int64                     `json:"total"`
+		Data  []models.EphemeraResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, int64(1), response.Total)
+
+	// Deleting a camera hides its links
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/cameras/%d", cameras[0].ID), nil)
+	req.Header.Set("If-Match", "*")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/ephemera/%d", created.ID), nil)
+	router.ServeHTTP(w, req)
+
+	var item models.EphemeraResponse
+	json.Unmarshal(w.Body.Bytes(), &item)
+	if assert.Len(t, item.RelatedCameras, 1) {
+		assert.Equal(t, "Imperial", item.RelatedCameras[0].Name)
+	}
+
+	// Deleting the ephemera hides it from the camera side
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/ephemera/%d", created.ID), nil)
+	req.Header.Set("If-Match", "*")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/cameras/%d/ephemera", cameras[1].ID), nil)
+	router.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, int64(0), response.Total)
+
+	// The links are kept for a restore
+	var count int64
+	db.Model(&models.EphemeraCamera{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+func TestCameraConditionalRequests(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	camera, err := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard"})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.GET("/cameras/:id", cameraHandler.GetCamera)
+	router.PATCH("/cameras/:id", cameraHandler.PatchCamera)
+	router.DELETE("/cameras/:id", cameraHandler.DeleteCamera)
+
+	cameraURL := fmt.Sprintf("/cameras/%d", camera.ID)
+	send := func(method, body string, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, cameraURL, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Reads carry an ETag and revalidate with If-None-Match
+	w := send("GET", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	tag := w.Header().Get("ETag")
+	assert.Equal(t, `"1"`, tag)
+
+	w = send("GET", "", map[string]string{"If-None-Match": tag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// Writes need If-Match
+	w = send("PATCH", `{"lens": "Cooke"}`, nil)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w = send("PATCH", `{"lens": "Cooke"}`, map[string]string{"If-Match": tag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	newTag := w.Header().Get("ETag")
+	assert.Equal(t, `"2"`, newTag)
+
+	// A client still holding the old ETag is refused
+	w = send("PATCH", `{"lens": "Dallmeyer"}`, map[string]string{"If-Match": tag})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = send("GET", "", map[string]string{"If-None-Match": tag})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send("DELETE", "", map[string]string{"If-Match": tag})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = send("DELETE", "", map[string]string{"If-Match": newTag})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestEphemeraConditionalRequests(t *testing.T) {
+	db := setupTestDB()
+
+	item := models.Ephemera{}
+	assert.NoError(t, services.SaveEphemera(db, &item, &models.EphemeraRequest{Type: "catalog", Title: "1906 Catalogue"}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/ephemera/:id", handlers.UpdateEphemeraItem(db))
+	router.DELETE("/ephemera/:id", handlers.DeleteEphemeraItem(db))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/ephemera/%d", item.ID), bytes.NewBufferString(`{"type": "catalog", "title": "1907 Catalogue"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/ephemera/%d", item.ID), nil)
+	req.Header.Set("If-Match", `W/"2"`)
+	router.ServeHTTP(w, req)
+
+	// Weak tags never satisfy If-Match
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	// A write racing past the If-Match check still loses on the version
+	db.First(&item, item.ID)
+	stale := item
+	assert.NoError(t, services.SaveEphemera(db, &item, &models.EphemeraRequest{Type: "catalog", Title: "1908 Catalogue"}))
+	err := services.SaveEphemera(db, &stale, &models.EphemeraRequest{Type: "catalog", Title: "1909 Catalogue"})
+	assert.True(t, errors.Is(err, services.ErrVersionConflict))
+}
+
+func TestEmbeddedChangesMoveETags(t *testing.T) {
+	db := setupTestDB()
+	manufacturer := createTestManufacturer(db, "Thornton-Pickard")
+
+	camera, err := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard"})
+	assert.NoError(t, err)
+	item := models.Ephemera{}
+	assert.NoError(t, services.SaveEphemera(db, &item, &models.EphemeraRequest{
+		Type: "catalog", Title: "1912 Catalogue", RelatedCameras: []models.EphemeraCameraLink{{CameraID: camera.ID}},
+	}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/cameras/:id", handlers.NewCameraHandler(db).GetCamera)
+	router.GET("/ephemera/:id", handlers.GetEphemeraItem(db))
+
+	cameraURL := fmt.Sprintf("/cameras/%d", camera.ID)
+	ephemeraURL := fmt.Sprintf("/ephemera/%d", item.ID)
+	get := func(url, tag string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		if tag != "" {
+			req.Header.Set("If-None-Match", tag)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Linking the camera moved it on, as it embeds its ephemera
+	cameraTag := get(cameraURL, "").Header().Get("ETag")
+	assert.Equal(t, `"2"`, cameraTag)
+	ephemeraTag := get(ephemeraURL, "").Header().Get("ETag")
+
+	// Renaming the manufacturer moves both on
+	assert.NoError(t, services.SaveManufacturer(db, &manufacturer, &models.ManufacturerRequest{Name: "Thornton Pickard Ltd"}))
+	w := get(cameraURL, cameraTag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Thornton Pickard Ltd")
+	cameraTag = w.Header().Get("ETag")
+	w = get(ephemeraURL, ephemeraTag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	ephemeraTag = w.Header().Get("ETag")
+
+	// Renaming the camera moves its ephemera on
+	camera, _ = services.FindCamera(db, camera.ID)
+	req := camera.ToRequest()
+	req.Name = "Ruby Reflex Tropical"
+	assert.NoError(t, services.UpdateCamera(db, &camera, &req))
+	w = get(ephemeraURL, ephemeraTag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Ruby Reflex Tropical")
+	cameraTag = get(cameraURL, "").Header().Get("ETag")
+
+	// Retitling the ephemera moves the camera on
+	item, _ = services.FindEphemera(db, item.ID)
+	assert.NoError(t, services.SaveEphemera(db, &item, &models.EphemeraRequest{
+		Type: "catalog", Title: "1913 Catalogue", RelatedCameras: []models.EphemeraCameraLink{{CameraID: camera.ID}},
+	}))
+	assert.Equal(t, http.StatusOK, get(cameraURL, cameraTag).Code)
+	cameraTag = get(cameraURL, "").Header().Get("ETag")
+
+	// Each shape of a record has its own ETag
+	w = get(cameraURL+"?fields=name,id", cameraTag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	shapedTag := w.Header().Get("ETag")
+	assert.NotEqual(t, cameraTag, shapedTag)
+	assert.Equal(t, http.StatusNotModified, get(cameraURL+"?fields=id,name", shapedTag).Code)
+	assert.Equal(t, http.StatusOK, get(cameraURL+"?fields=id,name&include=images", shapedTag).Code)
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/middleware"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+func TestCameraExamples(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+	camera, err := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard"})
+	assert.NoError(t, err)
+
+	tokens := map[string]string{}
+	for _, user := range []models.User{
+		{Email: "collector@example.com", Role: "user"},
+		{Email: "other@example.com", Role: "user"},
+		{Email: "admin@example.com", Role: "admin"},
+	} {
+		user.HashPassword("password123")
+		db.Create(&user)
+		session, _ := services.StartSession(db, &user, "")
+		tokens[user.Email] = session.Token
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/cameras/:id/examples", middleware.AuthOptional(db), handlers.GetExamples(db))
+	router.GET("/cameras/:id/examples/:example", middleware.AuthOptional(db), handlers.GetExample(db))
+	protected := router.Group("/cameras", middleware.AuthRequired(db))
+	protected.POST("/:id/examples", handlers.CreateExample(db))
+	protected.PUT("/:id/examples/:example", handlers.UpdateExample(db))
+	protected.DELETE("/:id/examples/:example", handlers.DeleteExample(db))
+
+	send := func(method, url, body, email string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if email != "" {
+			req.Header.Set("Authorization", "Bearer "+tokens[email])
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	examplesURL := fmt.Sprintf("/cameras/%d/examples", camera.ID)
+
+	w := send("POST", examplesURL, `{"serial_number": "12345"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = send("POST", examplesURL, `{"serial_number": "12345", "condition": "very good", "location": "Private collection, Yorkshire", "owner": "J. Smith", "provenance": "Bought new by the vicar of Altrincham", "private_notes": "Insured for £900", "image_urls": ["/uploads/ruby-12345.jpg", " "]}`, "collector@example.com")
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var private models.ExampleResponse
+	json.Unmarshal(w.Body.Bytes(), &private)
+	assert.Equal(t, "Very Good", private.Condition)
+	assert.Equal(t, "J. Smith", private.Owner)
+	assert.Equal(t, "Insured for £900", private.PrivateNotes)
+	assert.Equal(t, []string{"/uploads/ruby-12345.jpg"}, private.ImageURLs)
+	exampleURL := fmt.Sprintf("%s/%d", examplesURL, private.ID)
+
+	w = send("POST", examplesURL, `{"serial_number": "20417", "location": "Science Museum, London", "owner": "Science Museum Group", "owner_public": true, "image_urls": ["/uploads/ruby-20417.jpg"]}`, "other@example.com")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var museum models.ExampleResponse
+	json.Unmarshal(w.Body.Bytes(), &museum)
+	museumURL := fmt.Sprintf("%s/%d", examplesURL, museum.ID)
+
+	for _, body := range []string{
+		`{"condition": "battered"}`,
+		`{"owner_public": true}`,
+	} {
+		w = send("POST", examplesURL, body, "other@example.com")
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	// Anonymous readers see public fields and public owners only
+	list := func(email string) []map[string]interface{} {
+		w := send("GET", examplesURL, "", email)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var page struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &page)
+		return page.Data
+	}
+
+	examples := list("")
+	if assert.Len(t, examples, 2) {
+		assert.Equal(t, "12345", examples[0]["serial_number"])
+		assert.Equal(t, "Bought new by the vicar of Altrincham", examples[0]["provenance"])
+		assert.NotContains(t, examples[0], "owner")
+		assert.NotContains(t, examples[0], "private_notes")
+		assert.Equal(t, "Science Museum Group", examples[1]["owner"])
+		assert.NotContains(t, examples[1], "owner_public")
+	}
+
+	// The recorder and admins also see the private fields
+	examples = list("collector@example.com")
+	assert.Equal(t, "Insured for £900", examples[0]["private_notes"])
+	assert.NotContains(t, examples[1], "owner_public")
+
+	w = send("GET", exampleURL, "", "admin@example.com")
+	assert.Contains(t, w.Body.String(), "J. Smith")
+
+	// Only the recorder or an admin may change an example
+	w = send("PUT", exampleURL, `{"serial_number": "12346"}`, "other@example.com")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = send("PUT", exampleURL, `{"serial_number": "12345", "condition": "Good", "location": "Private collection, Yorkshire"}`, "collector@example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &private)
+	assert.Empty(t, private.ImageURLs)
+
+	w = send("DELETE", exampleURL, "", "other@example.com")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = send("DELETE", exampleURL, "", "admin@example.com")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = send("GET", exampleURL, "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Examples of a trashed camera are not found
+	assert.NoError(t, services.DeleteCamera(db, camera.ID))
+	for _, method := range []string{"GET", "PUT", "DELETE"} {
+		w = send(method, museumURL, `{"serial_number": "20417"}`, "admin@example.com")
+		assert.Equal(t, http.StatusNotFound, w.Code, method)
+		assert.Contains(t, w.Body.String(), "Camera not found", method)
+	}
+
+	// Purging the camera removes its examples and their photos
+	assert.NoError(t, services.PurgeTrash(db, "cameras", camera.ID))
+
+	var examplesLeft, imagesLeft int64
+	db.Model(&models.Example{}).Count(&examplesLeft)
+	db.Model(&models.ExampleImage{}).Count(&imagesLeft)
+	assert.Equal(t, int64(0), examplesLeft)
+	assert.Equal(t, int64(0), imagesLeft)
+}
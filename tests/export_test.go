@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"gorm.io/gorm"
+)
+
+func setupExportRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/export/cameras", handlers.NewCameraHandler(db).ExportCameras)
+	router.GET("/export/ephemera", handlers.ExportEphemera(db))
+	router.GET("/export/manufacturers", handlers.ExportManufacturers(db))
+	router.POST("/admin/import/cameras", handlers.ImportCameras(db))
+	return router
+}
+
+func sendExport(router *gin.Engine, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", url, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestExportCameras(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+	createTestManufacturer(db, "Kodak")
+
+	services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard", YearIntroduced: 1905, Format: "Plate", PlateSizes: []string{"Quarter-plate", "Half-plate"}, Description: "Reflex, with \"quotes\"", Rarity: "Uncommon"})
+	services.CreateCamera(db, &models.CameraRequest{Name: "Imperial", Manufacturer: "Thornton-Pickard", YearIntroduced: 1904, Format: "Plate"})
+	services.CreateCamera(db, &models.CameraRequest{Name: "Brownie", Manufacturer: "Kodak", YearIntroduced: 1900, Format: "Roll film"})
+
+	router := setupExportRouter(db)
+
+	w := sendExport(router, "/export/cameras?format=csv&manufacturer=Thornton-Pickard")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Regexp(t, `^attachment; filename="cameras-\d{4}-\d{2}-\d{2}\.csv"$`, w.Header().Get("Content-Disposition"))
+
+	records, err := csv.NewReader(bytes.NewReader(w.Body.Bytes())).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, services.CameraExportColumns, records[0])
+		// Exports are in ID order, whatever the sort
+		assert.Equal(t, "Ruby Reflex", records[1][1])
+		assert.Equal(t, "Thornton-Pickard", records[1][2])
+		assert.Equal(t, "Half-plate;Quarter-plate", records[1][6])
+		assert.Equal(t, `Reflex, with "quotes"`, records[1][10])
+		assert.Equal(t, "Uncommon", records[1][12])
+		assert.Equal(t, "Imperial", records[2][1])
+	}
+
+	// The CSV can be imported again, matching the existing cameras
+	importReq, _ := http.NewRequest("POST", "/admin/import/cameras", bytes.NewReader(w.Body.Bytes()))
+	importReq.Header.Set("Content-Type", "text/csv")
+	importW := httptest.NewRecorder()
+	router.ServeHTTP(importW, importReq)
+
+	var result services.ImportResult
+	json.Unmarshal(importW.Body.Bytes(), &result)
+	assert.Equal(t, 2, result.Updated)
+	assert.Equal(t, 0, result.Failed)
+
+	// NDJSON has one camera response per line; format is the file format, so
+	// the camera format filter is camera_format
+	w = sendExport(router, "/export/cameras?format=ndjson&camera_format=Roll%20film")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if assert.Len(t, lines, 1) {
+		var camera models.CameraResponse
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &camera))
+		assert.Equal(t, "Brownie", camera.Name)
+		assert.Equal(t, "Kodak", camera.Manufacturer.Name)
+	}
+
+	w = sendExport(router, "/export/cameras")
+	var cameras []models.CameraResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cameras))
+	assert.Len(t, cameras, 3)
+
+	w = sendExport(router, "/export/cameras?format=xml")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportEphemeraAndManufacturers(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+	camera, _ := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard"})
+
+	for i := 1; i <= 3; i++ {
+		item := models.Ephemera{}
+		services.SaveEphemera(db, &item, &models.EphemeraRequest{
+			Type:           "catalog",
+			Title:          fmt.Sprintf("Catalogue %d", i),
+			Year:           1900 + i,
+			RelatedCameras: []models.EphemeraCameraLink{{CameraID: camera.ID}},
+		})
+	}
+
+	router := setupExportRouter(db)
+
+	w := sendExport(router, "/export/ephemera?format=json&year_from=1902")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), ".json")
+
+	var items []models.EphemeraResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "Catalogue 2", items[0].Title)
+		assert.Len(t, items[0].RelatedCameras, 1)
+	}
+
+	w = sendExport(router, "/export/ephemera?format=csv&type=manual")
+	records, err := csv.NewReader(bytes.NewReader(w.Body.Bytes())).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{services.EphemeraExportColumns}, records)
+
+	w = sendExport(router, "/export/manufacturers?format=json&country=atlantis")
+	assert.Equal(t, "[]\n", w.Body.String())
+
+	w = sendExport(router, "/export/manufacturers?format=csv")
+	records, err = csv.NewReader(bytes.NewReader(w.Body.Bytes())).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "Thornton-Pickard", records[1][1])
+	}
+}
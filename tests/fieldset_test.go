@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+func TestFieldsetsAndIncludes(t *testing.T) {
+	db := setupTestDB()
+	manufacturer := createTestManufacturer(db, "Thornton-Pickard")
+
+	ruby, err := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard", YearIntroduced: 1905, Description: "A long description", ImageURLs: []string{"/uploads/ruby.jpg"}})
+	assert.NoError(t, err)
+	_, err = services.CreateCamera(db, &models.CameraRequest{Name: "Imperial", Manufacturer: "Thornton-Pickard", YearIntroduced: 1904})
+	assert.NoError(t, err)
+
+	page := 12
+	item := models.Ephemera{}
+	assert.NoError(t, services.SaveEphemera(db, &item, &models.EphemeraRequest{
+		Type:           "catalog",
+		Title:          "1906 Catalogue",
+		Year:           1906,
+		RelatedCameras: []models.EphemeraCameraLink{{CameraID: ruby.ID, Page: &page}},
+	}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.GET("/cameras", cameraHandler.GetCameras)
+	router.GET("/cameras/:id", cameraHandler.GetCamera)
+	router.GET("/ephemera/:id", handlers.GetEphemeraItem(db))
+	router.GET("/manufacturers", handlers.GetManufacturers(db))
+	router.GET("/manufacturers/:id", handlers.GetManufacturer(db))
+
+	get := func(url string) (*httptest.ResponseRecorder, map[string]json.RawMessage) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, req)
+
+		var body map[string]json.RawMessage
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w, body
+	}
+
+	// Only the requested fields are returned
+	w, body := get("/cameras?fields=id,name,year_introduced,image_urls&sort=year_introduced")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var cameras []map[string]interface{}
+	json.Unmarshal(body["data"], &cameras)
+	if assert.Len(t, cameras, 2) {
+		assert.Len(t, cameras[1], 4)
+		assert.Equal(t, "Ruby Reflex", cameras[1]["name"])
+		assert.Equal(t, []interface{}{"/uploads/ruby.jpg"}, cameras[1]["image_urls"])
+	}
+
+	// Included relations are embedded even when not listed in fields
+	w, body = get(fmt.Sprintf("/cameras/%d?fields=name&include=ephemera,images,manufacturer", ruby.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, body, 4)
+
+	var ephemera []models.RelatedEphemera
+	json.Unmarshal(body["ephemera"], &ephemera)
+	if assert.Len(t, ephemera, 1) {
+		assert.Equal(t, "1906 Catalogue", ephemera[0].Title)
+		assert.Equal(t, 12, *ephemera[0].Page)
+	}
+
+	var images []models.CameraImage
+	json.Unmarshal(body["images"], &images)
+	assert.Len(t, images, 1)
+
+	var included models.Manufacturer
+	json.Unmarshal(body["manufacturer"], &included)
+	assert.Equal(t, manufacturer.ID, included.ID)
+
+	// Without fields or include the response is unchanged
+	w, body = get(fmt.Sprintf("/cameras/%d", ruby.ID))
+	assert.Contains(t, body, "description")
+	assert.NotContains(t, body, "ephemera")
+
+	w, body = get(fmt.Sprintf("/ephemera/%d?include=cameras&fields=title", item.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var linked []models.CameraResponse
+	json.Unmarshal(body["cameras"], &linked)
+	if assert.Len(t, linked, 1) {
+		assert.Equal(t, "Ruby Reflex", linked[0].Name)
+		assert.Equal(t, []string{"/uploads/ruby.jpg"}, linked[0].ImageURLs)
+	}
+
+	w, body = get(fmt.Sprintf("/manufacturers/%d?include=cameras", manufacturer.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, body, "country")
+
+	var made []models.CameraSummary
+	json.Unmarshal(body["cameras"], &made)
+	assert.Equal(t, []models.CameraSummary{
+		{ID: 2, Name: "Imperial", YearIntroduced: 1904},
+		{ID: ruby.ID, Name: "Ruby Reflex", YearIntroduced: 1905},
+	}, made)
+
+	// Unknown fields and relations are rejected
+	for _, url := range []string{
+		"/cameras?fields=id,colour",
+		"/cameras?include=owner",
+		fmt.Sprintf("/ephemera/%d?include=manufacturer", item.ID),
+		"/manufacturers?fields=cameras_made",
+	} {
+		w, _ = get(url)
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"gorm.io/gorm"
+)
+
+func setupImportRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/admin/import/cameras", handlers.ImportCameras(db))
+	return router
+}
+
+func sendImport(router *gin.Engine, query, contentType, body string) (*httptest.ResponseRecorder, services.ImportResult) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/import/cameras"+query, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	router.ServeHTTP(w, req)
+
+	var result services.ImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return w, result
+}
+
+const importCSV = `name,manufacturer,year_introduced,plate_sizes,rarity
+Ruby Reflex,Thornton-Pickard,1905,Quarter-plate;Half-plate,Uncommon
+Imperial,Thornton-Pickard,not a year,,
+Special Ruby,Unknown Maker,1920,,
+Junior Special Ruby,Thornton-Pickard
+`
+
+func TestImportCamerasCSV(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+	router := setupImportRouter(db)
+
+	w, result := sendImport(router, "", "text/csv", importCSV)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 4, result.Rows)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 3, result.Failed)
+	assert.True(t, result.Committed)
+
+	// Errors are reported against the file's line numbers
+	if assert.Len(t, result.Errors, 3) {
+		assert.Equal(t, 3, result.Errors[0].Line)
+		assert.Contains(t, result.Errors[0].Error, "year_introduced")
+		assert.Equal(t, 4, result.Errors[1].Line)
+		assert.Equal(t, "Special Ruby", result.Errors[1].Name)
+		assert.Equal(t, 5, result.Errors[2].Line)
+		assert.Contains(t, result.Errors[2].Error, "expected 5 fields")
+	}
+
+	camera, err := services.FindCamera(db, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ruby Reflex", camera.Name)
+	assert.Len(t, camera.PlateSizes, 2)
+
+	// Unknown columns make the whole file unreadable
+	w, _ = sendImport(router, "", "text/csv", "name,colour\nRuby,red\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = sendImport(router, "", "text/plain", importCSV)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportCamerasUpsert(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+	router := setupImportRouter(db)
+
+	body := `[
+  {"name": "Ruby Reflex", "manufacturer": "Thornton-Pickard", "year_introduced": 1905, "lens": "Cooke"},
+  {"name": "Imperial", "manufacturer": "Thornton-Pickard", "year_introduced": 1904}
+]`
+	w, result := sendImport(router, "", "application/json", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, result.Created)
+
+	// Same manufacturer, name (in any case) and year updates the camera
+	body = `{"name": "ruby reflex", "manufacturer": "Thornton-Pickard", "year_introduced": 1905, "lens": "Ross Xpres"}
+{"name": "Ruby Reflex", "manufacturer": "Thornton-Pickard", "year_introduced": 1912}
+`
+	w, result = sendImport(router, "?format=ndjson", "text/plain", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Created)
+
+	var count int64
+	db.Model(&models.Camera{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+
+	camera, err := services.FindCamera(db, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ross Xpres", camera.Lens)
+
+	// JSON rows report the line their object starts on
+	body = `[
+  {"name": "Imperial", "manufacturer": "Thornton-Pickard", "year_introduced": 1904},
+  {"name": "Imperial", "colour": "red"}
+]`
+	_, result = sendImport(router, "", "application/json", body)
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, 3, result.Errors[0].Line)
+	}
+}
+
+func TestImportCamerasDryRunAndAtomic(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+	router := setupImportRouter(db)
+
+	w, result := sendImport(router, "?dry_run=true", "text/csv", importCSV)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, result.DryRun)
+	assert.False(t, result.Committed)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 3, result.Failed)
+
+	var count int64
+	db.Model(&models.Camera{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// Atomic imports save nothing when any row fails
+	w, result = sendImport(router, "?atomic=true", "text/csv", importCSV)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.False(t, result.Committed)
+
+	db.Model(&models.Camera{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&models.Revision{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// Multipart uploads take their format from the file name
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	file, _ := form.CreateFormFile("file", "cameras.csv")
+	file.Write([]byte("name,manufacturer,year_introduced\nRuby Reflex,Thornton-Pickard,1905\n"))
+	form.Close()
+
+	w, result = sendImport(router, "?atomic=true", form.FormDataContentType(), buf.String())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, result.Committed)
+
+	db.Model(&models.Camera{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
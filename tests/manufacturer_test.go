@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// setupManufacturerRouter registers the manufacturer routes without the
+// auth middleware, which is exercised separately.
+func setupManufacturerRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	router.GET("/manufacturers", handlers.GetManufacturers(db))
+	router.GET("/manufacturers/:id", handlers.GetManufacturer(db))
+	router.POST("/manufacturers", handlers.CreateManufacturer(db))
+	router.PUT("/manufacturers/:id", handlers.UpdateManufacturer(db))
+	router.PATCH("/manufacturers/:id", handlers.PatchManufacturer(db))
+	router.DELETE("/manufacturers/:id", handlers.DeleteManufacturer(db))
+
+	return router
+}
+
+func TestGetManufacturersFilters(t *testing.T) {
+	db := setupTestDB()
+
+	defunct := 1939
+	manufacturers := []models.Manufacturer{
+		{Name: "Thornton-Pickard", Country: "England", Founded: 1888, Defunct: &defunct},
+		{Name: "Sanderson", Country: "England", Founded: 1895, Defunct: &defunct},
+		{Name: "Kodak", Country: "USA", Founded: 1888},
+		{Name: "Ensign", Country: "england", Founded: 1945},
+	}
+	db.Create(&manufacturers)
+
+	router := setupManufacturerRouter(db)
+
+	tests := []struct {
+		query string
+		names []string
+	}{
+		{"", []string{"Ensign", "Kodak", "Sanderson", "Thornton-Pickard"}},
+		{"?country=ENGLAND", []string{"Ensign", "Sanderson", "Thornton-Pickard"}},
+		{"?active_from=1940", []string{"Ensign", "Kodak"}},
+		{"?active_to=1890", []string{"Kodak", "Thornton-Pickard"}},
+		{"?country=england&active_from=1900&active_to=1920", []string{"Sanderson", "Thornton-Pickard"}},
+		{"?page=2&page_size=3", []string{"Thornton-Pickard"}},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/manufacturers"+tt.query, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, tt.query)
+
+		var response struct {
+			Total int64                 `json:"total"`
+			Data  []models.Manufacturer `json:"data"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err, tt.query)
+
+		names := make([]string, len(response.Data))
+		for i, manufacturer := range response.Data {
+			names[i] = manufacturer.Name
+		}
+		assert.Equal(t, tt.names, names, tt.query)
+	}
+}
+
+func TestManufacturerCRUD(t *testing.T) {
+	db := setupTestDB()
+	router := setupManufacturerRouter(db)
+
+	// Create
+	body, _ := json.Marshal(models.ManufacturerRequest{Name: "Sanderson", Country: "England", Founded: 1895})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/manufacturers", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created models.Manufacturer
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.NotZero(t, created.ID)
+
+	// Names differing only in punctuation and case are duplicates
+	body, _ = json.Marshal(models.ManufacturerRequest{Name: "SANDERSON."})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/manufacturers", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Patch leaves absent fields alone
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", fmt.Sprintf("/manufacturers/%d", created.ID), bytes.NewBufferString(`{"defunct": 1939}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var patched models.Manufacturer
+	json.Unmarshal(w.Body.Bytes(), &patched)
+	assert.Equal(t, "Sanderson", patched.Name)
+	assert.Equal(t, "England", patched.Country)
+	if assert.NotNil(t, patched.Defunct) {
+		assert.Equal(t, 1939, *patched.Defunct)
+	}
+
+	// Defunct before founded is rejected
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", fmt.Sprintf("/manufacturers/%d", created.ID), bytes.NewBufferString(`{"defunct": 1800}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Put replaces every field
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("/manufacturers/%d", created.ID), bytes.NewBufferString(`{"name": "Sanderson Camera Works"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/manufacturers/%d", created.ID), nil)
+	router.ServeHTTP(w, req)
+
+	var updated models.Manufacturer
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.Equal(t, "Sanderson Camera Works", updated.Name)
+	assert.Empty(t, updated.Country)
+	assert.Nil(t, updated.Defunct)
+
+	// Delete
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/manufacturers/%d", created.ID), nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/manufacturers/%d", created.ID), nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteManufacturerInUse(t *testing.T) {
+	db := setupTestDB()
+	manufacturer := createTestManufacturer(db, "Thornton-Pickard")
+
+	// A soft-deleted camera still holds the reference
+	camera := models.Camera{Name: "Ruby Reflex", ManufacturerID: &manufacturer.ID}
+	db.Create(&camera)
+	db.Delete(&camera)
+
+	router := setupManufacturerRouter(db)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/manufacturers/%d", manufacturer.ID), nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var count int64
+	db.Model(&models.Manufacturer{}).Where("id = ?", manufacturer.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,295 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/database"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"gorm.io/gorm"
+)
+
+// legacyCamera mirrors the cameras table from before plate sizes, features,
+// images and manufacturers were normalised into their own tables.
+type legacyCamera struct {
+	ID             uint   `gorm:"primaryKey"`
+	Name           string `gorm:"not null"`
+	Manufacturer   string `gorm:"not null"`
+	YearIntroduced int
+	PlateSizes     string
+	Features       string
+	ImageURLs      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyCamera) TableName() string { return "cameras" }
+
+// legacyEphemera mirrors the ephemera table from before camera links moved
+// to the ephemera_cameras join table.
+type legacyEphemera struct {
+	ID             uint   `gorm:"primaryKey"`
+	Type           string `gorm:"not null"`
+	Title          string `gorm:"not null"`
+	RelatedCameras string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyEphemera) TableName() string { return "ephemeras" }
+
+func TestMigrateLegacyCameraColumns(t *testing.T) {
+	db := openTestDB()
+
+	assert.NoError(t, db.AutoMigrate(&legacyCamera{}, &models.Manufacturer{}))
+	assert.NoError(t, db.Create(&models.Manufacturer{Name: "Thornton-Pickard"}).Error)
+	assert.NoError(t, db.Create(&[]legacyCamera{
+		{
+			Name:         "Ruby Reflex",
+			Manufacturer: "Thornton Pickard",
+			PlateSizes:   `["Quarter-plate", "Half-plate"]`,
+			Features:     "Reflex viewing, Rising front",
+			ImageURLs:    `["/uploads/ruby.jpg"]`,
+		},
+		{
+			Name:         "Victo",
+			Manufacturer: "Lancaster & Son",
+		},
+	}).Error)
+
+	assert.NoError(t, database.Migrate(db))
+
+	var columns []string
+	columnTypes, err := db.Migrator().ColumnTypes("cameras")
+	assert.NoError(t, err)
+	for _, columnType := range columnTypes {
+		columns = append(columns, columnType.Name())
+	}
+	assert.NotContains(t, columns, "plate_sizes")
+	assert.NotContains(t, columns, "features")
+	assert.NotContains(t, columns, "image_urls")
+	assert.NotContains(t, columns, "manufacturer")
+
+	// Migrating again is a no-op
+	assert.NoError(t, database.Migrate(db))
+
+	camera, err := services.FindCamera(db, 1)
+	assert.NoError(t, err)
+
+	// Spelling variants are matched to the manufacturer row
+	if assert.NotNil(t, camera.Manufacturer) {
+		assert.Equal(t, "Thornton-Pickard", camera.Manufacturer.Name)
+	}
+
+	// Unmatched names are kept for manual review
+	var legacyName string
+	db.Table("cameras").Where("id = ?", 2).Select("legacy_manufacturer").Scan(&legacyName)
+	assert.Equal(t, "Lancaster & Son", legacyName)
+
+	response := camera.ToCameraResponse()
+	assert.Equal(t, []string{"Half-plate", "Quarter-plate"}, response.PlateSizes)
+	assert.Equal(t, []string{"Reflex viewing", "Rising front"}, response.Features)
+	assert.Equal(t, []string{"/uploads/ruby.jpg"}, response.ImageURLs)
+}
+
+func TestMigrateLegacyEphemeraCameras(t *testing.T) {
+	db := openTestDB()
+
+	assert.NoError(t, database.Migrate(db))
+
+	// Add the legacy column back to the migrated schema
+	assert.NoError(t, db.Migrator().AddColumn(&legacyEphemera{}, "RelatedCameras"))
+
+	manufacturer := createTestManufacturer(db, "Thornton-Pickard")
+	cameras := []models.Camera{
+		{Name: "Ruby Reflex", ManufacturerID: &manufacturer.ID},
+		{Name: "Imperial", ManufacturerID: &manufacturer.ID},
+	}
+	db.Create(&cameras)
+
+	assert.NoError(t, db.Create(&[]legacyEphemera{
+		{Type: "catalog", Title: "1912 Catalogue", RelatedCameras: `[1, 2, 99]`},
+		{Type: "manual", Title: "Ruby Instructions", RelatedCameras: `["1"]`},
+		{Type: "advertisement", Title: "Advert"},
+	}).Error)
+
+	assert.NoError(t, database.Migrate(db))
+	assert.False(t, db.Migrator().HasColumn(&legacyEphemera{}, "RelatedCameras"))
+
+	// Links to missing cameras are dropped
+	catalogue, err := services.FindEphemera(db, 1)
+	assert.NoError(t, err)
+	response := catalogue.ToEphemeraResponse()
+	if assert.Len(t, response.RelatedCameras, 2) {
+		assert.Equal(t, "Ruby Reflex", response.RelatedCameras[0].Name)
+		assert.Equal(t, "Imperial", response.RelatedCameras[1].Name)
+	}
+
+	var count int64
+	db.Model(&models.EphemeraCamera{}).Where("camera_id = ?", 1).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestMigrateCameraRarity(t *testing.T) {
+	db := setupTestDB()
+	manufacturer := createTestManufacturer(db, "Thornton-Pickard")
+
+	// Free text as stored before the rarity scale
+	for name, rarity := range map[string]string{"Ruby Reflex": "very rare", "Imperial": "Ultra-Rare", "Victo": "Mint", "Brownie": ""} {
+		assert.NoError(t, db.Exec("INSERT INTO cameras (name, manufacturer_id, rarity) VALUES (?, ?, ?)", name, manufacturer.ID, rarity).Error)
+	}
+
+	assert.NoError(t, database.Migrate(db))
+	assert.NoError(t, database.Migrate(db))
+
+	var cameras []models.Camera
+	db.Order("name").Find(&cameras)
+	ranked := map[string]models.Rarity{}
+	for _, camera := range cameras {
+		ranked[camera.Name] = models.Rarity{Rank: camera.RarityRank, Name: camera.Rarity}
+	}
+
+	assert.Equal(t, map[string]models.Rarity{
+		"Brownie":     {Rank: 0, Name: ""},
+		"Imperial":    {Rank: 6, Name: "Extremely Rare"},
+		"Ruby Reflex": {Rank: 5, Name: "Very Rare"},
+		// Unknown values are cleared so the migration does not see them again
+		"Victo": {Rank: 0, Name: ""},
+	}, ranked)
+
+	// ...and kept for fixing by hand
+	var legacyRarity string
+	db.Table("cameras").Where("name = ?", "Victo").Select("legacy_rarity").Scan(&legacyRarity)
+	assert.Equal(t, "Mint", legacyRarity)
+}
+
+func TestMigrateCameraValuations(t *testing.T) {
+	db := setupTestDB()
+	manufacturer := createTestManufacturer(db, "Thornton-Pickard")
+
+	// The estimate columns as they were before valuations
+	assert.NoError(t, db.Exec("ALTER TABLE cameras ADD COLUMN estimated_value_min real").Error)
+	assert.NoError(t, db.Exec("ALTER TABLE cameras ADD COLUMN estimated_value_max real").Error)
+	assert.NoError(t, db.Exec("INSERT INTO cameras (name, manufacturer_id, estimated_value_min, estimated_value_max, updated_at) VALUES (?, ?, ?, ?, ?)",
+		"Ruby Reflex", manufacturer.ID, 500, 800, time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)).Error)
+	assert.NoError(t, db.Exec("INSERT INTO cameras (name, manufacturer_id, estimated_value_min) VALUES (?, ?, ?)", "Imperial", manufacturer.ID, 300).Error)
+	assert.NoError(t, db.Exec("INSERT INTO cameras (name, manufacturer_id) VALUES (?, ?)", "Victo", manufacturer.ID).Error)
+
+	assert.NoError(t, database.Migrate(db))
+	assert.NoError(t, database.Migrate(db))
+
+	var valuations []models.Valuation
+	db.Order("camera_id").Find(&valuations)
+	if assert.Len(t, valuations, 2) {
+		assert.Equal(t, "$500 - $800", valuations[0].ToValuationResponse().Range)
+		assert.Equal(t, "2020-05-01", models.NewDate(valuations[0].ValuedOn).String())
+		assert.Equal(t, "$300", valuations[1].ToValuationResponse().Range)
+	}
+
+	camera, err := services.FindCamera(db, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "$500 - $800", camera.ToCameraResponse().EstimatedValueRange)
+}
+
+func TestMigrateExistingUsersVerified(t *testing.T) {
+	db := setupTestDB()
+
+	// The users table as it was before email verification
+	assert.NoError(t, db.Exec("ALTER TABLE users DROP COLUMN email_verified_at").Error)
+	assert.NoError(t, db.Exec("INSERT INTO users (email, password, created_at) VALUES (?, ?, ?)", "old@example.com", "x", time.Now()).Error)
+
+	assert.NoError(t, database.Migrate(db))
+
+	newUser := models.User{Email: "new@example.com", Password: "x"}
+	db.Create(&newUser)
+	assert.NoError(t, database.Migrate(db))
+
+	var users []models.User
+	db.Order("id").Find(&users)
+	if assert.Len(t, users, 2) {
+		assert.True(t, users[0].EmailVerified())
+		assert.False(t, users[1].EmailVerified())
+	}
+}
+
+func TestMigrateRoles(t *testing.T) {
+	db := setupTestDB()
+
+	// Users and role permissions as they were before roles existed
+	assert.NoError(t, db.Exec("INSERT INTO users (email, password, role, created_at) VALUES (?, ?, ?, ?)", "old@example.com", "x", "user", time.Now()).Error)
+	assert.NoError(t, db.Migrator().DropTable(&models.RolePermission{}))
+
+	assert.NoError(t, database.Migrate(db))
+
+	var user models.User
+	db.Where("email = ?", "old@example.com").First(&user)
+	assert.Equal(t, models.RoleContributor, user.Role)
+
+	permissions, err := services.RolePermissions(db, models.RoleContributor)
+	assert.NoError(t, err)
+	assert.True(t, permissions.Has(models.PermCamerasWrite))
+	assert.False(t, permissions.Has(models.PermCamerasDelete))
+
+	// Changed permissions are not re-seeded
+	assert.NoError(t, services.SetRolePermissions(db, models.RoleContributor, []string{}))
+	assert.NoError(t, database.Migrate(db))
+	permissions, _ = services.RolePermissions(db, models.RoleContributor)
+	assert.Empty(t, permissions.List())
+
+	// Users are only renamed the first time
+	assert.NoError(t, db.Model(&user).Update("role", "user").Error)
+	assert.NoError(t, database.Migrate(db))
+	db.First(&user, user.ID)
+	assert.Equal(t, "user", user.Role)
+}
+
+func TestMigrateManufacturerNames(t *testing.T) {
+	db := setupTestDB()
+
+	// Manufacturers from before normalized names were stored
+	assert.NoError(t, db.Migrator().DropIndex(&models.Manufacturer{}, "NormalizedName"))
+	assert.NoError(t, db.Exec("ALTER TABLE manufacturers DROP COLUMN normalized_name").Error)
+	assert.NoError(t, db.Exec("INSERT INTO manufacturers (name) VALUES (?), (?), (?)", "Thornton-Pickard", "Houghton", "Thornton Pickard").Error)
+
+	assert.NoError(t, database.Migrate(db))
+	assert.NoError(t, database.Migrate(db))
+
+	manufacturer, err := services.FindManufacturerByName(db, "THORNTON PICKARD")
+	assert.NoError(t, err)
+	assert.Equal(t, "Thornton-Pickard", manufacturer.Name)
+
+	// The duplicate is kept apart until it is merged
+	var duplicate models.Manufacturer
+	db.Where("name = ?", "Thornton Pickard").First(&duplicate)
+	assert.NotNil(t, duplicate.NormalizedName)
+	assert.NotEqual(t, "thorntonpickard", *duplicate.NormalizedName)
+
+	// The unique index refuses equivalent names
+	assert.Error(t, db.Create(&models.Manufacturer{Name: "houghton"}).Error)
+}
+
+func TestMigrateCameraSearchFillsGaps(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	for _, name := range []string{"Ruby Reflex", "Imperial"} {
+		_, err := services.CreateCamera(db, &models.CameraRequest{Name: name, Manufacturer: "Thornton-Pickard"})
+		assert.NoError(t, err)
+	}
+
+	// A camera missing from the index, as after a rebuild without triggers
+	assert.NoError(t, db.Exec("DELETE FROM camera_search WHERE name = ?", "Imperial").Error)
+
+	assert.NoError(t, database.Migrate(db))
+	assert.NoError(t, database.Migrate(db))
+
+	var indexed int64
+	db.Raw("SELECT COUNT(*) FROM camera_search").Scan(&indexed)
+	assert.Equal(t, int64(2), indexed)
+	db.Raw("SELECT COUNT(*) FROM camera_search WHERE camera_search MATCH 'imperial'").Scan(&indexed)
+	assert.Equal(t, int64(1), indexed)
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+type cursorPage struct {
+	Page       *int                    `json:"page"`
+	Total      *int64                  `json:"total"`
+	PageSize   int                     `json:"page_size"`
+	NextCursor string                  `json:"next_cursor"`
+	Data       []models.CameraResponse `json:"data"`
+}
+
+func TestCameraCursorPagination(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	// Repeated years make the ID tie-break matter
+	years := []int{1905, 1910, 1905, 1920, 1910, 1905, 1930}
+	for i, year := range years {
+		_, err := services.CreateCamera(db, &models.CameraRequest{Name: fmt.Sprintf("Camera %d", i+1), Manufacturer: "Thornton-Pickard", YearIntroduced: year})
+		assert.NoError(t, err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/cameras", handlers.NewCameraHandler(db).GetCameras)
+
+	get := func(url string) (*httptest.ResponseRecorder, cursorPage) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, req)
+
+		var page cursorPage
+		json.Unmarshal(w.Body.Bytes(), &page)
+		return w, page
+	}
+
+	var names []string
+	url := "/cameras?sort=year_introduced&order=desc&page_size=3&cursor="
+	for pages := 0; pages < 5; pages++ {
+		w, page := get(url)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, page.Page)
+		assert.Nil(t, page.Total)
+
+		for _, camera := range page.Data {
+			names = append(names, camera.Name)
+		}
+		if page.NextCursor == "" {
+			assert.NotContains(t, w.Header().Get("Link"), `rel="next"`)
+			break
+		}
+		assert.Contains(t, w.Header().Get("Link"), `rel="next"`)
+
+		// A camera added mid-walk before the cursor shifts nothing
+		if pages == 0 {
+			services.CreateCamera(db, &models.CameraRequest{Name: "Late Addition", Manufacturer: "Thornton-Pickard", YearIntroduced: 1940})
+		}
+		url = "/cameras?sort=year_introduced&order=desc&page_size=3&cursor=" + page.NextCursor
+	}
+
+	assert.Equal(t, []string{
+		"Camera 7", "Camera 4", "Camera 5", "Camera 2", "Camera 6", "Camera 3", "Camera 1",
+	}, names)
+
+	// Cursors only work with the sort they were issued for
+	_, page := get("/cameras?sort=year_introduced&order=desc&page_size=3&cursor=")
+	w, _ := get("/cameras?sort=name&page_size=3&cursor=" + page.NextCursor)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = get("/cameras?cursor=not-a-cursor")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, page = get("/cameras?search=camera&page_size=3&cursor=")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, page.Data, 3)
+	assert.NotEmpty(t, page.NextCursor)
+
+	w, _ = get("/cameras?search=camera&sort=relevance&cursor=")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Page numbers still work and link to their neighbours
+	w, _ = get("/cameras?page=2&page_size=3")
+	assert.Equal(t, http.StatusOK, w.Code)
+	link := w.Header().Get("Link")
+	assert.Contains(t, link, `</cameras?page=1&page_size=3>; rel="prev"`)
+	assert.Contains(t, link, `</cameras?page=3&page_size=3>; rel="next"`)
+	assert.Contains(t, link, `</cameras?page=3&page_size=3>; rel="last"`)
+}
+
+func TestManufacturerCursorPagination(t *testing.T) {
+	db := setupTestDB()
+	for _, name := range []string{"Sanderson", "Kodak", "Thornton-Pickard", "Houghton", "Ensign"} {
+		createTestManufacturer(db, name)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/manufacturers", handlers.GetManufacturers(db))
+
+	var names []string
+	cursor := ""
+	for {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/manufacturers?page_size=2&cursor="+cursor, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var page struct {
+			NextCursor string                `json:"next_cursor"`
+			Data       []models.Manufacturer `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &page)
+		for _, manufacturer := range page.Data {
+			names = append(names, manufacturer.Name)
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	assert.Equal(t, []string{"Ensign", "Houghton", "Kodak", "Sanderson", "Thornton-Pickard"}, names)
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/middleware"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"gorm.io/gorm"
+)
+
+func setupProfileRouter(db *gorm.DB, mail *mailbox) func(method, url, body, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/login", handlers.Login(db))
+	router.POST("/auth/verify-email", handlers.VerifyEmail(db))
+	router.GET("/auth/profile", middleware.AuthRequired(db), handlers.GetProfile(db))
+	router.PATCH("/auth/profile", middleware.AuthRequired(db), handlers.UpdateProfile(db, mail))
+	router.DELETE("/auth/profile", middleware.AuthRequired(db), handlers.DeleteProfile(db))
+	router.POST("/auth/change-password", middleware.AuthRequired(db), handlers.ChangePassword(db, mail))
+
+	return func(method, url, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+}
+
+// createVerifiedUser adds a user with a verified email and password123.
+func createVerifiedUser(db *gorm.DB, email string) models.User {
+	user := models.User{Email: email, FirstName: "Test", LastName: "User", Role: models.RoleContributor}
+	user.HashPassword("password123")
+	db.Create(&user)
+	db.Model(&user).Update("email_verified_at", gorm.Expr("CURRENT_TIMESTAMP"))
+	return user
+}
+
+func TestUpdateProfile(t *testing.T) {
+	db := setupTestDB()
+	mail := &mailbox{}
+	send := setupProfileRouter(db, mail)
+
+	user := createVerifiedUser(db, "old@example.com")
+	createVerifiedUser(db, "taken@example.com")
+	session, _ := services.StartSession(db, &user, "")
+
+	w := send("PATCH", "/auth/profile", `{"first_name": "Arthur"}`, session.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var profile models.UserResponse
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	assert.Equal(t, "Arthur", profile.FirstName)
+	assert.Equal(t, "User", profile.LastName)
+	assert.True(t, profile.EmailVerified)
+	assert.Empty(t, mail.sent)
+
+	assert.Equal(t, http.StatusBadRequest, send("PATCH", "/auth/profile", `{"last_name": " "}`, session.Token).Code)
+
+	// Changing email needs the password and a free address
+	assert.Equal(t, http.StatusForbidden, send("PATCH", "/auth/profile", `{"email": "new@example.com"}`, session.Token).Code)
+	assert.Equal(t, http.StatusConflict, send("PATCH", "/auth/profile", `{"email": "taken@example.com", "current_password": "password123"}`, session.Token).Code)
+
+	w = send("PATCH", "/auth/profile", `{"email": "new@example.com", "current_password": "password123"}`, session.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	assert.Equal(t, "new@example.com", profile.Email)
+	assert.False(t, profile.EmailVerified)
+
+	// The new address is sent a verification link and the old one a notice
+	if assert.Len(t, mail.sent, 2) {
+		assert.Equal(t, "new@example.com", mail.sent[0].To)
+		assert.Equal(t, "old@example.com", mail.sent[1].To)
+	}
+	mail.sent = mail.sent[:1]
+
+	w = send("POST", "/auth/verify-email", `{"token": "`+mail.lastToken()+`"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	assert.True(t, profile.EmailVerified)
+}
+
+func TestChangePassword(t *testing.T) {
+	db := setupTestDB()
+	mail := &mailbox{}
+	send := setupProfileRouter(db, mail)
+
+	user := createVerifiedUser(db, "user@example.com")
+	current, _ := services.StartSession(db, &user, "")
+	other, _ := services.StartSession(db, &user, "")
+
+	assert.Equal(t, http.StatusForbidden, send("POST", "/auth/change-password", `{"current_password": "wrong", "new_password": "newpassword"}`, current.Token).Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/auth/change-password", `{"current_password": "password123", "new_password": "short"}`, current.Token).Code)
+
+	assert.Equal(t, http.StatusNoContent, send("POST", "/auth/change-password", `{"current_password": "password123", "new_password": "newpassword"}`, current.Token).Code)
+	assert.Len(t, mail.sent, 1)
+
+	// Other sessions are logged out; this one is not
+	assert.Equal(t, http.StatusOK, send("GET", "/auth/profile", "", current.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/auth/profile", "", other.Token).Code)
+
+	assert.Equal(t, http.StatusOK, send("POST", "/auth/login", `{"email": "user@example.com", "password": "newpassword"}`, "").Code)
+}
+
+func TestDeleteProfile(t *testing.T) {
+	db := setupTestDB()
+	send := setupProfileRouter(db, &mailbox{})
+
+	createTestManufacturer(db, "Thornton-Pickard")
+	user := createVerifiedUser(db, "leaving@example.com")
+	session, _ := services.StartSession(db, &user, "")
+
+	ctx := services.WithAuthor(db.Statement.Context, user.ID)
+	camera, err := services.CreateCamera(db.WithContext(ctx), &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard"})
+	assert.NoError(t, err)
+
+	example := models.Example{CameraID: camera.ID, AddedByID: user.ID}
+	assert.NoError(t, services.SaveExample(db, &example, &models.ExampleRequest{SerialNumber: "12345", Owner: "Leaving User", PrivateNotes: "Kept in the loft"}))
+
+	assert.Equal(t, http.StatusForbidden, send("DELETE", "/auth/profile", `{"password": "wrong"}`, session.Token).Code)
+	assert.Equal(t, http.StatusNoContent, send("DELETE", "/auth/profile", `{"password": "password123"}`, session.Token).Code)
+
+	// The account and its sessions are gone
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/auth/profile", "", session.Token).Code)
+	var count int64
+	db.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// What they contributed stays, without their name or private details
+	var revision models.Revision
+	db.Where("entity_type = ? AND entity_id = ?", models.RevisionCamera, camera.ID).First(&revision)
+	assert.Nil(t, revision.UserID)
+
+	db.First(&example, example.ID)
+	assert.Equal(t, "12345", example.SerialNumber)
+	assert.Empty(t, example.Owner)
+	assert.Empty(t, example.PrivateNotes)
+	assert.Zero(t, example.AddedByID)
+}
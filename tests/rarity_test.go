@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
+)
+
+func TestCameraRarityScale(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	for name, rarity := range map[string]string{
+		"Brownie":     "common",
+		"Imperial":    "Very Rare",
+		"Ruby Reflex": "Uncommon",
+		"Royal Ruby":  "rare",
+		"Victo":       "",
+	} {
+		_, err := services.CreateCamera(db, &models.CameraRequest{Name: name, Manufacturer: "Thornton-Pickard", Rarity: rarity})
+		assert.NoError(t, err)
+	}
+
+	// Rarities off the scale are rejected
+	_, err := services.CreateCamera(db, &models.CameraRequest{Name: "Special", Manufacturer: "Thornton-Pickard", Rarity: "Legendary"})
+	assert.True(t, errors.Is(err, services.ErrInvalidCamera))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/cameras", handlers.NewCameraHandler(db).GetCameras)
+
+	list := func(url string) (int, []models.CameraResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, req)
+
+		var response utils.Pagination
+		var cameras []models.CameraResponse
+		response.Data = &cameras
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, cameras
+	}
+
+	names := func(cameras []models.CameraResponse) []string {
+		result := make([]string, len(cameras))
+		for i, camera := range cameras {
+			result[i] = camera.Name + " (" + camera.Rarity + ")"
+		}
+		return result
+	}
+
+	// Sorted by rank, not alphabetically
+	code, cameras := list("/cameras?sort=rarity&order=desc")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Imperial (Very Rare)", "Royal Ruby (Rare)", "Ruby Reflex (Uncommon)", "Brownie (Common)", "Victo ()"}, names(cameras))
+
+	code, cameras = list("/cameras?sort=rarity&rarity_min=uncommon&rarity_max=4")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Ruby Reflex (Uncommon)", "Royal Ruby (Rare)"}, names(cameras))
+
+	_, cameras = list("/cameras?sort=rarity&cursor=&page_size=2&rarity_min=Rare")
+	assert.Equal(t, []string{"Royal Ruby (Rare)", "Imperial (Very Rare)"}, names(cameras))
+
+	_, cameras = list("/cameras?rarity_max=priceless")
+	assert.Empty(t, cameras)
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+)
+
+func TestCameraRevisions(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	// Stand in for AuthRequired
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(7))
+		c.Next()
+	})
+
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.POST("/cameras", cameraHandler.CreateCamera)
+	router.PATCH("/cameras/:id", cameraHandler.PatchCamera)
+	router.DELETE("/cameras/:id", cameraHandler.DeleteCamera)
+	router.GET("/cameras/:id/revisions", handlers.GetRevisions(db, models.RevisionCamera))
+	router.GET("/cameras/:id/revisions/:rev", handlers.GetRevision(db, models.RevisionCamera))
+	router.POST("/cameras/:id/revisions/:rev/restore", cameraHandler.RestoreCameraRevision)
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/cameras", `{"name": "Ruby Reflex", "manufacturer": "Thornton-Pickard", "lens": "Cooke", "features": ["Reflex viewing"]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var camera models.CameraResponse
+	json.Unmarshal(w.Body.Bytes(), &camera)
+	cameraURL := fmt.Sprintf("/cameras/%d", camera.ID)
+
+	w = send("PATCH", cameraURL, `{"lens": "Dallmeyer", "features": ["Reflex viewing", "Rising front"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A patch that changes nothing is not recorded
+	w = send("PATCH", cameraURL, `{"lens": "Dallmeyer"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send("GET", cameraURL+"/revisions", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var list struct {
+		Total int64                     `json:"total"`
+		Data  []models.RevisionResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Equal(t, int64(2), list.Total)
+	if assert.Len(t, list.Data, 2) {
+		update := list.Data[0]
+		assert.Equal(t, 2, update.Revision)
+		assert.Equal(t, models.RevisionUpdate, update.Action)
+		assert.Equal(t, uint(7), *update.UserID)
+		assert.Equal(t, "Cooke", update.Changes["lens"].From)
+		assert.Equal(t, "Dallmeyer", update.Changes["lens"].To)
+		assert.Contains(t, update.Changes, "features")
+		assert.NotContains(t, update.Changes, "name")
+		assert.Nil(t, update.Snapshot)
+
+		assert.Equal(t, models.RevisionCreate, list.Data[1].Action)
+	}
+
+	w = send("GET", cameraURL+"/revisions/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var first models.RevisionResponse
+	json.Unmarshal(w.Body.Bytes(), &first)
+	assert.Contains(t, string(first.Snapshot), `"lens":"Cooke"`)
+
+	// Roll back to the first revision
+	w = send("POST", cameraURL+"/revisions/1/restore", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var restored models.CameraResponse
+	json.Unmarshal(w.Body.Bytes(), &restored)
+	assert.Equal(t, "Cooke", restored.Lens)
+	assert.Equal(t, []string{"Reflex viewing"}, restored.Features)
+
+	w = send("GET", cameraURL+"/revisions/3", "")
+	var restore models.RevisionResponse
+	json.Unmarshal(w.Body.Bytes(), &restore)
+	assert.Equal(t, models.RevisionRestore, restore.Action)
+
+	w = send("POST", cameraURL+"/revisions/9/restore", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Deletion keeps the final state
+	w = send("DELETE", cameraURL, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = send("GET", cameraURL+"/revisions/4", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var deletion models.RevisionResponse
+	json.Unmarshal(w.Body.Bytes(), &deletion)
+	assert.Equal(t, models.RevisionDelete, deletion.Action)
+	assert.Contains(t, string(deletion.Snapshot), `"name":"Ruby Reflex"`)
+	assert.Nil(t, deletion.Changes["name"].To)
+}
+
+func TestManufacturerAndEphemeraRevisions(t *testing.T) {
+	db := setupTestDB()
+	router := setupManufacturerRouter(db)
+	router.GET("/manufacturers/:id/revisions", handlers.GetRevisions(db, models.RevisionManufacturer))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/manufacturers", bytes.NewBufferString(`{"name": "Sanderson"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var manufacturer models.Manufacturer
+	json.Unmarshal(w.Body.Bytes(), &manufacturer)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", fmt.Sprintf("/manufacturers/%d", manufacturer.ID), bytes.NewBufferString(`{"country": "England"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/manufacturers/%d/revisions", manufacturer.ID), nil)
+	router.ServeHTTP(w, req)
+
+	var list struct {
+		Data []models.RevisionResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if assert.Len(t, list.Data, 2) {
+		assert.Equal(t, "England", list.Data[0].Changes["country"].To)
+		// No authenticated user
+		assert.Nil(t, list.Data[0].UserID)
+	}
+
+	ephemeraRouter := setupEphemeraRouter(db)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/ephemera", bytes.NewBufferString(`{"type": "catalog", "title": "1912 Catalogue"}`))
+	req.Header.Set("Content-Type", "application/json")
+	ephemeraRouter.ServeHTTP(w, req)
+
+	var count int64
+	db.Model(&models.Revision{}).Where("entity_type = ?", models.RevisionEphemera).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/middleware"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"gorm.io/gorm"
+)
+
+func setupRoleRouter(db *gorm.DB) func(method, url, body, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/cameras", middleware.AuthRequired(db), middleware.RequirePermission(models.PermCamerasWrite), ok)
+	router.DELETE("/cameras/:id", middleware.AuthRequired(db), middleware.RequirePermission(models.PermCamerasDelete), ok)
+
+	admin := router.Group("/admin", middleware.AuthRequired(db), middleware.RequirePermission(models.PermRolesManage))
+	admin.GET("/roles", handlers.GetRoles(db))
+	admin.PUT("/roles/:role", handlers.UpdateRolePermissions(db))
+
+	userHandler := handlers.NewUserHandler(db, &mailbox{})
+	users := router.Group("/users", middleware.AuthRequired(db), middleware.RequirePermission(models.PermUsersManage))
+	users.POST("/:id/suspend", userHandler.SuspendUser)
+
+	return func(method, url, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+}
+
+// signInAs creates a user with role and returns an access token for them.
+func signInAs(db *gorm.DB, role string) (models.User, string) {
+	user := createVerifiedUser(db, role+"@example.com")
+	db.Model(&user).Update("role", role)
+	session, _ := services.StartSession(db, &user, "")
+	return user, session.Token
+}
+
+func TestRequirePermission(t *testing.T) {
+	db := setupTestDB()
+	send := setupRoleRouter(db)
+
+	_, viewer := signInAs(db, models.RoleViewer)
+	_, contributor := signInAs(db, models.RoleContributor)
+	_, editor := signInAs(db, models.RoleEditor)
+	_, unknown := signInAs(db, "wizard")
+
+	assert.Equal(t, http.StatusForbidden, send("POST", "/cameras", "", viewer).Code)
+	assert.Equal(t, http.StatusOK, send("POST", "/cameras", "", contributor).Code)
+	assert.Equal(t, http.StatusForbidden, send("DELETE", "/cameras/1", "", contributor).Code)
+	assert.Equal(t, http.StatusOK, send("DELETE", "/cameras/1", "", editor).Code)
+	assert.Equal(t, http.StatusForbidden, send("POST", "/cameras", "", unknown).Code)
+}
+
+func TestManageRolePermissions(t *testing.T) {
+	db := setupTestDB()
+	send := setupRoleRouter(db)
+
+	_, admin := signInAs(db, models.RoleAdmin)
+	_, moderator := signInAs(db, models.RoleModerator)
+	_, viewer := signInAs(db, models.RoleViewer)
+
+	assert.Equal(t, http.StatusForbidden, send("GET", "/admin/roles", "", moderator).Code)
+
+	var roles []models.RoleResponse
+	w := send("GET", "/admin/roles", "", admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &roles)
+	if assert.Len(t, roles, 5) {
+		assert.Equal(t, models.RoleViewer, roles[0].Role)
+		assert.Empty(t, roles[0].Permissions)
+		assert.Equal(t, models.RoleAdmin, roles[4].Role)
+		assert.Equal(t, models.Permissions, roles[4].Permissions)
+		assert.False(t, roles[4].Editable)
+	}
+
+	// New permissions apply to signed-in users at once
+	assert.Equal(t, http.StatusForbidden, send("POST", "/cameras", "", viewer).Code)
+	w = send("PUT", "/admin/roles/viewer", `{"permissions": ["cameras:write", "cameras:write"]}`, admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var role models.RoleResponse
+	json.Unmarshal(w.Body.Bytes(), &role)
+	assert.Equal(t, []string{models.PermCamerasWrite}, role.Permissions)
+	assert.Equal(t, http.StatusOK, send("POST", "/cameras", "", viewer).Code)
+
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/admin/roles/viewer", `{"permissions": ["cameras:fly"]}`, admin).Code)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/admin/roles/wizard", `{"permissions": []}`, admin).Code)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/admin/roles/admin", `{"permissions": []}`, admin).Code)
+}
+
+func TestModeratorsCannotManageAdmins(t *testing.T) {
+	db := setupTestDB()
+	send := setupRoleRouter(db)
+
+	adminUser, _ := signInAs(db, models.RoleAdmin)
+	editorUser, _ := signInAs(db, models.RoleEditor)
+	_, moderator := signInAs(db, models.RoleModerator)
+
+	assert.Equal(t, http.StatusForbidden, send("POST", fmt.Sprintf("/users/%d/suspend", adminUser.ID), "", moderator).Code)
+	assert.Equal(t, http.StatusOK, send("POST", fmt.Sprintf("/users/%d/suspend", editorUser.ID), "", moderator).Code)
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"gorm.io/gorm"
+)
+
+func setupSaleRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/cameras/:id/sales", handlers.GetSales(db))
+	router.GET("/cameras/:id/price-history", handlers.GetPriceHistory(db))
+	router.POST("/cameras/:id/sales", handlers.CreateSale(db))
+	router.PUT("/cameras/:id/sales/:sale", handlers.UpdateSale(db))
+	router.DELETE("/cameras/:id/sales/:sale", handlers.DeleteSale(db))
+	router.POST("/admin/import/sales", handlers.ImportSales(db))
+	return router
+}
+
+func TestCameraSales(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+	camera, err := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard"})
+	assert.NoError(t, err)
+
+	router := setupSaleRouter(db)
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	salesURL := fmt.Sprintf("/cameras/%d/sales", camera.ID)
+
+	w := send("POST", salesURL, `{"sold_on": "2022-11-05", "venue": "Bonhams", "price": 420, "currency": "gbp", "condition": "good", "lot_url": "https://example.com/lot/12"}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var sale models.SaleRecordResponse
+	json.Unmarshal(w.Body.Bytes(), &sale)
+	assert.Equal(t, "GBP", sale.Currency)
+	assert.Equal(t, "Good", sale.Condition)
+	assert.Equal(t, "£420", sale.FormattedPrice)
+
+	for _, body := range []string{
+		`{"sold_on": "2022-11-05", "price": 0, "currency": "GBP"}`,
+		`{"sold_on": "2022-11-05", "price": 420, "currency": "GBP", "lot_url": "javascript:alert(1)"}`,
+		`{"price": 420, "currency": "GBP"}`,
+	} {
+		w = send("POST", salesURL, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w = send("PUT", fmt.Sprintf("%s/%d", salesURL, sale.ID), `{"sold_on": "2022-11-05", "venue": "Bonhams", "price": 450, "currency": "GBP"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send("GET", salesURL, "")
+	var page struct {
+		Total int64                       `json:"total"`
+		Data  []models.SaleRecordResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if assert.Len(t, page.Data, 1) {
+		assert.Equal(t, 450.0, page.Data[0].Price)
+	}
+
+	w = send("DELETE", fmt.Sprintf("%s/%d", salesURL, sale.ID), "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = send("GET", "/cameras/999/sales", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+const salesCSV = `camera,manufacturer,sold_on,venue,price,currency,condition,lot_url
+Ruby Reflex,,2021-03-01,Christie's,300,GBP,Good,
+Ruby Reflex,,2021-09-14,Bonhams,"1,000",GBP,Excellent,https://example.com/lot/7
+Ruby Reflex,,2021-12-01,eBay,500,GBP,,
+Ruby Reflex,,2023-05-20,Christie's,650,GBP,Very Good,
+Ruby Reflex,,2023-06-02,Leitz Auction,900,EUR,,
+Imperial,,2022-01-01,Bonhams,200,GBP,,
+Imperial,Kodak,2022-01-01,Bonhams,200,GBP,,
+Unknown Camera,,2022-01-01,Bonhams,200,GBP,,
+Ruby Reflex,,2022-01-01,Bonhams,lots,GBP,,
+`
+
+func TestImportSalesAndPriceHistory(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+	createTestManufacturer(db, "Kodak")
+
+	ruby, _ := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard"})
+	services.CreateCamera(db, &models.CameraRequest{Name: "Imperial", Manufacturer: "Thornton-Pickard"})
+	services.CreateCamera(db, &models.CameraRequest{Name: "Imperial", Manufacturer: "Kodak"})
+
+	router := setupSaleRouter(db)
+	importSales := func() services.ImportResult {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/import/sales", bytes.NewBufferString(salesCSV))
+		req.Header.Set("Content-Type", "text/csv")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var result services.ImportResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+
+	result := importSales()
+	assert.Equal(t, 6, result.Created)
+	assert.Equal(t, 3, result.Failed)
+	if assert.Len(t, result.Errors, 3) {
+		assert.Contains(t, result.Errors[0].Error, "several cameras")
+		assert.Contains(t, result.Errors[1].Error, "unknown camera")
+		assert.Equal(t, 10, result.Errors[2].Line)
+	}
+
+	// Importing the same results again updates rather than duplicates
+	result = importSales()
+	assert.Equal(t, 0, result.Created)
+	assert.Equal(t, 6, result.Updated)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/cameras/%d/price-history", ruby.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var history models.PriceHistory
+	json.Unmarshal(w.Body.Bytes(), &history)
+	assert.Equal(t, "GBP", history.Currency)
+	assert.Equal(t, []string{"GBP", "EUR"}, history.Currencies)
+	assert.Equal(t, []models.PriceYear{
+		{Year: 2021, Sales: 3, Min: 300, Median: 500, Max: 1000},
+		{Year: 2023, Sales: 1, Min: 650, Median: 650, Max: 650},
+	}, history.Years)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/cameras/%d/price-history?currency=eur", ruby.ID), nil)
+	router.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &history)
+	assert.Equal(t, []models.PriceYear{{Year: 2023, Sales: 1, Min: 900, Median: 900, Max: 900}}, history.Years)
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"github.com/Candoo/thornton-pickard-api/internal/utils"
+)
+
+func TestFullTextSearchRanking(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	requests := []models.CameraRequest{
+		{Name: "Imperial Triple Extension", Manufacturer: "Thornton-Pickard", Description: "Field camera; a reflex back was offered as an accessory"},
+		{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard", Description: "Single lens reflex popular with press photographers"},
+		{Name: "Rocket", Manufacturer: "Thornton-Pickard", Description: "Folding hand camera"},
+	}
+	for i := range requests {
+		_, err := services.CreateCamera(db, &requests[i])
+		assert.NoError(t, err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.GET("/cameras", cameraHandler.GetCameras)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cameras?search=REFLEX&sort=relevance", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data []models.CameraResponse `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	// A name match outranks a description-only match
+	if assert.Len(t, response.Data, 2) {
+		assert.Equal(t, "Ruby Reflex", response.Data[0].Name)
+		assert.Equal(t, "Imperial Triple Extension", response.Data[1].Name)
+		assert.Greater(t, *response.Data[0].Score, *response.Data[1].Score)
+		assert.Equal(t, "Ruby <mark>Reflex</mark>", response.Data[0].Highlights["name"])
+		assert.Contains(t, response.Data[1].Highlights["description"], "<mark>reflex</mark>")
+	}
+}
+
+func TestFullTextSearchFollowsUpdates(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	request := models.CameraRequest{Name: "Rocket", Manufacturer: "Thornton-Pickard"}
+	camera, err := services.CreateCamera(db, &request)
+	assert.NoError(t, err)
+
+	request.Name = "Puck"
+	assert.NoError(t, services.UpdateCamera(db, &camera, &request))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.GET("/cameras", cameraHandler.GetCameras)
+
+	for query, expected := range map[string]int{"rocket": 0, "puck": 1, "pu": 1} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/cameras?search="+query, nil)
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(expected), response["total"], query)
+	}
+}
+
+func TestCameraFacets(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+	createTestManufacturer(db, "Kodak")
+
+	requests := []models.CameraRequest{
+		{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard", YearIntroduced: 1909, Format: "Plate", Rarity: "Uncommon", PlateSizes: []string{"Quarter-plate"}},
+		{Name: "Imperial", Manufacturer: "Thornton-Pickard", YearIntroduced: 1895, Format: "Plate", Rarity: "Rare", PlateSizes: []string{"Half-plate", "Whole-plate"}},
+		{Name: "Royal Ruby", Manufacturer: "Thornton-Pickard", YearIntroduced: 1904, Format: "Plate", Rarity: "Rare", PlateSizes: []string{"Half-plate"}},
+		{Name: "Brownie", Manufacturer: "Kodak", YearIntroduced: 1900, Format: "Roll film", Rarity: "Common"},
+	}
+	for i := range requests {
+		_, err := services.CreateCamera(db, &requests[i])
+		assert.NoError(t, err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.GET("/cameras", cameraHandler.GetCameras)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cameras?format=Plate&facets=manufacturer,rarity,decade,plate_size", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response utils.Pagination
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), response.Total)
+
+	// Counts are computed against the format filter, so Kodak is absent
+	assert.Equal(t, []utils.FacetValue{{Value: "Thornton-Pickard", Count: 3}}, response.Facets["manufacturer"])
+	assert.Equal(t, []utils.FacetValue{{Value: "Rare", Count: 2}, {Value: "Uncommon", Count: 1}}, response.Facets["rarity"])
+	assert.Equal(t, []utils.FacetValue{{Value: "1900", Count: 2}, {Value: "1890", Count: 1}}, response.Facets["decade"])
+	assert.Equal(t, []utils.FacetValue{
+		{Value: "Half-plate", Count: 2},
+		{Value: "Quarter-plate", Count: 1},
+		{Value: "Whole-plate", Count: 1},
+	}, response.Facets["plate_size"])
+
+	// Unknown facets are rejected
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/cameras?facets=colour", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSearchHighlightsAreEscaped(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	_, err := services.CreateCamera(db, &models.CameraRequest{
+		Name:         "Ruby <b>Reflex</b>",
+		Manufacturer: "Thornton-Pickard",
+		Description:  `<script>alert("reflex")</script> & more`,
+	})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/cameras", handlers.NewCameraHandler(db).GetCameras)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cameras?search=reflex", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data []models.CameraResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	// Stored markup is escaped; only the match markers are tags
+	if assert.Len(t, response.Data, 1) {
+		assert.Equal(t, "Ruby &lt;b&gt;<mark>Reflex</mark>&lt;/b&gt;", response.Data[0].Highlights["name"])
+		assert.Equal(t, "&lt;script&gt;alert(&#34;<mark>reflex</mark>&#34;)&lt;/script&gt; &amp; more", response.Data[0].Highlights["description"])
+	}
+}
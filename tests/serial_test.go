@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+func TestSerialLookup(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+	ruby, err := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard"})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/serials", handlers.GetSerialRanges(db))
+	router.GET("/serials/lookup", handlers.LookupSerial(db))
+	router.GET("/serials/:id", handlers.GetSerialRange(db))
+	router.POST("/serials", handlers.CreateSerialRange(db))
+	router.PUT("/serials/:id", handlers.UpdateSerialRange(db))
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/serials", fmt.Sprintf(`{"model": "Ruby Reflex", "camera_id": %d, "start_serial": 10000, "end_serial": 20000, "date_from": "1910-01-01", "date_to": "1912-01-01", "source": "Works ledger"}`, ruby.ID))
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var created models.SerialRangeResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if assert.NotNil(t, created.Camera) {
+		assert.Equal(t, "Ruby Reflex", created.Camera.Name)
+	}
+
+	w = send("POST", "/serials", `{"model": "Thornton-Pickard shutter", "start_serial": 15000, "end_serial": 15999, "date_from": "1911-06-01"}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	for _, body := range []string{
+		// Overlaps the first Ruby Reflex range
+		`{"model": "ruby reflex", "start_serial": 19000, "end_serial": 25000, "date_from": "1912-01-01"}`,
+		`{"model": "Ruby Reflex", "start_serial": 30000, "end_serial": 29000, "date_from": "1913-01-01"}`,
+		`{"model": "Ruby Reflex", "start_serial": 30000, "date_from": "1913-01-01", "date_to": "1912-01-01"}`,
+		`{"model": "Ruby Reflex", "start_serial": 30000}`,
+		`{"model": "Ruby Reflex", "camera_id": 999, "start_serial": 30000, "date_from": "1913-01-01"}`,
+	} {
+		w = send("POST", "/serials", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	lookup := func(query string) (int, models.SerialLookup) {
+		w := send("GET", "/serials/lookup?"+query, "")
+		var result models.SerialLookup
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result
+	}
+
+	// Halfway through the range is halfway through its dates
+	code, result := lookup("model=ruby&serial=15000")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 15000, result.Serial)
+	if assert.Len(t, result.Matches, 1) {
+		assert.Equal(t, "1911-01-01", result.Matches[0].EstimatedDate.String())
+		assert.Equal(t, "Ruby Reflex", result.Matches[0].Model)
+		assert.Equal(t, ruby.ID, result.Matches[0].Camera.ID)
+		assert.Equal(t, "Works ledger", result.Matches[0].Range.Source)
+	}
+
+	// Without a model every range covering the serial matches, narrowest first
+	code, result = lookup("serial=No.%2015,500")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, result.Matches, 2) {
+		assert.Equal(t, "Thornton-Pickard shutter", result.Matches[0].Model)
+		assert.Equal(t, "1911-06-01", result.Matches[0].EstimatedDate.String())
+		assert.Equal(t, "Ruby Reflex", result.Matches[1].Model)
+	}
+
+	code, _ = lookup("model=ruby&serial=25000")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = lookup("serial=abc")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// A range can be updated without overlapping itself
+	w = send("PUT", fmt.Sprintf("/serials/%d", created.ID), `{"model": "Ruby Reflex", "start_serial": 10000, "end_serial": 21000, "date_from": "1910-01-01", "date_to": "1912-03-01"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = send("GET", "/serials?model=shutter", "")
+	var page struct {
+		Total int64                        `json:"total"`
+		Data  []models.SerialRangeResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, int64(1), page.Total)
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/middleware"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+func TestSessionsRefreshAndLogout(t *testing.T) {
+	db := setupTestDB()
+
+	user := models.User{Email: "test@example.com", Role: "user"}
+	user.HashPassword("password123")
+	db.Create(&user)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/login", handlers.Login(db))
+	router.POST("/auth/refresh", handlers.Refresh(db))
+	router.GET("/auth/profile", middleware.AuthRequired(db), handlers.GetProfile(db))
+	router.POST("/auth/logout", middleware.AuthRequired(db), handlers.Logout(db))
+	router.POST("/auth/logout-all", middleware.AuthRequired(db), handlers.LogoutAll(db))
+
+	send := func(method, url, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func() models.AuthResponse {
+		w := send("POST", "/auth/login", `{"email": "test@example.com", "password": "password123"}`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+	refresh := func(token string) (int, models.AuthResponse) {
+		w := send("POST", "/auth/refresh", `{"refresh_token": "`+token+`"}`, "")
+		var response models.AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	first := login()
+	assert.NotEmpty(t, first.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(services.DefaultAccessTokenTTL), first.ExpiresAt, time.Minute)
+
+	claims, err := services.ValidateToken(first.Token)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, claims.ID)
+
+	// Refresh tokens are stored hashed
+	var stored models.RefreshToken
+	db.First(&stored)
+	assert.NotEqual(t, first.RefreshToken, stored.TokenHash)
+
+	// Refreshing rotates the refresh token within the same session
+	code, second := refresh(first.RefreshToken)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	assert.Equal(t, http.StatusOK, send("GET", "/auth/profile", "", second.Token).Code)
+
+	// Replaying the old refresh token revokes the whole session
+	code, _ = refresh(first.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = refresh(second.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/auth/profile", "", second.Token).Code)
+
+	var session models.Session
+	db.First(&session, "id = ?", claims.SessionID)
+	assert.Equal(t, services.RevokedReuse, session.RevokedReason)
+
+	code, _ = refresh("not-a-token")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// Logout ends only the current session
+	phone, laptop := login(), login()
+	assert.Equal(t, http.StatusNoContent, send("POST", "/auth/logout", "", phone.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/auth/profile", "", phone.Token).Code)
+	code, _ = refresh(phone.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, http.StatusOK, send("GET", "/auth/profile", "", laptop.Token).Code)
+
+	// Logout-all ends every session
+	tablet := login()
+	assert.Equal(t, http.StatusNoContent, send("POST", "/auth/logout-all", "", laptop.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/auth/profile", "", laptop.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/auth/profile", "", tablet.Token).Code)
+}
+
+func TestAuthUsesCurrentRole(t *testing.T) {
+	db := setupTestDB()
+
+	user := models.User{Email: "editor@example.com", Role: "admin"}
+	db.Create(&user)
+	session, err := services.StartSession(db, &user, "")
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin", middleware.AuthRequired(db), middleware.RequirePermission(models.PermUsersManage), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	get := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+session.Token)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get())
+
+	// A demotion applies to tokens already issued
+	db.Model(&user).Update("role", models.RoleContributor)
+	assert.Equal(t, http.StatusForbidden, get())
+
+	// So does deleting the account
+	db.Delete(&user)
+	assert.Equal(t, http.StatusUnauthorized, get())
+}
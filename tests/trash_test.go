@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+func TestTrashRestoreAndPurge(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	ruby, err := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard", Features: []string{"Reflex viewing"}, ImageURLs: []string{"/uploads/ruby.jpg"}})
+	assert.NoError(t, err)
+	imperial, err := services.CreateCamera(db, &models.CameraRequest{Name: "Imperial", Manufacturer: "Thornton-Pickard"})
+	assert.NoError(t, err)
+	assert.NoError(t, services.DeleteCamera(db, ruby.ID))
+	assert.NoError(t, services.DeleteCamera(db, imperial.ID))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/trash/:type", handlers.GetTrash(db))
+	router.POST("/admin/trash/:type/:id/restore", handlers.RestoreTrashItem(db))
+	router.DELETE("/admin/trash/:type/:id", handlers.PurgeTrashItem(db))
+
+	send := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("GET", "/admin/trash/cameras")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var list struct {
+		Total int64                `json:"total"`
+		Data  []services.TrashItem `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Equal(t, int64(2), list.Total)
+
+	w = send("GET", "/admin/trash/manufacturers")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Restore brings the camera back
+	w = send("POST", fmt.Sprintf("/admin/trash/cameras/%d/restore", ruby.ID))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	restored, err := services.FindCamera(db, ruby.ID)
+	assert.NoError(t, err)
+	assert.Len(t, restored.Features, 1)
+
+	w = send("POST", fmt.Sprintf("/admin/trash/cameras/%d/restore", ruby.ID))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Live records cannot be purged
+	w = send("DELETE", fmt.Sprintf("/admin/trash/cameras/%d", ruby.ID))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = send("DELETE", fmt.Sprintf("/admin/trash/cameras/%d", imperial.ID))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	var count int64
+	db.Unscoped().Model(&models.Camera{}).Where("id = ?", imperial.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestTrashRestoreKeepsLinks(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	ruby, err := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard"})
+	assert.NoError(t, err)
+	imperial, err := services.CreateCamera(db, &models.CameraRequest{Name: "Imperial", Manufacturer: "Thornton-Pickard"})
+	assert.NoError(t, err)
+
+	page := 12
+	item := models.Ephemera{}
+	assert.NoError(t, services.SaveEphemera(db, &item, &models.EphemeraRequest{
+		Type:  "catalog",
+		Title: "1912 Catalogue",
+		RelatedCameras: []models.EphemeraCameraLink{
+			{CameraID: ruby.ID, Page: &page, Note: "Full-page illustration"},
+			{CameraID: imperial.ID},
+		},
+	}))
+
+	related := func() []models.RelatedCamera {
+		found, err := services.FindEphemera(db, item.ID)
+		assert.NoError(t, err)
+		return found.ToEphemeraResponse().RelatedCameras
+	}
+
+	// A trashed camera drops out of the item's links, even across a save
+	assert.NoError(t, services.DeleteCamera(db, ruby.ID))
+	assert.Len(t, related(), 1)
+
+	item, _ = services.FindEphemera(db, item.ID)
+	req := item.ToRequest()
+	req.Title = "1912 Catalogue (reprint)"
+	assert.NoError(t, services.SaveEphemera(db, &item, &req))
+
+	// ...and comes back with its page and note when restored
+	assert.NoError(t, services.RestoreTrash(db, "cameras", ruby.ID))
+	links := related()
+	if assert.Len(t, links, 2) {
+		assert.Equal(t, "Ruby Reflex", links[0].Name)
+		assert.Equal(t, 12, *links[0].Page)
+		assert.Equal(t, "Full-page illustration", links[0].Note)
+	}
+
+	// The same goes for a trashed ephemera item
+	item, _ = services.FindEphemera(db, item.ID)
+	assert.NoError(t, services.DeleteEphemera(db, &item))
+	ephemera, err := services.FindRelatedEphemera(db, []uint{ruby.ID})
+	assert.NoError(t, err)
+	assert.Empty(t, ephemera[ruby.ID])
+
+	assert.NoError(t, services.RestoreTrash(db, "ephemera", item.ID))
+	ephemera, err = services.FindRelatedEphemera(db, []uint{ruby.ID, imperial.ID})
+	assert.NoError(t, err)
+	assert.Len(t, ephemera[ruby.ID], 1)
+	assert.Len(t, ephemera[imperial.ID], 1)
+	assert.Len(t, related(), 2)
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	camera, err := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard", PlateSizes: []string{"Quarter-plate"}})
+	assert.NoError(t, err)
+	assert.NoError(t, services.DeleteCamera(db, camera.ID))
+
+	item := models.Ephemera{Type: "manual", Title: "Ruby Instructions"}
+	db.Create(&item)
+	db.Delete(&item)
+
+	user := models.User{Email: "old@test.com", Password: "password123"}
+	db.Create(&user)
+	db.Delete(&user)
+
+	// Only records deleted before the cutoff go
+	db.Unscoped().Model(&models.Camera{}).Where("id = ?", camera.ID).Update("deleted_at", time.Now().AddDate(0, 0, -40))
+	db.Unscoped().Model(&models.Ephemera{}).Where("id = ?", item.ID).Update("deleted_at", time.Now().AddDate(0, 0, -40))
+
+	purged, err := services.PurgeExpiredTrash(db, time.Now().AddDate(0, 0, -30))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+
+	var count int64
+	db.Unscoped().Model(&models.User{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+	db.Table("camera_plate_sizes").Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	t.Setenv("TRASH_RETENTION_DAYS", "7")
+	assert.Equal(t, 7*24*time.Hour, services.TrashRetention())
+	t.Setenv("TRASH_RETENTION_DAYS", "")
+	assert.Equal(t, services.DefaultTrashRetentionDays*24*time.Hour, services.TrashRetention())
+}
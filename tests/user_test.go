@@ -1,7 +1,9 @@
 package tests
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -9,7 +11,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/middleware"
 	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+	"gorm.io/gorm"
 )
 
 // NOTE: setupTestDB must be accessible from this package (as it is in your camera_test.go)
@@ -40,7 +45,7 @@ func TestGetUsers(t *testing.T) {
 	router := gin.New()
 	
 	// Instantiate the handler
-	userHandler := handlers.NewUserHandler(db)
+	userHandler := handlers.NewUserHandler(db, &mailbox{})
 	
 	// NOTE: Since the route in main.go is protected, we must skip or mock Auth/Admin middleware 
 	// for the test to reach the handler, or just test the handler directly.
@@ -56,10 +61,15 @@ func TestGetUsers(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	
 	// Assert Content
-	var response []models.UserResponse
-	err := json.Unmarshal(w.Body.Bytes(), &response)
+	var page struct {
+		Total int64                 `json:"total"`
+		Data  []models.UserResponse `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &page)
+	response := page.Data
 	
 	assert.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
 	assert.Len(t, response, 2)
 	
 	// Assert newly added fields
@@ -73,4 +83,170 @@ func TestGetUsers(t *testing.T) {
 	
 	// Crucial check: Assert that the sensitive field 'Password' is NOT returned
 	assert.NotContains(t, w.Body.String(), "password", "Response body should not contain the word 'password'")
-}
\ No newline at end of file
+}
+
+func setupUserAdminRouter(db *gorm.DB, mail *mailbox) func(method, url, body, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/login", handlers.Login(db))
+	router.POST("/auth/reset-password", handlers.ResetPassword(db))
+	router.GET("/auth/profile", middleware.AuthRequired(db), handlers.GetProfile(db))
+
+	userHandler := handlers.NewUserHandler(db, mail)
+	users := router.Group("/users", middleware.AuthRequired(db), middleware.RequirePermission(models.PermUsersManage))
+	users.GET("", userHandler.GetUsers)
+	users.GET("/:id", userHandler.GetUser)
+	users.GET("/:id/audit", userHandler.GetUserAudit)
+	users.PUT("/:id/role", middleware.RequirePermission(models.PermRolesAssign), userHandler.UpdateUserRole)
+	users.POST("/:id/suspend", userHandler.SuspendUser)
+	users.POST("/:id/unsuspend", userHandler.UnsuspendUser)
+	users.POST("/:id/reset-password", userHandler.ForcePasswordReset)
+	users.DELETE("/:id", userHandler.DeleteUser)
+	users.POST("/:id/restore", userHandler.RestoreUser)
+	router.DELETE("/admin/trash/:type/:id", middleware.AuthRequired(db), middleware.RequirePermission(models.PermTrashManage), handlers.PurgeTrashItem(db))
+
+	return func(method, url, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	db := setupTestDB()
+	send := setupUserAdminRouter(db, &mailbox{})
+
+	admin := createVerifiedUser(db, "admin@example.com")
+	db.Model(&admin).Update("role", "admin")
+	adminSession, _ := services.StartSession(db, &admin, "")
+
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		user := createVerifiedUser(db, fmt.Sprintf("%s@example.com", name))
+		db.Model(&user).Update("first_name", name)
+	}
+
+	// Only admins can list users
+	bob := models.User{}
+	db.Where("first_name = ?", "Bob").First(&bob)
+	bobSession, _ := services.StartSession(db, &bob, "")
+	assert.Equal(t, http.StatusForbidden, send("GET", "/users", "", bobSession.Token).Code)
+
+	var page struct {
+		Total int64                 `json:"total"`
+		Data  []models.UserResponse `json:"data"`
+	}
+	w := send("GET", "/users?page_size=2", "", adminSession.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, int64(4), page.Total)
+	assert.Len(t, page.Data, 2)
+
+	w = send("GET", "/users?q=carol%20user", "", adminSession.Token)
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if assert.Len(t, page.Data, 1) {
+		assert.Equal(t, "Carol@example.com", page.Data[0].Email)
+	}
+
+	w = send("GET", "/users?role=admin", "", adminSession.Token)
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if assert.Len(t, page.Data, 1) {
+		assert.Equal(t, admin.ID, page.Data[0].ID)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send("GET", "/users?status=banned", "", adminSession.Token).Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/users/999", "", adminSession.Token).Code)
+}
+
+func TestManageUser(t *testing.T) {
+	db := setupTestDB()
+	mail := &mailbox{}
+	send := setupUserAdminRouter(db, mail)
+
+	admin := createVerifiedUser(db, "admin@example.com")
+	db.Model(&admin).Update("role", "admin")
+	adminSession, _ := services.StartSession(db, &admin, "")
+	user := createVerifiedUser(db, "member@example.com")
+	userSession, _ := services.StartSession(db, &user, "")
+	userURL := fmt.Sprintf("/users/%d", user.ID)
+
+	// Role changes apply to the user's current session
+	assert.Equal(t, http.StatusBadRequest, send("PUT", userURL+"/role", `{"role": "overlord"}`, adminSession.Token).Code)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", fmt.Sprintf("/users/%d/role", admin.ID), `{"role": "contributor"}`, adminSession.Token).Code)
+	w := send("PUT", userURL+"/role", `{"role": "admin"}`, adminSession.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, send("GET", "/users", "", userSession.Token).Code)
+	send("PUT", userURL+"/role", `{"role": "contributor"}`, adminSession.Token)
+
+	// Suspension logs the user out and keeps them out
+	w = send("POST", userURL+"/suspend", `{"reason": "Spam"}`, adminSession.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp models.UserResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NotNil(t, resp.SuspendedAt)
+	assert.Equal(t, "Spam", resp.SuspendedReason)
+	assert.Equal(t, http.StatusBadRequest, send("POST", userURL+"/suspend", "", adminSession.Token).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, send("GET", "/auth/profile", "", userSession.Token).Code)
+	login := `{"email": "member@example.com", "password": "password123"}`
+	assert.Equal(t, http.StatusForbidden, send("POST", "/auth/login", login, "").Code)
+
+	assert.Equal(t, http.StatusOK, send("POST", userURL+"/unsuspend", "", adminSession.Token).Code)
+	assert.Equal(t, http.StatusOK, send("POST", "/auth/login", login, "").Code)
+
+	// A forced reset blocks sign-in until a new password is chosen
+	w = send("POST", userURL+"/reset-password", "", adminSession.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.True(t, resp.ResetRequired)
+	assert.Equal(t, http.StatusForbidden, send("POST", "/auth/login", login, "").Code)
+	assert.Equal(t, http.StatusNoContent, send("POST", "/auth/reset-password", `{"token": "`+mail.lastToken()+`", "password": "newpassword"}`, "").Code)
+	assert.Equal(t, http.StatusOK, send("POST", "/auth/login", `{"email": "member@example.com", "password": "newpassword"}`, "").Code)
+
+	// Deleted users are listed separately and can be restored
+	assert.Equal(t, http.StatusNoContent, send("DELETE", userURL, "", adminSession.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, send("POST", "/auth/login", `{"email": "member@example.com", "password": "newpassword"}`, "").Code)
+	var page struct {
+		Data []models.UserResponse `json:"data"`
+	}
+	json.Unmarshal(send("GET", "/users?status=deleted", "", adminSession.Token).Body.Bytes(), &page)
+	if assert.Len(t, page.Data, 1) {
+		assert.Equal(t, user.ID, page.Data[0].ID)
+		assert.NotNil(t, page.Data[0].DeletedAt)
+	}
+	assert.Equal(t, http.StatusBadRequest, send("POST", userURL+"/suspend", "", adminSession.Token).Code)
+
+	assert.Equal(t, http.StatusOK, send("POST", userURL+"/restore", "", adminSession.Token).Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", userURL+"/restore", "", adminSession.Token).Code)
+
+	// Every action is in the audit trail, newest first
+	var audit struct {
+		Total int64                     `json:"total"`
+		Data  []models.AuditLogResponse `json:"data"`
+	}
+	json.Unmarshal(send("GET", userURL+"/audit?page_size=20", "", adminSession.Token).Body.Bytes(), &audit)
+	actions := []string{}
+	for _, entry := range audit.Data {
+		actions = append(actions, entry.Action)
+		assert.Equal(t, admin.ID, *entry.ActorID)
+	}
+	assert.Equal(t, []string{
+		models.AuditRestore, models.AuditDelete, models.AuditPasswordReset, models.AuditUnsuspend,
+		models.AuditSuspend, models.AuditRoleChange, models.AuditRoleChange,
+	}, actions)
+	assert.Equal(t, "Spam", audit.Data[4].Details["reason"])
+	assert.Equal(t, "admin", audit.Data[6].Details["to"])
+
+	// Purging the deleted user is logged against the admin who did it
+	assert.Equal(t, http.StatusNoContent, send("DELETE", userURL, "", adminSession.Token).Code)
+	assert.Equal(t, http.StatusNoContent, send("DELETE", fmt.Sprintf("/admin/trash/users/%d", user.ID), "", adminSession.Token).Code)
+	var purge models.AuditLog
+	assert.NoError(t, db.Where("user_id = ? AND action = ?", user.ID, models.AuditPurge).First(&purge).Error)
+	if assert.NotNil(t, purge.ActorID) {
+		assert.Equal(t, admin.ID, *purge.ActorID)
+	}
+}
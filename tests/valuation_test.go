@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/Candoo/thornton-pickard-api/internal/handlers"
+	"github.com/Candoo/thornton-pickard-api/internal/models"
+	"github.com/Candoo/thornton-pickard-api/internal/services"
+)
+
+func TestCameraValuations(t *testing.T) {
+	db := setupTestDB()
+	createTestManufacturer(db, "Thornton-Pickard")
+
+	camera, err := services.CreateCamera(db, &models.CameraRequest{Name: "Ruby Reflex", Manufacturer: "Thornton-Pickard"})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cameraHandler := handlers.NewCameraHandler(db)
+	router.GET("/cameras/:id", cameraHandler.GetCamera)
+	router.GET("/cameras/:id/valuations", handlers.GetValuations(db))
+	router.POST("/cameras/:id/valuations", handlers.CreateValuation(db))
+	router.PUT("/cameras/:id/valuations/:valuation", handlers.UpdateValuation(db))
+	router.DELETE("/cameras/:id/valuations/:valuation", handlers.DeleteValuation(db))
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	valuationsURL := fmt.Sprintf("/cameras/%d/valuations", camera.ID)
+
+	for _, body := range []string{
+		`{"amount_min": 400, "amount_max": 600, "currency": "usd", "valued_on": "2019-03-01", "source": "Price guide"}`,
+		`{"amount_min": 300, "amount_max": 500, "currency": "GBP", "condition": "very good", "valued_on": "2021-06-15"}`,
+		`{"amount_min": 500, "amount_max": 700, "currency": "GBP", "condition": "Excellent", "valued_on": "2023-09-30"}`,
+	} {
+		w := send("POST", valuationsURL, body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var created models.ValuationResponse
+	w := send("POST", valuationsURL, `{"amount_min": 450, "currency": "CHF", "valued_on": "2010-01-01"}`)
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, "450 CHF", created.Range)
+	assert.Equal(t, "2010-01-01", created.ValuedOn.String())
+
+	// Invalid input is rejected
+	for _, body := range []string{
+		`{"amount_min": 500, "amount_max": 100, "currency": "GBP", "valued_on": "2023-01-01"}`,
+		`{"amount_min": 500, "currency": "pounds", "valued_on": "2023-01-01"}`,
+		`{"amount_min": 500, "currency": "GBP", "condition": "Shabby", "valued_on": "2023-01-01"}`,
+		`{"amount_min": 500, "currency": "GBP", "valued_on": "30/09/2023"}`,
+		`{"amount_min": 500, "currency": "GBP"}`,
+	} {
+		w = send("POST", valuationsURL, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	// Newest first, with the range in each valuation's currency
+	w = send("GET", valuationsURL, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var page struct {
+		Total int64                      `json:"total"`
+		Data  []models.ValuationResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, int64(4), page.Total)
+	if assert.Len(t, page.Data, 4) {
+		assert.Equal(t, "£500 - £700", page.Data[0].Range)
+		assert.Equal(t, "$400 - $600", page.Data[2].Range)
+	}
+
+	// The current estimate averages the latest valuations in one currency
+	w = send("GET", fmt.Sprintf("/cameras/%d", camera.ID), "")
+	var response models.CameraResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if assert.NotNil(t, response.CurrentEstimate) {
+		assert.Equal(t, "GBP", response.CurrentEstimate.Currency)
+		assert.Equal(t, 2, response.CurrentEstimate.Basis)
+		assert.Equal(t, "2023-09-30", response.CurrentEstimate.AsOf.String())
+	}
+	assert.Equal(t, "£400 - £600", response.EstimatedValueRange)
+
+	// Changing a valuation moves the camera's ETag on
+	tag := w.Header().Get("ETag")
+	w = send("PUT", fmt.Sprintf("%s/%d", valuationsURL, page.Data[0].ID), `{"amount_min": 900, "amount_max": 1100, "currency": "GBP", "valued_on": "2023-09-30"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send("GET", fmt.Sprintf("/cameras/%d", camera.ID), "")
+	assert.NotEqual(t, tag, w.Header().Get("ETag"))
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "£600 - £800", response.EstimatedValueRange)
+
+	w = send("DELETE", fmt.Sprintf("%s/%d", valuationsURL, created.ID), "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = send("DELETE", fmt.Sprintf("%s/%d", valuationsURL, created.ID), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = send("GET", "/cameras/999/valuations", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}